@@ -0,0 +1,25 @@
+//go:build linux
+
+package api
+
+import (
+	"syscall"
+)
+
+// bindToDeviceSupported 当前平台是否支持 SO_BINDTODEVICE
+const bindToDeviceSupported = true
+
+// bindToDevice 返回将套接字绑定到指定网络接口的 Control 函数
+// 需要 CAP_NET_RAW 权限（OpenWrt 上通常以 root 运行）
+func bindToDevice(interfaceName string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, interfaceName)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
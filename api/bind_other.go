@@ -0,0 +1,18 @@
+//go:build !linux
+
+package api
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// bindToDeviceSupported 当前平台是否支持 SO_BINDTODEVICE
+const bindToDeviceSupported = false
+
+// bindToDevice 非 Linux 平台不支持按接口绑定套接字
+func bindToDevice(interfaceName string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		return fmt.Errorf("当前平台不支持绑定到接口 %s", interfaceName)
+	}
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// DefaultUserAgent 默认的 User-Agent
+const DefaultUserAgent = "SpeedTestUp/1.0"
+
+// HTTPOptions 共享的 HTTP 客户端选项
+type HTTPOptions struct {
+	UserAgent    string            // User-Agent，为空时使用 DefaultUserAgent
+	Headers      map[string]string // 附加到每个请求的请求头
+	Proxy        string            // 代理地址（http, https, socks5, socks5h），为空时使用 HTTP_PROXY 等环境变量
+	CAFile       string            // 额外信任的 CA 证书文件（PEM），为空时只使用系统证书
+	MaxRetries   int               // 临时性失败（连接错误、5xx、429）的最大重试次数，0 表示不重试
+	RetryWait    time.Duration     // 首次重试前的等待时间，之后按指数增长并加入随机抖动
+	RetryMaxWait time.Duration     // 单次重试的最长等待时间
+
+	// OnRetry 每次请求因临时性失败需要重试时调用（包括重试次数耗尽的最后一次），可用于记录日志
+	OnRetry func(url string, attempt int, reason string)
+}
+
+// HTTPClientFactory 共享的 HTTP 客户端工厂
+// speedtest.cn 客户端和 text/json 类型的 IP 提供者通过同一个工厂创建客户端，
+// 使用相同的代理、证书、请求头和重试策略
+type HTTPClientFactory struct {
+	opts  HTTPOptions
+	proxy func(*http.Request) (*url.URL, error)
+	tls   *tls.Config
+}
+
+// NewHTTPClientFactory 根据选项创建 HTTP 客户端工厂
+func NewHTTPClientFactory(opts HTTPOptions) (*HTTPClientFactory, error) {
+	f := &HTTPClientFactory{opts: opts, proxy: http.ProxyFromEnvironment}
+	if f.opts.UserAgent == "" {
+		f.opts.UserAgent = DefaultUserAgent
+	}
+
+	if opts.Proxy != "" {
+		proxyURL, err := ParseProxyURL(opts.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		f.proxy = http.ProxyURL(proxyURL)
+	}
+
+	if opts.CAFile != "" {
+		pool, err := LoadCertPool(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		f.tls = &tls.Config{RootCAs: pool}
+	}
+
+	return f, nil
+}
+
+// DefaultHTTPClientFactory 默认的 HTTP 客户端工厂（不重试，使用环境变量中的代理）
+func DefaultHTTPClientFactory() *HTTPClientFactory {
+	f, _ := NewHTTPClientFactory(HTTPOptions{})
+	return f
+}
+
+// ParseProxyURL 解析代理地址，支持 http、https、socks5 和 socks5h
+func ParseProxyURL(raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("代理地址 %q 无效: %v", raw, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("代理地址 %q 的协议不受支持（可选 http, https, socks5, socks5h）", raw)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("代理地址 %q 缺少主机", raw)
+	}
+	return proxyURL, nil
+}
+
+// LoadCertPool 加载系统证书和 PEM 文件中的 CA 证书
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取 CA 证书文件失败: %v", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA 证书文件 %s 中没有有效的 PEM 证书", file)
+	}
+	return pool, nil
+}
+
+// Transport 创建使用指定拨号函数的 Transport，并应用代理和 CA 证书配置
+// 使用代理时拨号函数用于连接代理服务器，地址绑定对代理连接同样生效
+func (f *HTTPClientFactory) Transport(dial func(ctx context.Context, network, addr string) (net.Conn, error)) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if dial != nil {
+		transport.DialContext = dial
+	}
+	transport.Proxy = f.proxy
+	if f.tls != nil {
+		transport.TLSClientConfig = f.tls.Clone()
+	}
+	return transport
+}
+
+// Client 创建指定超时时间的 resty 客户端，并应用请求头和重试策略
+// timeout 为单次请求的超时时间，每次重试重新计时
+func (f *HTTPClientFactory) Client(timeout time.Duration, transport *http.Transport) *resty.Client {
+	client := f.SingleAttemptClient(timeout, transport)
+	if f.opts.MaxRetries > 0 {
+		client.
+			SetRetryCount(f.opts.MaxRetries).
+			SetRetryWaitTime(f.opts.RetryWait).
+			SetRetryMaxWaitTime(f.opts.RetryMaxWait).
+			SetRetryAfter(retryAfter).
+			AddRetryCondition(isTransient)
+		if f.opts.OnRetry != nil {
+			client.AddRetryHook(f.onRetry)
+		}
+	}
+	return client
+}
+
+// SingleAttemptClient 创建不重试的 resty 客户端，只应用请求头
+// 用于非幂等的请求，失败后由调用方决定是否以及何时重试
+func (f *HTTPClientFactory) SingleAttemptClient(timeout time.Duration, transport *http.Transport) *resty.Client {
+	return resty.New().
+		SetLogger(discardLogger{}).
+		SetTransport(transport).
+		SetTimeout(timeout).
+		SetHeader("User-Agent", f.opts.UserAgent).
+		SetHeaders(f.opts.Headers)
+}
+
+// onRetry 将 resty 的重试回调转换为 OnRetry
+func (f *HTTPClientFactory) onRetry(resp *resty.Response, err error) {
+	reqURL, attempt := "", 0
+	if resp != nil && resp.Request != nil {
+		reqURL, attempt = resp.Request.URL, resp.Request.Attempt
+	}
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	} else if resp != nil {
+		reason = fmt.Sprintf("状态码 %d", resp.StatusCode())
+	}
+	f.opts.OnRetry(reqURL, attempt, reason)
+}
+
+// discardLogger 丢弃 resty 内部日志，请求错误由调用方处理，重试通过 OnRetry 记录
+type discardLogger struct{}
+
+func (discardLogger) Errorf(string, ...interface{}) {}
+func (discardLogger) Warnf(string, ...interface{})  {}
+func (discardLogger) Debugf(string, ...interface{}) {}
+
+// isTransient 是否为可重试的临时性失败：连接错误、超时、5xx 和 429
+func isTransient(resp *resty.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	code := resp.StatusCode()
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// retryAfter 按 Retry-After 响应头（秒数）确定等待时间，未设置时使用指数退避
+// 等待时间会被限制在 RetryWait 和 RetryMaxWait 之间
+func retryAfter(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+	if resp == nil {
+		return 0, nil
+	}
+	seconds, err := strconv.Atoi(resp.Header().Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0, nil
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestFactory 创建重试等待时间很短的 HTTP 客户端工厂
+func newTestFactory(t *testing.T, opts HTTPOptions) *HTTPClientFactory {
+	t.Helper()
+	if opts.RetryWait == 0 {
+		opts.RetryWait = time.Millisecond
+		opts.RetryMaxWait = 10 * time.Millisecond
+	}
+	f, err := NewHTTPClientFactory(opts)
+	if err != nil {
+		t.Fatalf("NewHTTPClientFactory returned error: %v", err)
+	}
+	return f
+}
+
+func TestHTTPClientFactory_RetryTransient(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			fmt.Fprint(w, "203.0.113.7")
+		}
+	}))
+	defer server.Close()
+
+	f := newTestFactory(t, HTTPOptions{MaxRetries: 3})
+	provider := NewTextIPProvider("local", server.URL, IPFamilyAny, time.Second, WithProviderHTTPClientFactory(f))
+
+	start := time.Now()
+	ip, err := provider.GetPublicIP(context.Background(), IPFamilyAny)
+	if err != nil {
+		t.Fatalf("GetPublicIP returned error: %v", err)
+	}
+	if ip != "203.0.113.7" {
+		t.Errorf("Expected IP 203.0.113.7, got %s", ip)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 requests, got %d", calls)
+	}
+	// Retry-After 超过 RetryMaxWait 时按 RetryMaxWait 等待
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected Retry-After to be capped by RetryMaxWait, took %v", elapsed)
+	}
+}
+
+func TestHTTPClientFactory_NoRetryOnClientError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	f := newTestFactory(t, HTTPOptions{MaxRetries: 3})
+	provider := NewTextIPProvider("local", server.URL, IPFamilyAny, time.Second, WithProviderHTTPClientFactory(f))
+	if _, err := provider.GetPublicIP(context.Background(), IPFamilyAny); err == nil {
+		t.Error("Expected error for 404 response")
+	}
+	if calls != 1 {
+		t.Errorf("Expected 404 not to be retried, got %d requests", calls)
+	}
+}
+
+func TestHTTPClientFactory_RetryConnectionError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	var attempts []int
+	f := newTestFactory(t, HTTPOptions{
+		MaxRetries: 2,
+		OnRetry: func(u string, attempt int, reason string) {
+			if u != url || reason == "" {
+				t.Errorf("Unexpected retry callback: url=%q reason=%q", u, reason)
+			}
+			attempts = append(attempts, attempt)
+		},
+	})
+	client := f.Client(time.Second, f.Transport(nil))
+	if _, err := client.R().Get(url); err == nil {
+		t.Fatal("Expected connection error")
+	}
+	// 每次失败的尝试都会回调，共 1 次请求 + 2 次重试
+	if fmt.Sprint(attempts) != "[1 2 3]" {
+		t.Errorf("Expected retry callbacks for attempts [1 2 3], got %v", attempts)
+	}
+}
+
+func TestHTTPClientFactory_HeadersAndProxy(t *testing.T) {
+	var gotUA, gotHeader, gotURL string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = r.Header.Get("User-Agent")
+		gotHeader = r.Header.Get("X-Router")
+		gotURL = r.URL.String()
+		fmt.Fprint(w, "198.51.100.1")
+	}))
+	defer proxy.Close()
+
+	f := newTestFactory(t, HTTPOptions{
+		UserAgent: "router/1.0",
+		Headers:   map[string]string{"X-Router": "r1"},
+		Proxy:     proxy.URL,
+	})
+	provider := NewTextIPProvider("proxied", "http://ip.example.invalid/", IPFamilyAny, time.Second, WithProviderHTTPClientFactory(f))
+	ip, err := provider.GetPublicIP(context.Background(), IPFamilyAny)
+	if err != nil {
+		t.Fatalf("GetPublicIP through proxy returned error: %v", err)
+	}
+	if ip != "198.51.100.1" {
+		t.Errorf("Expected IP 198.51.100.1, got %s", ip)
+	}
+	if gotURL != "http://ip.example.invalid/" {
+		t.Errorf("Expected proxy to receive absolute URL, got %s", gotURL)
+	}
+	if gotUA != "router/1.0" || gotHeader != "r1" {
+		t.Errorf("Expected custom headers, got User-Agent=%q X-Router=%q", gotUA, gotHeader)
+	}
+}
+
+func TestHTTPClientFactory_InvalidOptions(t *testing.T) {
+	for _, proxy := range []string{"ftp://proxy:21", "socks5://", "://bad"} {
+		if _, err := NewHTTPClientFactory(HTTPOptions{Proxy: proxy}); err == nil {
+			t.Errorf("Expected error for proxy %q", proxy)
+		}
+	}
+	if _, err := NewHTTPClientFactory(HTTPOptions{Proxy: "socks5h://127.0.0.1:1080"}); err != nil {
+		t.Errorf("Expected socks5h proxy to be accepted, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewHTTPClientFactory(HTTPOptions{CAFile: path}); err == nil {
+		t.Error("Expected error for CA file without certificates")
+	}
+}
+
+func TestHTTPClientFactory_CAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "203.0.113.9")
+	}))
+	defer server.Close()
+
+	// 未信任测试服务器的证书时请求失败
+	provider := NewTextIPProvider("tls", server.URL, IPFamilyAny, time.Second)
+	if _, err := provider.GetPublicIP(context.Background(), IPFamilyAny); err == nil {
+		t.Error("Expected certificate error without CA file")
+	}
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newTestFactory(t, HTTPOptions{CAFile: path})
+	provider = NewTextIPProvider("tls", server.URL, IPFamilyAny, time.Second, WithProviderHTTPClientFactory(f))
+	ip, err := provider.GetPublicIP(context.Background(), IPFamilyAny)
+	if err != nil {
+		t.Fatalf("GetPublicIP with CA file returned error: %v", err)
+	}
+	if ip != "203.0.113.9" {
+		t.Errorf("Expected IP 203.0.113.9, got %s", ip)
+	}
+}
+
+func TestHTTPClientFactory_RetryCanceled(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	f := newTestFactory(t, HTTPOptions{MaxRetries: 5, RetryWait: 10 * time.Second, RetryMaxWait: 10 * time.Second})
+	provider := NewTextIPProvider("local", server.URL, IPFamilyAny, time.Second, WithProviderHTTPClientFactory(f))
+
+	// 取消 ctx 会中止重试前的等待
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := provider.GetPublicIP(ctx, IPFamilyAny); err == nil {
+		t.Fatal("Expected error after cancellation")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected retry wait to be canceled, took %v", elapsed)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single request before cancellation, got %d", calls)
+	}
+}
+
+func TestSpeedTestCNClient_ReopenNotRetried(t *testing.T) {
+	var queries, reopens int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/reopen" {
+			atomic.AddInt32(&reopens, 1)
+		} else {
+			atomic.AddInt32(&queries, 1)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	f := newTestFactory(t, HTTPOptions{MaxRetries: 2})
+	client := mustNewClient(t, "", WithHTTPClientFactory(f), WithEndpoints(server.URL+"/query", server.URL+"/reopen"))
+
+	if _, err := client.QuerySpeedupStatus(context.Background()); err == nil {
+		t.Error("Expected query error")
+	}
+	if queries != 3 {
+		t.Errorf("Expected query to be retried (3 requests), got %d", queries)
+	}
+
+	// 重新开启提速不是幂等请求，只发送一次
+	if _, err := client.ReopenSpeedup(context.Background()); err == nil {
+		t.Error("Expected reopen error")
+	}
+	if reopens != 1 {
+		t.Errorf("Expected a single reopen request, got %d", reopens)
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// InterfaceAddr 获取指定网络接口的地址
+// 对应 luci-app-broadbandacc 中的 get_bind_ip 函数
+// 接口名称为空时遍历所有接口；地址族为 any 时优先返回 IPv4 地址，其次是全局 IPv6 地址
+func InterfaceAddr(interfaceName string, family IPFamily) (netip.Addr, error) {
+	var interfaces []net.Interface
+	if interfaceName != "" {
+		iface, err := net.InterfaceByName(interfaceName)
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("未找到接口 %s: %v", interfaceName, err)
+		}
+		interfaces = []net.Interface{*iface}
+	} else {
+		all, err := net.Interfaces()
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("获取网络接口列表失败: %v", err)
+		}
+		interfaces = all
+	}
+
+	var fallback netip.Addr
+	for _, iface := range interfaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip, ok := netip.AddrFromSlice(ipNet.IP)
+			if !ok {
+				continue
+			}
+			ip = ip.Unmap()
+
+			// 过滤回环地址、链路本地地址和不符合地址族的地址
+			if ip.IsLoopback() || ip.IsLinkLocalUnicast() || !family.Matches(ip) {
+				continue
+			}
+
+			if ip.Is4() {
+				return ip, nil
+			}
+			if !fallback.IsValid() {
+				fallback = ip
+			}
+		}
+	}
+
+	if fallback.IsValid() {
+		return fallback, nil
+	}
+
+	if interfaceName != "" {
+		return netip.Addr{}, fmt.Errorf("未找到接口 %s 的有效网络 IP", interfaceName)
+	}
+	return netip.Addr{}, fmt.Errorf("未找到任何有效的网络接口 IP")
+}
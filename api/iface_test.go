@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestInterfaceAddr(t *testing.T) {
+	if _, err := InterfaceAddr("nonexistent_interface_12345", IPFamilyAny); err == nil {
+		t.Error("Expected error for nonexistent interface")
+	}
+
+	// 回环接口的地址会被过滤
+	interfaces, _ := net.Interfaces()
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			if addr, err := InterfaceAddr(iface.Name, IPFamilyAny); err == nil {
+				t.Errorf("Expected loopback interface %s to be skipped, got %s", iface.Name, addr)
+			}
+			break
+		}
+	}
+
+	// 返回的地址必须符合请求的地址族
+	if addr, err := InterfaceAddr("", IPFamilyV4); err == nil && !addr.Is4() {
+		t.Errorf("Expected IPv4 address, got %s", addr)
+	}
+}
+
+func TestSpeedTestCNClient_Rebind(t *testing.T) {
+	var dials int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"code":0}`)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&dials, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := mustNewClient(t, "", WithBindInterface("pppoe-wan"), WithEndpoints(server.URL, server.URL))
+	if client.bindInterface != "pppoe-wan" {
+		t.Errorf("Expected bindInterface pppoe-wan, got %s", client.bindInterface)
+	}
+	// 测试环境没有 pppoe-wan 接口，使用不绑定的客户端验证连接复用
+	client = mustNewClient(t, "", WithEndpoints(server.URL, server.URL))
+	transport := client.transport
+
+	query := func() {
+		if _, err := client.QuerySpeedupStatus(context.Background()); err != nil {
+			t.Errorf("QuerySpeedupStatus returned error: %v", err)
+		}
+	}
+	query()
+	query()
+	if n := atomic.LoadInt32(&dials); n != 1 {
+		t.Errorf("Expected the idle connection to be reused, got %d connections", n)
+	}
+
+	// Rebind 只丢弃空闲连接，不替换 Transport
+	client.Rebind()
+	query()
+	if n := atomic.LoadInt32(&dials); n != 2 {
+		t.Errorf("Expected a new connection after Rebind, got %d connections", n)
+	}
+	if client.transport != transport {
+		t.Error("Expected Rebind to keep the transport")
+	}
+
+	// 与正在进行的请求并发调用（配合 -race）
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			query()
+		}()
+		go func() {
+			defer wg.Done()
+			client.Rebind()
+		}()
+	}
+	wg.Wait()
+}
@@ -1,41 +1,88 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
-	"regexp"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 )
 
-// 缓存正则表达式，避免重复编译
-var (
-	// 匹配空白字符的正则表达式
-	whitespaceRegex = regexp.MustCompile(`\s+`)
-	// 验证 IPv4 地址的正则表达式
-	ipRegex = regexp.MustCompile(`^(\d{1,3}\.){3}\d{1,3}$`)
-)
+// DefaultIPInfoURL ipinfo.io 纯文本 IP 查询地址（与 luci-app-broadbandacc 相同）
+const DefaultIPInfoURL = "https://ipinfo.io/ip/"
 
-// IPAPI IP 查询 API
+// IPAPI IP 查询 API（纯文本响应）
 type IPAPI struct {
 	client *resty.Client
+	name   string
+	url    string
+	family IPFamily // 该接口支持的地址族
 }
 
-// NewIPAPI 创建新的 IP API 实例
+// NewIPAPI 创建新的 IP API 实例（使用 ipinfo.io）
 func NewIPAPI() *IPAPI {
+	return NewTextIPProvider("ipinfo", DefaultIPInfoURL, IPFamilyAny, 10*time.Second)
+}
+
+// NewTextIPProvider 创建返回纯文本 IP 的查询接口
+func NewTextIPProvider(name, url string, family IPFamily, timeout time.Duration, opts ...ProviderOption) *IPAPI {
 	return &IPAPI{
-		client: resty.New().
-			SetTimeout(10 * time.Second).
-			SetHeader("User-Agent", "SpeedTestUp/1.0"),
+		client: newIPClient(timeout, opts),
+		name:   name,
+		url:    url,
+		family: family,
+	}
+}
+
+// ProviderOption IP 提供者选项
+type ProviderOption func(*providerOptions)
+
+// providerOptions IP 提供者的可选配置
+type providerOptions struct {
+	http *HTTPClientFactory
+}
+
+// WithProviderHTTPClientFactory 使用共享的 HTTP 客户端工厂（仅 text/json 类型的提供者）
+func WithProviderHTTPClientFactory(f *HTTPClientFactory) ProviderOption {
+	return func(o *providerOptions) {
+		o.http = f
+	}
+}
+
+// newIPClient 创建按请求地址族拨号的 HTTP 客户端
+// 地址族在拨号时按请求选择，复用连接会让 IPv6 查询沿用同一主机的 IPv4 连接，因此不保持长连接
+func newIPClient(timeout time.Duration, opts []ProviderOption) *resty.Client {
+	o := providerOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.http == nil {
+		o.http = DefaultHTTPClientFactory()
 	}
+
+	transport := o.http.Transport(familyDialer(&net.Dialer{Timeout: timeout}, IPFamilyAny))
+	transport.DisableKeepAlives = true
+	return o.http.Client(timeout, transport)
 }
 
-// GetPublicIP 获取公网 IP（使用 ipinfo.io）
-func (a *IPAPI) GetPublicIP() (string, error) {
-	// 根据 luci-app-broadbandacc，使用 ipinfo.io/ip/ 获取公网 IP
+// Name 返回提供者名称
+func (a *IPAPI) Name() string {
+	return a.name
+}
+
+// GetPublicIP 获取指定地址族的公网 IP
+func (a *IPAPI) GetPublicIP(ctx context.Context, family IPFamily) (string, error) {
+	family, err := resolveFamily(a.family, family)
+	if err != nil {
+		return "", err
+	}
+
+	// 根据 luci-app-broadbandacc，默认使用 ipinfo.io/ip/ 获取公网 IP
 	resp, err := a.client.R().
-		Get("https://ipinfo.io/ip/")
+		SetContext(withFamily(ctx, family)).
+		Get(a.url)
 	if err != nil {
 		return "", fmt.Errorf("获取公网 IP 失败: %v", err)
 	}
@@ -44,14 +91,9 @@ func (a *IPAPI) GetPublicIP() (string, error) {
 		return "", fmt.Errorf("获取公网 IP 失败，状态码: %d", resp.StatusCode())
 	}
 
-	ip := string(resp.Body())
-	// 清理 IP 字符串（移除可能的换行符和空格）
-	ip = whitespaceRegex.ReplaceAllString(ip, "")
-
-	// 验证 IP 格式
-	if !ipRegex.MatchString(ip) {
-		return "", fmt.Errorf("获取的 IP 格式无效: %s", ip)
+	addr, err := ParseIP(string(resp.Body()), family)
+	if err != nil {
+		return "", err
 	}
-
-	return ip, nil
+	return addr.String(), nil
 }
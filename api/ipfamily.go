@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// IPFamily IP 地址族
+type IPFamily string
+
+// 地址族常量
+const (
+	IPFamilyAny IPFamily = "any"  // IPv4 或 IPv6
+	IPFamilyV4  IPFamily = "ipv4" // 仅 IPv4
+	IPFamilyV6  IPFamily = "ipv6" // 仅 IPv6
+)
+
+// ParseIPFamily 解析地址族配置（支持 any/ipv4/ipv6/4/6/v4/v6，空字符串视为 any）
+func ParseIPFamily(s string) (IPFamily, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "any", "dual":
+		return IPFamilyAny, nil
+	case "4", "v4", "ipv4", "ip4":
+		return IPFamilyV4, nil
+	case "6", "v6", "ipv6", "ip6":
+		return IPFamilyV6, nil
+	default:
+		return "", fmt.Errorf("无效的地址族: %s（可选 any, ipv4, ipv6）", s)
+	}
+}
+
+// Matches 检查地址是否属于该地址族
+func (f IPFamily) Matches(addr netip.Addr) bool {
+	switch f {
+	case IPFamilyV4:
+		return addr.Is4()
+	case IPFamilyV6:
+		return addr.Is6()
+	default:
+		return addr.IsValid()
+	}
+}
+
+// Network 将 tcp/udp 网络类型限定到该地址族（如 tcp -> tcp4）
+func (f IPFamily) Network(network string) string {
+	base := strings.TrimRight(network, "46")
+	switch f {
+	case IPFamilyV4:
+		return base + "4"
+	case IPFamilyV6:
+		return base + "6"
+	default:
+		return network
+	}
+}
+
+// familyOf 返回地址所属的地址族
+func familyOf(addr netip.Addr) IPFamily {
+	if addr.Is4() {
+		return IPFamilyV4
+	}
+	return IPFamilyV6
+}
+
+// ParseIP 解析并验证 IP 地址，IPv4 映射的 IPv6 地址会被还原为 IPv4
+func ParseIP(raw string, family IPFamily) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(raw))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("获取的 IP 格式无效: %s", strings.TrimSpace(raw))
+	}
+	addr = addr.Unmap()
+	if addr.Zone() != "" {
+		return netip.Addr{}, fmt.Errorf("获取的 IP 不应包含区域标识: %s", addr)
+	}
+	if !family.Matches(addr) {
+		return netip.Addr{}, fmt.Errorf("获取的 IP %s 不属于地址族 %s", addr, family)
+	}
+	return addr, nil
+}
+
+// familyDialer 返回按地址族限定网络类型的拨号函数
+func familyDialer(dialer *net.Dialer, family IPFamily) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		f := family
+		if requested, ok := ctx.Value(familyContextKey{}).(IPFamily); ok && requested != IPFamilyAny {
+			f = requested
+		}
+		return dialer.DialContext(ctx, f.Network(network), addr)
+	}
+}
+
+// resolveFamily 合并提供者支持的地址族与本次请求的地址族
+func resolveFamily(supported, requested IPFamily) (IPFamily, error) {
+	switch {
+	case supported == "" || supported == IPFamilyAny:
+		return requested, nil
+	case requested == "" || requested == IPFamilyAny || requested == supported:
+		return supported, nil
+	default:
+		return "", fmt.Errorf("不支持地址族 %s（仅支持 %s）", requested, supported)
+	}
+}
+
+// familyContextKey 在请求上下文中携带地址族
+type familyContextKey struct{}
+
+// withFamily 在上下文中设置本次请求使用的地址族
+func withFamily(ctx context.Context, family IPFamily) context.Context {
+	return context.WithValue(ctx, familyContextKey{}, family)
+}
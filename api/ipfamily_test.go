@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseIPFamily(t *testing.T) {
+	cases := map[string]IPFamily{
+		"":     IPFamilyAny,
+		"any":  IPFamilyAny,
+		"4":    IPFamilyV4,
+		"IPv4": IPFamilyV4,
+		"v6":   IPFamilyV6,
+		"ipv6": IPFamilyV6,
+	}
+	for input, expected := range cases {
+		family, err := ParseIPFamily(input)
+		if err != nil {
+			t.Errorf("ParseIPFamily(%q) returned error: %v", input, err)
+			continue
+		}
+		if family != expected {
+			t.Errorf("ParseIPFamily(%q) = %s, expected %s", input, family, expected)
+		}
+	}
+
+	if _, err := ParseIPFamily("ipx"); err == nil {
+		t.Error("Expected error for invalid family")
+	}
+}
+
+func TestParseIP(t *testing.T) {
+	addr, err := ParseIP(" 2001:db8::1\n", IPFamilyAny)
+	if err != nil {
+		t.Fatalf("ParseIP returned error for IPv6: %v", err)
+	}
+	if !addr.Is6() || addr.String() != "2001:db8::1" {
+		t.Errorf("Unexpected IPv6 parse result: %s", addr)
+	}
+
+	// IPv4 映射地址应还原为 IPv4
+	addr, err = ParseIP("::ffff:203.0.113.5", IPFamilyV4)
+	if err != nil {
+		t.Fatalf("ParseIP returned error for mapped IPv4: %v", err)
+	}
+	if addr.String() != "203.0.113.5" {
+		t.Errorf("Expected 203.0.113.5, got %s", addr)
+	}
+
+	if _, err := ParseIP("203.0.113.5", IPFamilyV6); err == nil {
+		t.Error("Expected error for IPv4 address with IPv6 family")
+	}
+	if _, err := ParseIP("999.1.1.1", IPFamilyAny); err == nil {
+		t.Error("Expected error for invalid IPv4 address")
+	}
+	if _, err := ParseIP("fe80::1%eth0", IPFamilyAny); err == nil {
+		t.Error("Expected error for zoned address")
+	}
+}
+
+func TestIPFamilyNetwork(t *testing.T) {
+	if IPFamilyV4.Network("tcp") != "tcp4" {
+		t.Errorf("Expected tcp4, got %s", IPFamilyV4.Network("tcp"))
+	}
+	if IPFamilyV6.Network("udp4") != "udp6" {
+		t.Errorf("Expected udp6, got %s", IPFamilyV6.Network("udp4"))
+	}
+	if IPFamilyAny.Network("tcp") != "tcp" {
+		t.Errorf("Expected tcp, got %s", IPFamilyAny.Network("tcp"))
+	}
+}
+
+func TestTextIPProvider_Family(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "2001:db8::7")
+	}))
+	defer server.Close()
+
+	// 只支持 IPv4 的提供者不能用于查询 IPv6
+	provider := NewTextIPProvider("v4only", server.URL, IPFamilyV4, time.Second)
+	if _, err := provider.GetPublicIP(context.Background(), IPFamilyV6); err == nil {
+		t.Error("Expected error when requesting unsupported family")
+	}
+
+	// 返回的地址族与请求不一致
+	provider = NewTextIPProvider("any", server.URL, IPFamilyAny, time.Second)
+	if _, err := provider.GetPublicIP(context.Background(), IPFamilyV4); err == nil {
+		t.Error("Expected error when response family does not match")
+	}
+
+	ip, err := provider.GetPublicIP(context.Background(), IPFamilyAny)
+	if err != nil {
+		t.Fatalf("GetPublicIP returned error: %v", err)
+	}
+	if ip != "2001:db8::7" {
+		t.Errorf("Expected 2001:db8::7, got %s", ip)
+	}
+}
+
+// TestTextIPProvider_NoConnectionReuse 连续两次查询不复用连接，每次按请求的地址族重新拨号
+func TestTextIPProvider_NoConnectionReuse(t *testing.T) {
+	var dials int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "203.0.113.7")
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&dials, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	provider := NewTextIPProvider("local", server.URL, IPFamilyAny, time.Second)
+	for i := 0; i < 2; i++ {
+		if _, err := provider.GetPublicIP(context.Background(), IPFamilyV4); err != nil {
+			t.Fatalf("GetPublicIP returned error: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&dials); n != 2 {
+		t.Errorf("Expected the second lookup to dial again, got %d connections", n)
+	}
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// IPProvider 公网 IP 提供者
+type IPProvider interface {
+	// Name 返回提供者名称（用于日志）
+	Name() string
+	// GetPublicIP 获取指定地址族的公网 IP（IPFamilyAny 表示任意地址族），ctx 取消时中止请求
+	GetPublicIP(ctx context.Context, family IPFamily) (string, error)
+}
+
+// JSONIPProvider 返回 JSON 的 IP 查询接口
+// 例如 https://api.ipify.org?format=json 返回 {"ip": "1.2.3.4"}
+type JSONIPProvider struct {
+	client *resty.Client
+	name   string
+	url    string
+	field  string   // IP 所在字段，支持 a.b 形式的嵌套路径
+	family IPFamily // 该接口支持的地址族
+}
+
+// NewJSONIPProvider 创建返回 JSON 的 IP 查询接口
+func NewJSONIPProvider(name, url, field string, family IPFamily, timeout time.Duration, opts ...ProviderOption) *JSONIPProvider {
+	if field == "" {
+		field = "ip"
+	}
+	return &JSONIPProvider{
+		client: newIPClient(timeout, opts).
+			SetHeader("Accept", "application/json"),
+		name:   name,
+		url:    url,
+		field:  field,
+		family: family,
+	}
+}
+
+// Name 返回提供者名称
+func (p *JSONIPProvider) Name() string {
+	return p.name
+}
+
+// GetPublicIP 获取指定地址族的公网 IP
+func (p *JSONIPProvider) GetPublicIP(ctx context.Context, family IPFamily) (string, error) {
+	family, err := resolveFamily(p.family, family)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := p.client.R().
+		SetContext(withFamily(ctx, family)).
+		Get(p.url)
+	if err != nil {
+		return "", fmt.Errorf("获取公网 IP 失败: %v", err)
+	}
+
+	if resp.StatusCode() != http.StatusOK {
+		return "", fmt.Errorf("获取公网 IP 失败，状态码: %d", resp.StatusCode())
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(resp.Body(), &data); err != nil {
+		return "", fmt.Errorf("解析 IP 响应失败: %v", err)
+	}
+
+	// 按路径逐级查找字段
+	value := data
+	for _, key := range strings.Split(p.field, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("IP 响应中不存在字段: %s", p.field)
+		}
+		if value, ok = obj[key]; !ok {
+			return "", fmt.Errorf("IP 响应中不存在字段: %s", p.field)
+		}
+	}
+
+	ip, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("IP 响应字段 %s 不是字符串", p.field)
+	}
+
+	addr, err := ParseIP(ip, family)
+	if err != nil {
+		return "", err
+	}
+	return addr.String(), nil
+}
+
+// DNSIPProvider 通过 DNS 查询获取公网 IP
+// 例如向 resolver1.opendns.com 查询 myip.opendns.com
+type DNSIPProvider struct {
+	name     string
+	resolver string // DNS 服务器地址（host:port）
+	host     string // 查询的域名
+	family   IPFamily
+	timeout  time.Duration
+}
+
+// NewDNSIPProvider 创建基于 DNS 的 IP 查询接口
+// 类似 OpenDNS 的服务会按查询所用的地址族返回对应的公网地址
+func NewDNSIPProvider(name, resolver, host string, family IPFamily, timeout time.Duration) *DNSIPProvider {
+	if _, _, err := net.SplitHostPort(resolver); err != nil {
+		resolver = net.JoinHostPort(resolver, "53")
+	}
+	return &DNSIPProvider{
+		name:     name,
+		resolver: resolver,
+		host:     host,
+		family:   family,
+		timeout:  timeout,
+	}
+}
+
+// Name 返回提供者名称
+func (p *DNSIPProvider) Name() string {
+	return p.name
+}
+
+// GetPublicIP 获取指定地址族的公网 IP
+func (p *DNSIPProvider) GetPublicIP(ctx context.Context, family IPFamily) (string, error) {
+	family, err := resolveFamily(p.family, family)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, family.Network(network), p.resolver)
+		},
+	}
+
+	lookupNetwork := "ip"
+	switch family {
+	case IPFamilyV4:
+		lookupNetwork = "ip4"
+	case IPFamilyV6:
+		lookupNetwork = "ip6"
+	}
+
+	addrs, err := resolver.LookupNetIP(ctx, lookupNetwork, p.host)
+	if err != nil {
+		return "", fmt.Errorf("DNS 查询公网 IP 失败: %v", err)
+	}
+
+	for _, addr := range addrs {
+		if ip, err := ParseIP(addr.String(), family); err == nil {
+			return ip.String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("DNS 查询未返回有效 IP: %v", addrs)
+}
+
+// IPProviderChain 按顺序回退的 IP 提供者链
+type IPProviderChain struct {
+	providers []IPProvider
+	quorum    int // 需要多少个提供者结果一致（<=1 表示取第一个成功结果）
+}
+
+// NewIPProviderChain 创建 IP 提供者链
+func NewIPProviderChain(quorum int, providers ...IPProvider) *IPProviderChain {
+	return &IPProviderChain{
+		providers: providers,
+		quorum:    quorum,
+	}
+}
+
+// Name 返回提供者名称
+func (c *IPProviderChain) Name() string {
+	names := make([]string, 0, len(c.providers))
+	for _, p := range c.providers {
+		names = append(names, p.Name())
+	}
+	return "chain(" + strings.Join(names, ",") + ")"
+}
+
+// GetPublicIP 依次尝试各个提供者获取指定地址族的公网 IP
+// 仲裁模式下，只有当足够多的提供者返回相同 IP 时才采信；ctx 取消后不再尝试后续的提供者
+func (c *IPProviderChain) GetPublicIP(ctx context.Context, family IPFamily) (string, error) {
+	if len(c.providers) == 0 {
+		return "", fmt.Errorf("未配置任何 IP 提供者")
+	}
+
+	quorum := c.quorum
+	if quorum < 1 {
+		quorum = 1
+	}
+
+	votes := make(map[string]int)
+	var errs []string
+	for _, p := range c.providers {
+		if err := ctx.Err(); err != nil {
+			return "", fmt.Errorf("获取公网 IP 已取消: %v", err)
+		}
+		ip, err := p.GetPublicIP(ctx, family)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+
+		votes[ip]++
+		if votes[ip] >= quorum {
+			return ip, nil
+		}
+	}
+
+	if len(votes) > 0 {
+		return "", fmt.Errorf("IP 提供者结果未达成一致 (需要 %d 个一致): %v", quorum, votes)
+	}
+	return "", fmt.Errorf("所有 IP 提供者均失败: %s", strings.Join(errs, "; "))
+}
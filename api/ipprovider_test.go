@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// staticIPProvider 返回固定结果的 IP 提供者
+type staticIPProvider struct {
+	name  string
+	ip    string
+	err   error
+	calls int
+}
+
+func (p *staticIPProvider) Name() string { return p.name }
+
+func (p *staticIPProvider) GetPublicIP(_ context.Context, family IPFamily) (string, error) {
+	p.calls++
+	return p.ip, p.err
+}
+
+func TestTextIPProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, " 203.0.113.7\n")
+	}))
+	defer server.Close()
+
+	provider := NewTextIPProvider("local", server.URL, IPFamilyAny, time.Second)
+	ip, err := provider.GetPublicIP(context.Background(), IPFamilyAny)
+	if err != nil {
+		t.Fatalf("GetPublicIP returned error: %v", err)
+	}
+	if ip != "203.0.113.7" {
+		t.Errorf("Expected IP 203.0.113.7, got %s", ip)
+	}
+	if provider.Name() != "local" {
+		t.Errorf("Expected name local, got %s", provider.Name())
+	}
+}
+
+func TestTextIPProvider_InvalidResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html>blocked</html>")
+	}))
+	defer server.Close()
+
+	provider := NewTextIPProvider("local", server.URL, IPFamilyAny, time.Second)
+	if _, err := provider.GetPublicIP(context.Background(), IPFamilyAny); err == nil {
+		t.Error("Expected error for invalid IP response")
+	}
+}
+
+func TestTextIPProvider_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprint(w, "203.0.113.7")
+	}))
+	defer server.Close()
+
+	provider := NewTextIPProvider("slow", server.URL, IPFamilyAny, 50*time.Millisecond)
+	if _, err := provider.GetPublicIP(context.Background(), IPFamilyAny); err == nil {
+		t.Error("Expected timeout error")
+	}
+}
+
+func TestJSONIPProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"result": {"address": "198.51.100.9"}, "ip": 1}`)
+	}))
+	defer server.Close()
+
+	provider := NewJSONIPProvider("json", server.URL, "result.address", IPFamilyAny, time.Second)
+	ip, err := provider.GetPublicIP(context.Background(), IPFamilyAny)
+	if err != nil {
+		t.Fatalf("GetPublicIP returned error: %v", err)
+	}
+	if ip != "198.51.100.9" {
+		t.Errorf("Expected IP 198.51.100.9, got %s", ip)
+	}
+
+	// 字段不是字符串
+	provider = NewJSONIPProvider("json", server.URL, "ip", IPFamilyAny, time.Second)
+	if _, err := provider.GetPublicIP(context.Background(), IPFamilyAny); err == nil {
+		t.Error("Expected error for non-string field")
+	}
+
+	// 字段不存在
+	provider = NewJSONIPProvider("json", server.URL, "missing.field", IPFamilyAny, time.Second)
+	if _, err := provider.GetPublicIP(context.Background(), IPFamilyAny); err == nil {
+		t.Error("Expected error for missing field")
+	}
+}
+
+func TestIPProviderChain_Fallback(t *testing.T) {
+	failing := &staticIPProvider{name: "failing", err: fmt.Errorf("blocked")}
+	working := &staticIPProvider{name: "working", ip: "203.0.113.1"}
+	unused := &staticIPProvider{name: "unused", ip: "203.0.113.2"}
+
+	chain := NewIPProviderChain(1, failing, working, unused)
+	ip, err := chain.GetPublicIP(context.Background(), IPFamilyAny)
+	if err != nil {
+		t.Fatalf("GetPublicIP returned error: %v", err)
+	}
+	if ip != "203.0.113.1" {
+		t.Errorf("Expected IP from second provider, got %s", ip)
+	}
+	if unused.calls != 0 {
+		t.Error("Providers after the first success should not be queried")
+	}
+}
+
+func TestIPProviderChain_AllFailed(t *testing.T) {
+	chain := NewIPProviderChain(1,
+		&staticIPProvider{name: "a", err: fmt.Errorf("timeout")},
+		&staticIPProvider{name: "b", err: fmt.Errorf("refused")},
+	)
+	if _, err := chain.GetPublicIP(context.Background(), IPFamilyAny); err == nil {
+		t.Error("Expected error when all providers fail")
+	}
+
+	if _, err := NewIPProviderChain(1).GetPublicIP(context.Background(), IPFamilyAny); err == nil {
+		t.Error("Expected error for empty chain")
+	}
+}
+
+func TestIPProviderChain_Quorum(t *testing.T) {
+	// 前两个结果不一致，第三个与第一个一致
+	a := &staticIPProvider{name: "a", ip: "203.0.113.1"}
+	b := &staticIPProvider{name: "b", ip: "203.0.113.2"}
+	c := &staticIPProvider{name: "c", ip: "203.0.113.1"}
+
+	chain := NewIPProviderChain(2, a, b, c)
+	ip, err := chain.GetPublicIP(context.Background(), IPFamilyAny)
+	if err != nil {
+		t.Fatalf("GetPublicIP returned error: %v", err)
+	}
+	if ip != "203.0.113.1" {
+		t.Errorf("Expected quorum IP 203.0.113.1, got %s", ip)
+	}
+
+	// 无法达成一致
+	chain = NewIPProviderChain(2, a, b)
+	if _, err := chain.GetPublicIP(context.Background(), IPFamilyAny); err == nil {
+		t.Error("Expected error when quorum is not reached")
+	}
+}
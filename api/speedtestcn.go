@@ -1,10 +1,12 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -12,58 +14,193 @@ import (
 
 // SpeedTestCNClient speedtest.cn API 客户端
 type SpeedTestCNClient struct {
-	client  *resty.Client
-	bindIP  string // 绑定的 IP 地址
+	query         *resty.Client // 提速查询接口使用的客户端
+	reopen        *resty.Client // 重新开启提速接口使用的客户端（不重试）
+	http          *HTTPClientFactory
+	queryTimeout  time.Duration
+	reopenTimeout time.Duration
+	bindIP        string     // 绑定的 IP 地址
+	bindAddr      netip.Addr // 解析后的绑定地址，未绑定 IP 时为零值
+	bindInterface string     // 绑定的网络接口（每次拨号时解析地址）
+	family        IPFamily   // 请求使用的地址族
+	queryURL      string
+	reopenURL     string
+	roundTripper  http.RoundTripper // 注入的 RoundTripper，设置后不再按绑定和地址族定制 Transport
+	transport     *http.Transport
+}
+
+// DefaultSpeedTestCNTimeout speedtest.cn 接口默认的请求超时时间
+const DefaultSpeedTestCNTimeout = 30 * time.Second
+
+// speedtest.cn 接口的默认地址
+const (
+	DefaultQueryURL  = "https://tisu-api-v3.speedtest.cn/speedUp/query"
+	DefaultReopenURL = "https://tisu-api.speedtest.cn/api/v2/speedup/reopen"
+)
+
+// ClientOption speedtest.cn 客户端选项
+type ClientOption func(*SpeedTestCNClient)
+
+// WithIPFamily 限定请求使用的地址族（IPv4、IPv6 或任意）
+func WithIPFamily(family IPFamily) ClientOption {
+	return func(c *SpeedTestCNClient) {
+		c.family = family
+	}
+}
+
+// WithBindInterface 绑定到指定网络接口
+// 每次建立连接时解析接口当前地址，适用于 PPPoE 等地址会变化的链路；
+// 接口没有可用地址时在 Linux 上回退为 SO_BINDTODEVICE
+func WithBindInterface(interfaceName string) ClientOption {
+	return func(c *SpeedTestCNClient) {
+		c.bindInterface = interfaceName
+	}
+}
+
+// WithHTTPClientFactory 使用共享的 HTTP 客户端工厂（代理、证书、请求头和重试策略）
+func WithHTTPClientFactory(f *HTTPClientFactory) ClientOption {
+	return func(c *SpeedTestCNClient) {
+		c.http = f
+	}
+}
+
+// WithTimeouts 分别设置提速查询和重新开启提速接口的请求超时时间，0 表示使用默认值
+func WithTimeouts(query, reopen time.Duration) ClientOption {
+	return func(c *SpeedTestCNClient) {
+		if query > 0 {
+			c.queryTimeout = query
+		}
+		if reopen > 0 {
+			c.reopenTimeout = reopen
+		}
+	}
+}
+
+// WithEndpoints 设置提速查询和重新开启提速接口的地址，为空时使用默认地址
+// 可指向镜像或本地的模拟服务
+func WithEndpoints(queryURL, reopenURL string) ClientOption {
+	return func(c *SpeedTestCNClient) {
+		if queryURL != "" {
+			c.queryURL = queryURL
+		}
+		if reopenURL != "" {
+			c.reopenURL = reopenURL
+		}
+	}
+}
+
+// WithTransport 使用指定的 RoundTripper 发送请求（如测试中的模拟实现）
+// 设置后绑定 IP、绑定接口、地址族以及 HTTP 客户端工厂的代理和证书配置不再生效
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *SpeedTestCNClient) {
+		c.roundTripper = rt
+	}
 }
 
 // NewSpeedTestCNClient 创建新的 speedtest.cn API 客户端
-func NewSpeedTestCNClient(bindIP string) *SpeedTestCNClient {
-	client := resty.New().
-		SetTimeout(30 * time.Second).
-		SetHeader("User-Agent", "SpeedTestUp/1.0")
+// bindIP 无法解析或与地址族不符时返回错误
+func NewSpeedTestCNClient(bindIP string, opts ...ClientOption) (*SpeedTestCNClient, error) {
+	c := &SpeedTestCNClient{
+		http:          DefaultHTTPClientFactory(),
+		queryTimeout:  DefaultSpeedTestCNTimeout,
+		reopenTimeout: DefaultSpeedTestCNTimeout,
+		bindIP:        bindIP,
+		family:        IPFamilyAny,
+		queryURL:      DefaultQueryURL,
+		reopenURL:     DefaultReopenURL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
 
-	// 如果设置了绑定 IP，创建自定义的 Transport
 	if bindIP != "" {
-		// 解析绑定的 IP 地址
-		localAddr, err := net.ResolveTCPAddr("tcp", bindIP+":0")
+		addr, err := ParseIP(bindIP, c.family)
 		if err != nil {
-			// 如果解析失败，使用默认配置
-			fmt.Printf("Warning: Failed to parse bind IP %s: %v\n", bindIP, err)
-		} else {
-			// 创建自定义的 Dialer
-			dialer := &net.Dialer{
-				LocalAddr: localAddr,
-			}
-
-			// 设置自定义的 Transport
-			transport := &http.Transport{
-				DialContext: dialer.DialContext,
-			}
-
-			client.SetTransport(transport)
+			return nil, fmt.Errorf("绑定 IP 无效: %v", err)
 		}
+		c.bindAddr = addr
 	}
 
-	return &SpeedTestCNClient{
-		client: client,
-		bindIP: bindIP,
+	// 两个接口共用同一个按绑定 IP、绑定接口和地址族定制的 Transport
+	c.transport = c.newTransport()
+	// 重新开启提速不是幂等请求，不做传输层重试，失败后由自动恢复按 min_reopen_interval 重新执行
+	c.query = c.http.Client(c.queryTimeout, c.transport)
+	c.reopen = c.http.SingleAttemptClient(c.reopenTimeout, c.transport)
+	if c.roundTripper != nil {
+		c.query.SetTransport(c.roundTripper)
+		c.reopen.SetTransport(c.roundTripper)
 	}
+
+	return c, nil
+}
+
+// Rebind 关闭空闲连接
+// 绑定接口的地址变化后调用，丢弃仍绑定在旧地址上的连接；拨号时按接口当前地址绑定，
+// 新连接自动使用新地址，因此 Transport 保持不变，可与正在进行的请求并发调用
+func (c *SpeedTestCNClient) Rebind() {
+	if c.roundTripper != nil {
+		return
+	}
+	c.transport.CloseIdleConnections()
+}
+
+// newTransport 根据绑定 IP、绑定接口和地址族创建 Transport
+func (c *SpeedTestCNClient) newTransport() *http.Transport {
+	return c.http.Transport(c.dialer())
+}
+
+// dialer 根据绑定 IP、绑定接口和地址族创建拨号函数，无需定制时返回 nil
+func (c *SpeedTestCNClient) dialer() func(ctx context.Context, network, address string) (net.Conn, error) {
+	if c.bindIP == "" && c.bindInterface != "" {
+		return c.dialInterface
+	}
+
+	dialer := &net.Dialer{}
+	family := c.family
+
+	if c.bindAddr.IsValid() {
+		dialer.LocalAddr = &net.TCPAddr{IP: c.bindAddr.AsSlice()}
+		// 绑定地址决定了可用的地址族
+		family = familyOf(c.bindAddr)
+	}
+
+	if dialer.LocalAddr == nil && family == IPFamilyAny {
+		return nil
+	}
+
+	return familyDialer(dialer, family)
+}
+
+// dialInterface 按绑定接口的当前地址拨号
+func (c *SpeedTestCNClient) dialInterface(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	family := c.family
+
+	addr, err := InterfaceAddr(c.bindInterface, family)
+	switch {
+	case err == nil:
+		dialer.LocalAddr = &net.TCPAddr{IP: addr.AsSlice()}
+		family = familyOf(addr)
+	case bindToDeviceSupported:
+		// 接口暂无可用地址（如 PPPoE 重拨中），直接绑定到设备
+		dialer.Control = bindToDevice(c.bindInterface)
+	default:
+		return nil, fmt.Errorf("解析绑定接口地址失败: %v", err)
+	}
+
+	return dialer.DialContext(ctx, family.Network(network), address)
 }
 
 // QuerySpeedupStatus 查询提速状态
 // 对应 luci-app-broadbandacc 中的 $_http_cmd
-func (c *SpeedTestCNClient) QuerySpeedupStatus() (*SpeedupQueryResponse, error) {
-	url := "https://tisu-api-v3.speedtest.cn/speedUp/query"
+// ctx 取消时中止请求（包括临时性失败的重试等待）
+func (c *SpeedTestCNClient) QuerySpeedupStatus(ctx context.Context) (*SpeedupQueryResponse, error) {
+	url := c.queryURL
 
-	req := c.client.R().
+	req := c.query.R().
+		SetContext(ctx).
 		SetHeader("Content-Type", "application/json")
 
-	// 打印调试信息
-	// fmt.Printf("[DEBUG] QuerySpeedupStatus URL: %s\n", url)
-	// if c.bindIP != "" {
-	// 	fmt.Printf("[DEBUG] 使用绑定的IP: %s\n", c.bindIP)
-	// }
-
 	resp, err := req.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("请求提速查询接口失败: %v", err)
@@ -84,10 +221,12 @@ func (c *SpeedTestCNClient) QuerySpeedupStatus() (*SpeedupQueryResponse, error)
 
 // ReopenSpeedup 重新开启提速
 // 对应 luci-app-broadbandacc 中的 $_http_cmd2
-func (c *SpeedTestCNClient) ReopenSpeedup() (*SpeedupReopenResponse, error) {
-	url := "https://tisu-api.speedtest.cn/api/v2/speedup/reopen"
+// ctx 取消时中止请求（包括临时性失败的重试等待）
+func (c *SpeedTestCNClient) ReopenSpeedup(ctx context.Context) (*SpeedupReopenResponse, error) {
+	url := c.reopenURL
 
-	req := c.client.R().
+	req := c.reopen.R().
+		SetContext(ctx).
 		SetHeader("Content-Type", "application/json")
 
 	resp, err := req.Get(url)
@@ -113,27 +252,27 @@ type SpeedupQueryResponse struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Data    struct {
-		IP          string `json:"ip"`          // 出口 IP 地址
-		UpdatedAt   string `json:"updatedAt"`   // 提速开始时间
-		CanSpeed    int    `json:"canSpeed"`    // 是否可以提速 (0: 不支持, 1: 支持)
-		Download    int    `json:"download"`    // 下行带宽 (Mbps)
-		DownExpire  string `json:"downExpire"`  // 下行提速截止时间
+		IP          string      `json:"ip"`          // 出口 IP 地址
+		UpdatedAt   string      `json:"updatedAt"`   // 提速开始时间
+		CanSpeed    int         `json:"canSpeed"`    // 是否可以提速 (0: 不支持, 1: 支持)
+		Download    int         `json:"download"`    // 下行带宽 (Mbps)
+		DownExpire  string      `json:"downExpire"`  // 下行提速截止时间
 		DownExpireT interface{} `json:"downExpireT"` // 下行提速截止时间 (时间戳，可能是数字、字符串或bool)
 
 		// 上行带宽信息
-		TargetUpH     int    `json:"targetUpH"`     // 一类上行带宽 (Kbps)
-		UpHExpire     string `json:"upHExpire"`     // 一类上行带宽提速截止时间
-		UpHExpireT    interface{} `json:"upHExpireT"`    // 一类上行带宽提速截止时间 (时间戳)
+		TargetUpH  int         `json:"targetUpH"`  // 一类上行带宽 (Kbps)
+		UpHExpire  string      `json:"upHExpire"`  // 一类上行带宽提速截止时间
+		UpHExpireT interface{} `json:"upHExpireT"` // 一类上行带宽提速截止时间 (时间戳)
 
-		TargetUp100   int    `json:"targetUp100"`   // 二类上行带宽 (Kbps)
-		Up100Expire   string `json:"up100Expire"`   // 二类上行带宽提速截止时间
-		Up100ExpireT  interface{} `json:"up100ExpireT"`  // 二类上行带宽提速截止时间 (时间戳)
+		TargetUp100  int         `json:"targetUp100"`  // 二类上行带宽 (Kbps)
+		Up100Expire  string      `json:"up100Expire"`  // 二类上行带宽提速截止时间
+		Up100ExpireT interface{} `json:"up100ExpireT"` // 二类上行带宽提速截止时间 (时间戳)
 
 		// 套餐信息
-		DownUp50Expire  string `json:"downUp50Expire"`  // 一类套餐带宽上行+下行提速截止时间
+		DownUp50Expire  string      `json:"downUp50Expire"`  // 一类套餐带宽上行+下行提速截止时间
 		DownUp50ExpireT interface{} `json:"downUp50ExpireT"` // 一类套餐带宽上行+下行提速截止时间 (时间戳)
 
-		DownUpExpire  string `json:"downUpExpire"`  // 二类套餐带宽上行+下行提速截止时间
+		DownUpExpire  string      `json:"downUpExpire"`  // 二类套餐带宽上行+下行提速截止时间
 		DownUpExpireT interface{} `json:"downUpExpireT"` // 二类套餐带宽上行+下行提速截止时间 (时间戳)
 	} `json:"data"`
 }
@@ -147,6 +286,12 @@ type SpeedupReopenResponse struct {
 	} `json:"data"`
 }
 
+// Accepted 重新开启提速请求是否被受理
+// 错误码 10002 表示操作过于频繁，但提速请求已受理
+func (r *SpeedupReopenResponse) Accepted() bool {
+	return r.Code == 0 || r.Code == 10002
+}
+
 // IsSpeedupAvailable 检查是否可以提速
 func (r *SpeedupQueryResponse) IsSpeedupAvailable() bool {
 	return r.Data.CanSpeed == 1
@@ -167,15 +312,20 @@ func (r *SpeedupQueryResponse) GetUp100Bandwidth() int {
 	return r.Data.TargetUp100 / 1024
 }
 
-// IsDownloadSpeedupActive 检查下行提速是否激活
+// IsDownloadSpeedupActive 检查下行提速当前是否激活
 func (r *SpeedupQueryResponse) IsDownloadSpeedupActive() (bool, error) {
+	return r.IsDownloadSpeedupActiveAt(time.Now())
+}
+
+// IsDownloadSpeedupActiveAt 检查下行提速在 now 时是否激活
+func (r *SpeedupQueryResponse) IsDownloadSpeedupActiveAt(now time.Time) (bool, error) {
 	// 检查下行提速
 	if r.Data.DownExpireT != "false" {
 		expireTime, err := parseTimestamp(r.Data.DownExpireT)
 		if err != nil {
 			return false, err
 		}
-		if time.Now().Before(expireTime) {
+		if now.Before(expireTime) {
 			return true, nil
 		}
 	}
@@ -186,7 +336,7 @@ func (r *SpeedupQueryResponse) IsDownloadSpeedupActive() (bool, error) {
 		if err != nil {
 			return false, err
 		}
-		if time.Now().Before(expireTime) {
+		if now.Before(expireTime) {
 			return true, nil
 		}
 	}
@@ -197,7 +347,7 @@ func (r *SpeedupQueryResponse) IsDownloadSpeedupActive() (bool, error) {
 		if err != nil {
 			return false, err
 		}
-		if time.Now().Before(expireTime) {
+		if now.Before(expireTime) {
 			return true, nil
 		}
 	}
@@ -205,15 +355,20 @@ func (r *SpeedupQueryResponse) IsDownloadSpeedupActive() (bool, error) {
 	return false, nil
 }
 
-// IsUpSpeedupActive 检查上行提速是否激活
+// IsUpSpeedupActive 检查上行提速当前是否激活
 func (r *SpeedupQueryResponse) IsUpSpeedupActive() (bool, error) {
+	return r.IsUpSpeedupActiveAt(time.Now())
+}
+
+// IsUpSpeedupActiveAt 检查上行提速在 now 时是否激活
+func (r *SpeedupQueryResponse) IsUpSpeedupActiveAt(now time.Time) (bool, error) {
 	// 检查一类上行提速
 	if r.Data.UpHExpireT != "false" {
 		expireTime, err := parseTimestamp(r.Data.UpHExpireT)
 		if err != nil {
 			return false, err
 		}
-		if time.Now().Before(expireTime) {
+		if now.Before(expireTime) {
 			return true, nil
 		}
 	}
@@ -224,7 +379,7 @@ func (r *SpeedupQueryResponse) IsUpSpeedupActive() (bool, error) {
 		if err != nil {
 			return false, err
 		}
-		if time.Now().Before(expireTime) {
+		if now.Before(expireTime) {
 			return true, nil
 		}
 	}
@@ -235,7 +390,7 @@ func (r *SpeedupQueryResponse) IsUpSpeedupActive() (bool, error) {
 		if err != nil {
 			return false, err
 		}
-		if time.Now().Before(expireTime) {
+		if now.Before(expireTime) {
 			return true, nil
 		}
 	}
@@ -246,7 +401,7 @@ func (r *SpeedupQueryResponse) IsUpSpeedupActive() (bool, error) {
 		if err != nil {
 			return false, err
 		}
-		if time.Now().Before(expireTime) {
+		if now.Before(expireTime) {
 			return true, nil
 		}
 	}
@@ -313,3 +468,74 @@ func parseTimestamp(timestampVal interface{}) (time.Time, error) {
 	return timestamp, nil
 }
 
+// 提速截止时间类型
+const (
+	ExpiryDown     = "down"       // 下行提速
+	ExpiryUpH      = "up_h"       // 一类上行提速
+	ExpiryUp100    = "up_100"     // 二类上行提速
+	ExpiryDownUp50 = "down_up_50" // 一类套餐
+	ExpiryDownUp   = "down_up"    // 二类套餐
+)
+
+// ExpiryKinds 返回与下行、上行提速相关的截止时间类型
+// 套餐同时包含上行和下行提速，任一方向启用时都会计入
+func ExpiryKinds(down, up bool) []string {
+	var kinds []string
+	if down {
+		kinds = append(kinds, ExpiryDown)
+	}
+	if up {
+		kinds = append(kinds, ExpiryUpH, ExpiryUp100)
+	}
+	if down || up {
+		kinds = append(kinds, ExpiryDownUp50, ExpiryDownUp)
+	}
+	return kinds
+}
+
+// NextExpiry 获取 now 之后最早的提速截止时间，没有未过期的提速时返回 false
+// 指定 kinds 时只考虑这些类型的截止时间
+func (r *SpeedupQueryResponse) NextExpiry(now time.Time, kinds ...string) (string, time.Time, bool) {
+	allowed := make(map[string]bool, len(kinds))
+	for _, k := range kinds {
+		allowed[k] = true
+	}
+
+	var (
+		kind     string
+		earliest time.Time
+	)
+	for k, expireTime := range r.Expiries() {
+		if len(allowed) > 0 && !allowed[k] {
+			continue
+		}
+		if !expireTime.After(now) {
+			continue
+		}
+		if earliest.IsZero() || expireTime.Before(earliest) || (expireTime.Equal(earliest) && k < kind) {
+			kind, earliest = k, expireTime
+		}
+	}
+	return kind, earliest, !earliest.IsZero()
+}
+
+// Expiries 获取各项提速的截止时间（未开通或无法解析的项不包含在内）
+func (r *SpeedupQueryResponse) Expiries() map[string]time.Time {
+	values := map[string]interface{}{
+		ExpiryDown:     r.Data.DownExpireT,
+		ExpiryUpH:      r.Data.UpHExpireT,
+		ExpiryUp100:    r.Data.Up100ExpireT,
+		ExpiryDownUp50: r.Data.DownUp50ExpireT,
+		ExpiryDownUp:   r.Data.DownUpExpireT,
+	}
+
+	expiries := make(map[string]time.Time, len(values))
+	for kind, value := range values {
+		expireTime, err := parseTimestamp(value)
+		if err != nil || expireTime.IsZero() {
+			continue
+		}
+		expiries[kind] = expireTime
+	}
+	return expiries
+}
@@ -1,17 +1,34 @@
 package api
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
+// mustNewClient 创建 speedtest.cn 客户端，失败时终止测试
+func mustNewClient(t *testing.T, bindIP string, opts ...ClientOption) *SpeedTestCNClient {
+	t.Helper()
+	client, err := NewSpeedTestCNClient(bindIP, opts...)
+	if err != nil {
+		t.Fatalf("NewSpeedTestCNClient returned error: %v", err)
+	}
+	return client
+}
+
 func TestNewSpeedTestCNClient(t *testing.T) {
-	client := NewSpeedTestCNClient("192.168.1.1")
+	client := mustNewClient(t, "192.168.1.1")
 	if client == nil {
 		t.Fatal("NewSpeedTestCNClient should not return nil")
 	}
 
-	if client.client == nil {
-		t.Fatal("SpeedTestCNClient client should not be nil")
+	if client.query == nil || client.reopen == nil {
+		t.Fatal("SpeedTestCNClient clients should not be nil")
 	}
 
 	if client.bindIP != "192.168.1.1" {
@@ -22,22 +39,22 @@ func TestNewSpeedTestCNClient(t *testing.T) {
 func TestSpeedupQueryResponse_IsSpeedupAvailable(t *testing.T) {
 	resp := &SpeedupQueryResponse{
 		Data: struct {
-			IP            string      `json:"ip"`
-			UpdatedAt     string      `json:"updatedAt"`
-			CanSpeed      int         `json:"canSpeed"`
-			Download      int         `json:"download"`
-			DownExpire    string      `json:"downExpire"`
-			DownExpireT   interface{} `json:"downExpireT"`
-			TargetUpH     int         `json:"targetUpH"`
-			UpHExpire     string      `json:"upHExpire"`
-			UpHExpireT    interface{} `json:"upHExpireT"`
-			TargetUp100   int         `json:"targetUp100"`
-			Up100Expire   string      `json:"up100Expire"`
-			Up100ExpireT  interface{} `json:"up100ExpireT"`
+			IP              string      `json:"ip"`
+			UpdatedAt       string      `json:"updatedAt"`
+			CanSpeed        int         `json:"canSpeed"`
+			Download        int         `json:"download"`
+			DownExpire      string      `json:"downExpire"`
+			DownExpireT     interface{} `json:"downExpireT"`
+			TargetUpH       int         `json:"targetUpH"`
+			UpHExpire       string      `json:"upHExpire"`
+			UpHExpireT      interface{} `json:"upHExpireT"`
+			TargetUp100     int         `json:"targetUp100"`
+			Up100Expire     string      `json:"up100Expire"`
+			Up100ExpireT    interface{} `json:"up100ExpireT"`
 			DownUp50Expire  string      `json:"downUp50Expire"`
 			DownUp50ExpireT interface{} `json:"downUp50ExpireT"`
-			DownUpExpire  string      `json:"downUpExpire"`
-			DownUpExpireT interface{} `json:"downUpExpireT"`
+			DownUpExpire    string      `json:"downUpExpire"`
+			DownUpExpireT   interface{} `json:"downUpExpireT"`
 		}{
 			CanSpeed: 1,
 		},
@@ -56,26 +73,26 @@ func TestSpeedupQueryResponse_IsSpeedupAvailable(t *testing.T) {
 func TestSpeedupQueryResponse_GetBandwidth(t *testing.T) {
 	resp := &SpeedupQueryResponse{
 		Data: struct {
-			IP            string      `json:"ip"`
-			UpdatedAt     string      `json:"updatedAt"`
-			CanSpeed      int         `json:"canSpeed"`
-			Download      int         `json:"download"`
-			DownExpire    string      `json:"downExpire"`
-			DownExpireT   interface{} `json:"downExpireT"`
-			TargetUpH     int         `json:"targetUpH"`
-			UpHExpire     string      `json:"upHExpire"`
-			UpHExpireT    interface{} `json:"upHExpireT"`
-			TargetUp100   int         `json:"targetUp100"`
-			Up100Expire   string      `json:"up100Expire"`
-			Up100ExpireT  interface{} `json:"up100ExpireT"`
+			IP              string      `json:"ip"`
+			UpdatedAt       string      `json:"updatedAt"`
+			CanSpeed        int         `json:"canSpeed"`
+			Download        int         `json:"download"`
+			DownExpire      string      `json:"downExpire"`
+			DownExpireT     interface{} `json:"downExpireT"`
+			TargetUpH       int         `json:"targetUpH"`
+			UpHExpire       string      `json:"upHExpire"`
+			UpHExpireT      interface{} `json:"upHExpireT"`
+			TargetUp100     int         `json:"targetUp100"`
+			Up100Expire     string      `json:"up100Expire"`
+			Up100ExpireT    interface{} `json:"up100ExpireT"`
 			DownUp50Expire  string      `json:"downUp50Expire"`
 			DownUp50ExpireT interface{} `json:"downUp50ExpireT"`
-			DownUpExpire  string      `json:"downUpExpire"`
-			DownUpExpireT interface{} `json:"downUpExpireT"`
+			DownUpExpire    string      `json:"downUpExpire"`
+			DownUpExpireT   interface{} `json:"downUpExpireT"`
 		}{
-			Download:     100,
-			TargetUpH:    2048,
-			TargetUp100:  5120,
+			Download:    100,
+			TargetUpH:   2048,
+			TargetUp100: 5120,
 		},
 	}
 
@@ -107,3 +124,201 @@ func TestSpeedupReopenResponse(t *testing.T) {
 		t.Error("SpeedupReopenResponse fields do not match expected values")
 	}
 }
+
+func TestNewSpeedTestCNClient_Family(t *testing.T) {
+	// 未绑定 IP 且地址族为 any 时使用默认拨号
+	client := mustNewClient(t, "")
+	if client.family != IPFamilyAny {
+		t.Errorf("Expected default family any, got %s", client.family)
+	}
+	if client.dialer() != nil {
+		t.Error("Expected no custom dialer without binding")
+	}
+
+	// 限定地址族时需要自定义拨号
+	client = mustNewClient(t, "", WithIPFamily(IPFamilyV6))
+	if client.dialer() == nil {
+		t.Error("Expected custom dialer when family is forced")
+	}
+
+	// 绑定 IPv6 地址
+	client = mustNewClient(t, "2001:db8::1")
+	if client.dialer() == nil || client.newTransport().DialContext == nil {
+		t.Error("Expected custom dialer for IPv6 bind address")
+	}
+}
+
+func TestNewSpeedTestCNClient_InvalidBindIP(t *testing.T) {
+	if _, err := NewSpeedTestCNClient("not-an-ip"); err == nil {
+		t.Error("Expected error for unparsable bind IP")
+	}
+	if _, err := NewSpeedTestCNClient("2001:db8::1", WithIPFamily(IPFamilyV4)); err == nil {
+		t.Error("Expected error for bind IP outside the address family")
+	}
+}
+
+func TestSpeedupQueryResponse_Expiries(t *testing.T) {
+	resp := &SpeedupQueryResponse{}
+	resp.Data.DownExpireT = float64(1700000000)
+	resp.Data.UpHExpireT = "false"
+	resp.Data.Up100ExpireT = false
+	resp.Data.DownUp50ExpireT = "2024-01-01 08:00:00"
+	resp.Data.DownUpExpireT = nil
+
+	expiries := resp.Expiries()
+	if len(expiries) != 2 {
+		t.Fatalf("Expected 2 expiries, got %v", expiries)
+	}
+	if !expiries[ExpiryDown].Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Unexpected down expiry: %v", expiries[ExpiryDown])
+	}
+	if !expiries[ExpiryDownUp50].Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected down_up_50 expiry: %v", expiries[ExpiryDownUp50])
+	}
+}
+
+func TestSpeedupQueryResponse_NextExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	resp := &SpeedupQueryResponse{}
+	resp.Data.DownExpireT = float64(now.Add(-time.Hour).Unix()) // 已过期
+	resp.Data.UpHExpireT = float64(now.Add(3 * time.Hour).Unix())
+	resp.Data.Up100ExpireT = float64(now.Add(2 * time.Hour).Unix())
+	resp.Data.DownUpExpireT = "false"
+
+	kind, expireTime, ok := resp.NextExpiry(now)
+	if !ok {
+		t.Fatal("Expected an upcoming expiry")
+	}
+	if kind != ExpiryUp100 || !expireTime.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("Expected up_100 at %v, got %s at %v", now.Add(2*time.Hour), kind, expireTime)
+	}
+
+	if _, _, ok := resp.NextExpiry(now.Add(4 * time.Hour)); ok {
+		t.Error("Expected no upcoming expiry after all expired")
+	}
+}
+
+func TestSpeedupQueryResponse_ActiveAt(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	resp := &SpeedupQueryResponse{}
+	resp.Data.DownExpireT = float64(now.Add(time.Hour).Unix())
+	resp.Data.UpHExpireT = "false"
+	resp.Data.Up100ExpireT = false
+	resp.Data.DownUp50ExpireT = "false"
+	resp.Data.DownUpExpireT = float64(now.Add(2 * time.Hour).Unix())
+
+	tests := []struct {
+		at       time.Time
+		down, up bool
+	}{
+		{now, true, true},
+		{now.Add(90 * time.Minute), true, true}, // 下行已过期，二类套餐仍有效
+		{now.Add(2 * time.Hour), false, false},
+	}
+	for _, tt := range tests {
+		down, err := resp.IsDownloadSpeedupActiveAt(tt.at)
+		if err != nil {
+			t.Fatalf("IsDownloadSpeedupActiveAt returned error: %v", err)
+		}
+		up, err := resp.IsUpSpeedupActiveAt(tt.at)
+		if err != nil {
+			t.Fatalf("IsUpSpeedupActiveAt returned error: %v", err)
+		}
+		if down != tt.down || up != tt.up {
+			t.Errorf("At %v: expected down=%v up=%v, got down=%v up=%v", tt.at.Sub(now), tt.down, tt.up, down, up)
+		}
+	}
+}
+
+func TestExpiryKinds(t *testing.T) {
+	if kinds := ExpiryKinds(true, false); len(kinds) != 3 || kinds[0] != ExpiryDown {
+		t.Errorf("Unexpected down-only kinds: %v", kinds)
+	}
+	if kinds := ExpiryKinds(false, true); len(kinds) != 4 || kinds[0] != ExpiryUpH {
+		t.Errorf("Unexpected up-only kinds: %v", kinds)
+	}
+	if kinds := ExpiryKinds(false, false); len(kinds) != 0 {
+		t.Errorf("Expected no kinds, got %v", kinds)
+	}
+
+	// 只考虑指定类型的截止时间
+	now := time.Unix(1700000000, 0)
+	resp := &SpeedupQueryResponse{}
+	resp.Data.UpHExpireT = float64(now.Add(time.Hour).Unix())
+	resp.Data.DownExpireT = float64(now.Add(2 * time.Hour).Unix())
+	kind, _, ok := resp.NextExpiry(now, ExpiryKinds(true, false)...)
+	if !ok || kind != ExpiryDown {
+		t.Errorf("Expected down expiry for down-only kinds, got %s (ok=%v)", kind, ok)
+	}
+}
+
+func TestSpeedTestCNClient_Endpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/query":
+			fmt.Fprint(w, `{"code":0,"data":{"ip":"203.0.113.7","canSpeed":1,"download":500}}`)
+		case "/v3/reopen":
+			fmt.Fprint(w, `{"code":10002,"message":"too frequent","data":{"result":"ok"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := mustNewClient(t, "", WithEndpoints(server.URL+"/v3/query", server.URL+"/v3/reopen"))
+
+	query, err := client.QuerySpeedupStatus(context.Background())
+	if err != nil {
+		t.Fatalf("QuerySpeedupStatus returned error: %v", err)
+	}
+	if query.Data.IP != "203.0.113.7" || query.GetDownloadBandwidth() != 500 {
+		t.Errorf("Unexpected query response: %+v", query.Data)
+	}
+
+	reopen, err := client.ReopenSpeedup(context.Background())
+	if err != nil {
+		t.Fatalf("ReopenSpeedup returned error: %v", err)
+	}
+	if reopen.Code != 10002 || !reopen.Accepted() {
+		t.Errorf("Expected accepted reopen with code 10002, got %+v", reopen)
+	}
+}
+
+// roundTripperFunc 以函数实现的 RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestSpeedTestCNClient_WithTransport(t *testing.T) {
+	var requested []string
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		requested = append(requested, r.URL.String())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"code":0,"data":{"result":"ok"}}`)),
+			Request:    r,
+		}, nil
+	})
+
+	client := mustNewClient(t, "", WithTransport(rt), WithIPFamily(IPFamilyV4))
+	resp, err := client.ReopenSpeedup(context.Background())
+	if err != nil {
+		t.Fatalf("ReopenSpeedup returned error: %v", err)
+	}
+	if resp.Data.Result != "ok" {
+		t.Errorf("Expected result ok, got %q", resp.Data.Result)
+	}
+	if len(requested) != 1 || requested[0] != DefaultReopenURL {
+		t.Errorf("Expected request to default reopen URL through injected transport, got %v", requested)
+	}
+
+	// 注入的 RoundTripper 不会被 Rebind 替换
+	client.Rebind()
+	if _, err := client.QuerySpeedupStatus(context.Background()); err != nil {
+		t.Fatalf("QuerySpeedupStatus returned error: %v", err)
+	}
+	if len(requested) != 2 || requested[1] != DefaultQueryURL {
+		t.Errorf("Expected query through injected transport after Rebind, got %v", requested)
+	}
+}
@@ -0,0 +1,515 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"speedtestup/api"
+	"speedtestup/config"
+	"speedtestup/service"
+	"speedtestup/state"
+	"speedtestup/utils"
+)
+
+// 子命令退出码
+const (
+	exitOK       = 0 // 成功
+	exitFailure  = 1 // 请求失败或提速失败
+	exitUsage    = 2 // 命令行参数错误
+	exitInactive = 3 // 提速请求成功，但启用的方向未激活
+)
+
+// subcommand 一次性子命令
+type subcommand struct {
+	name    string
+	summary string
+	quiet   bool // 结果输出到标准输出，info 级别的日志不输出
+	json    bool // 是否支持 -json 输出
+	state   bool // 读取并更新持久化的运行状态，与服务共用 min_reopen_interval 和上次运行记录
+	run     func(c *cli, lines []*service.Line) int
+
+	// standalone 不需要加载配置的命令，设置后不使用 run
+	standalone func(args []string) int
+}
+
+// subcommands 所有子命令
+var subcommands = []subcommand{
+	{name: "status", summary: "查询提速状态，输出带宽和各项提速截止时间", quiet: true, json: true, run: (*cli).status},
+	{name: "reopen", summary: "调用一次重新开启提速接口", quiet: true, json: true, state: true, run: (*cli).reopen},
+	{name: "ip", summary: "输出公网 IP 和绑定接口的 IP", quiet: true, json: true, run: (*cli).ip},
+	{name: "run-once", summary: "执行一次提速（含自动恢复）后退出，退出码表示提速结果", state: true, run: (*cli).runOnce},
+	{name: "mock-server", summary: "启动模拟的 speedtest.cn 服务，用于离线测试和演示", standalone: runMockServer},
+}
+
+// cli 子命令的运行环境
+type cli struct {
+	ctx  context.Context // 收到 SIGINT / SIGTERM 时取消，中止正在进行的请求
+	out  io.Writer
+	json bool
+	now  func() time.Time
+}
+
+// findSubcommand 按名称查找子命令
+func findSubcommand(name string) *subcommand {
+	for i := range subcommands {
+		if subcommands[i].name == name {
+			return &subcommands[i]
+		}
+	}
+	return nil
+}
+
+// printSubcommands 输出子命令列表
+func printSubcommands(w io.Writer) {
+	fmt.Fprintln(w, "命令:")
+	for _, cmd := range subcommands {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "不指定命令时作为常驻服务运行")
+}
+
+// runSubcommand 执行一次性子命令并返回退出码
+// 子命令不要求启用提速服务，默认对所有线路执行，可通过 -line 指定线路
+func runSubcommand(cfg *config.Config, name string, args []string) int {
+	cmd := findSubcommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "❌ 未知的命令: %s\n", name)
+		printSubcommands(os.Stderr)
+		return exitUsage
+	}
+	if cmd.standalone != nil {
+		return cmd.standalone(args)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	c := &cli{ctx: ctx, out: os.Stdout, now: time.Now}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	lineName := fs.String("line", "", "只对指定线路执行（默认所有线路）")
+	if cmd.json {
+		fs.BoolVar(&c.json, "json", false, "以 JSON 格式输出")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "❌ 多余的参数: %s\n", strings.Join(fs.Args(), " "))
+		return exitUsage
+	}
+
+	lineConfigs, err := selectLines(cfg, *lineName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return exitUsage
+	}
+
+	cmdCfg := subcommandConfig(cfg, cmd.quiet)
+	sink, err := service.OpenLogSink(cmdCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 初始化日志失败: %v\n", err)
+		return exitFailure
+	}
+	defer sink.Close()
+
+	lines := make([]*service.Line, 0, len(lineConfigs))
+	for _, lineCfg := range lineConfigs {
+		line, err := service.NewLine(cmdCfg.ForLine(lineCfg), service.WithLogSink(sink))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ 初始化线路 %s 失败: %v\n", lineCfg.Name, err)
+			return exitFailure
+		}
+		lines = append(lines, line)
+	}
+
+	if cmd.state && cfg.State.File != "" {
+		store, err := state.Open(cfg.State.File)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ 加载状态文件失败: %v\n", err)
+			return exitFailure
+		}
+		for _, line := range lines {
+			line.AttachState(store)
+		}
+	}
+
+	return cmd.run(c, lines)
+}
+
+// selectLines 选择子命令要处理的线路
+func selectLines(cfg *config.Config, name string) ([]config.LineConfig, error) {
+	lines := cfg.EffectiveLines()
+	if name == "" {
+		return lines, nil
+	}
+	for _, line := range lines {
+		if line.Name == name {
+			return []config.LineConfig{line}, nil
+		}
+	}
+
+	names := make([]string, 0, len(lines))
+	for _, line := range lines {
+		names = append(names, line.Name)
+	}
+	return nil, fmt.Errorf("未知的线路 %q（可选 %s）", name, strings.Join(names, ", "))
+}
+
+// subcommandConfig 子命令使用的配置副本
+// 输出到标准输出的日志改为标准错误，避免与命令结果混在一起；quiet 时 info 级别提升为 warn
+func subcommandConfig(cfg *config.Config, quiet bool) *config.Config {
+	c := *cfg
+	if c.Logging.Output == "stdout" {
+		c.Logging.Output = "stderr"
+	}
+	if quiet && strings.EqualFold(c.Logging.Level, utils.LevelInfo) {
+		c.Logging.Level = utils.LevelWarn
+	}
+	return &c
+}
+
+// worseExit 合并多条线路的退出码，失败优先于未激活
+func worseExit(a, b int) int {
+	rank := func(code int) int {
+		switch code {
+		case exitOK:
+			return 0
+		case exitInactive:
+			return 1
+		default:
+			return 2
+		}
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
+
+// writeJSON 以缩进的 JSON 格式输出
+func (c *cli) writeJSON(v interface{}) {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// statusResult 单条线路的提速状态
+type statusResult struct {
+	Line         string               `json:"line"`
+	IP           string               `json:"ip,omitempty"`
+	CanSpeed     bool                 `json:"can_speed"`
+	DownloadMbps int                  `json:"download_mbps"`
+	UpHMbps      int                  `json:"up_h_mbps"`
+	Up100Mbps    int                  `json:"up_100_mbps"`
+	DownAcc      bool                 `json:"down_acc"`
+	UpAcc        bool                 `json:"up_acc"`
+	DownActive   bool                 `json:"down_active"`
+	UpActive     bool                 `json:"up_active"`
+	Expiries     map[string]time.Time `json:"expiries,omitempty"`
+	Error        string               `json:"error,omitempty"`
+}
+
+// newStatusResult 根据查询结果生成线路的提速状态
+func newStatusResult(line string, svc *service.SpeedupService, resp *api.SpeedupQueryResponse) statusResult {
+	result := statusResult{
+		Line:         line,
+		IP:           resp.Data.IP,
+		CanSpeed:     resp.IsSpeedupAvailable(),
+		DownloadMbps: resp.GetDownloadBandwidth(),
+		UpHMbps:      resp.GetUpHBandwidth(),
+		Up100Mbps:    resp.GetUp100Bandwidth(),
+		Expiries:     resp.Expiries(),
+	}
+	result.DownAcc, result.UpAcc = svc.Directions()
+
+	downActive, upActive, _, err := svc.CheckActive(resp)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.DownActive, result.UpActive = downActive, upActive
+	return result
+}
+
+// status 查询各线路的提速状态
+func (c *cli) status(lines []*service.Line) int {
+	code := exitOK
+	results := make([]statusResult, 0, len(lines))
+	for _, line := range lines {
+		resp, err := line.SpeedupService.Query(c.ctx)
+		if err != nil {
+			results = append(results, statusResult{Line: line.Name, Error: err.Error()})
+			code = exitFailure
+			continue
+		}
+		result := newStatusResult(line.Name, line.SpeedupService, resp)
+		if result.Error != "" {
+			code = exitFailure
+		}
+		results = append(results, result)
+	}
+
+	if c.json {
+		c.writeJSON(results)
+	} else {
+		c.writeStatusTable(results)
+	}
+	return code
+}
+
+// writeStatusTable 以表格形式输出提速状态，之后按截止时间列出各项提速
+func (c *cli) writeStatusTable(results []statusResult) {
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tIP\tCAN_SPEED\tDOWN\tUP_H\tUP_100\tDOWN_ACC\tUP_ACC")
+	for _, r := range results {
+		if r.Error != "" && r.IP == "" {
+			fmt.Fprintf(tw, "%s\terror: %s\n", r.Line, r.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%dM\t%dM\t%dM\t%s\t%s\n", r.Line, r.IP, yesNo(r.CanSpeed),
+			r.DownloadMbps, r.UpHMbps, r.Up100Mbps,
+			accText(r.DownAcc, r.DownActive), accText(r.UpAcc, r.UpActive))
+	}
+	tw.Flush()
+
+	type expiry struct {
+		line, kind string
+		at         time.Time
+	}
+	var expiries []expiry
+	for _, r := range results {
+		for kind, at := range r.Expiries {
+			expiries = append(expiries, expiry{r.Line, kind, at})
+		}
+	}
+	if len(expiries) == 0 {
+		return
+	}
+	sort.Slice(expiries, func(i, j int) bool {
+		if expiries[i].line != expiries[j].line {
+			return expiries[i].line < expiries[j].line
+		}
+		if !expiries[i].at.Equal(expiries[j].at) {
+			return expiries[i].at.Before(expiries[j].at)
+		}
+		return expiries[i].kind < expiries[j].kind
+	})
+
+	fmt.Fprintln(c.out)
+	tw = tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tEXPIRY\tEXPIRES_AT\tREMAINING")
+	now := c.now()
+	for _, e := range expiries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.line, e.kind, e.at.Local().Format("2006-01-02 15:04:05"), remaining(e.at.Sub(now)))
+	}
+	tw.Flush()
+}
+
+// yesNo 布尔值的表格输出
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
+
+// accText 单个方向的提速状态，未启用的方向输出 -
+func accText(enabled, active bool) string {
+	if !enabled {
+		return "-"
+	}
+	if active {
+		return "active"
+	}
+	return "inactive"
+}
+
+// remaining 剩余时间的表格输出，精确到分钟
+func remaining(d time.Duration) string {
+	if d <= 0 {
+		return "expired"
+	}
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// reopenResult 单条线路重新开启提速的结果
+type reopenResult struct {
+	Line     string `json:"line"`
+	Code     int    `json:"code"`
+	Message  string `json:"message,omitempty"`
+	Result   string `json:"result,omitempty"`
+	Accepted bool   `json:"accepted"`
+	Error    string `json:"error,omitempty"`
+}
+
+// reopen 对各线路调用一次重新开启提速接口
+func (c *cli) reopen(lines []*service.Line) int {
+	code := exitOK
+	results := make([]reopenResult, 0, len(lines))
+	for _, line := range lines {
+		resp, err := line.SpeedupService.Reopen(c.ctx)
+		if err != nil {
+			results = append(results, reopenResult{Line: line.Name, Error: err.Error()})
+			code = exitFailure
+			continue
+		}
+		if !resp.Accepted() {
+			code = exitFailure
+		}
+		results = append(results, reopenResult{
+			Line:     line.Name,
+			Code:     resp.Code,
+			Message:  resp.Message,
+			Result:   resp.Data.Result,
+			Accepted: resp.Accepted(),
+		})
+	}
+
+	if c.json {
+		c.writeJSON(results)
+		return code
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tCODE\tACCEPTED\tMESSAGE")
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(tw, "%s\t-\tno\terror: %s\n", r.Line, r.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", r.Line, r.Code, yesNo(r.Accepted), r.Message)
+	}
+	tw.Flush()
+	return code
+}
+
+// ipResult 单条线路的公网 IP 和绑定接口 IP
+type ipResult struct {
+	Line        string `json:"line"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Interface   string `json:"interface,omitempty"`
+	InterfaceIP string `json:"interface_ip,omitempty"`
+	BindIP      string `json:"bind_ip,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ip 输出各线路的公网 IP，启用 IP 绑定时同时输出绑定的接口和地址
+func (c *cli) ip(lines []*service.Line) int {
+	code := exitOK
+	results := make([]ipResult, 0, len(lines))
+	for _, line := range lines {
+		result := ipResult{Line: line.Name}
+		var errs []string
+
+		ipv4, ipv6, err := line.IPService.GetCurrentIPs(c.ctx)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		result.IPv4, result.IPv6 = ipv4, ipv6
+
+		binding := line.Config.Speedup.IPBinding
+		if binding.Enabled {
+			result.BindIP = binding.BindIP
+			if binding.Interface != "" {
+				result.Interface = binding.Interface
+				result.InterfaceIP, err = line.IPService.GetInterfaceIP(binding.Interface)
+				if err != nil {
+					errs = append(errs, err.Error())
+				}
+			}
+		}
+
+		if len(errs) > 0 {
+			result.Error = strings.Join(errs, "; ")
+			code = exitFailure
+		}
+		results = append(results, result)
+	}
+
+	if c.json {
+		c.writeJSON(results)
+		return code
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tIPV4\tIPV6\tINTERFACE\tINTERFACE_IP\tBIND_IP")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Line, dash(r.IPv4), dash(r.IPv6),
+			dash(r.Interface), dash(r.InterfaceIP), dash(r.BindIP))
+	}
+	tw.Flush()
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(os.Stderr, "⚠️  线路 %s: %s\n", r.Line, r.Error)
+		}
+	}
+	return code
+}
+
+// dash 空值的表格输出
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// runOnce 对各线路执行一次提速
+// 提速失败返回 exitFailure，提速请求成功但启用的方向未激活返回 exitInactive
+func (c *cli) runOnce(lines []*service.Line) int {
+	code := exitOK
+	for _, line := range lines {
+		code = worseExit(code, runOnceLine(c.ctx, line))
+	}
+	return code
+}
+
+// runOnceLine 对单条线路执行一次提速并返回退出码
+func runOnceLine(ctx context.Context, line *service.Line) int {
+	if err := line.SpeedupService.Execute(ctx, service.TriggerCommand); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 线路 %s 提速失败: %v\n", line.Name, err)
+		return exitFailure
+	}
+
+	resp, _ := line.SpeedupService.GetLastQuery()
+	if resp == nil {
+		fmt.Fprintf(os.Stderr, "❌ 线路 %s 没有提速状态的查询结果\n", line.Name)
+		return exitFailure
+	}
+	_, _, active, err := line.SpeedupService.CheckActive(resp)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 线路 %s 检查提速状态失败: %v\n", line.Name, err)
+		return exitFailure
+	}
+	if !active {
+		fmt.Fprintf(os.Stderr, "⚠️  线路 %s 提速请求已完成，但启用的方向未激活\n", line.Name)
+		return exitInactive
+	}
+	fmt.Fprintf(os.Stderr, "✅ 线路 %s 提速已激活\n", line.Name)
+	return exitOK
+}
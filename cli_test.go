@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"speedtestup/api"
+	"speedtestup/config"
+	"speedtestup/mockserver"
+	"speedtestup/service"
+	"speedtestup/state"
+)
+
+// 测试子命令的线路选择
+func TestSelectLines(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+
+	lines, err := selectLines(cfg, "")
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, config.DefaultLineName, lines[0].Name)
+
+	cfg.Lines = []config.LineConfig{{Name: "telecom"}, {Name: "unicom"}}
+	lines, err = selectLines(cfg, "unicom")
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "unicom", lines[0].Name)
+
+	_, err = selectLines(cfg, "mobile")
+	assert.ErrorContains(t, err, "telecom, unicom")
+}
+
+// 测试子命令的日志配置：输出改为标准错误，quiet 时提升日志级别
+func TestSubcommandConfig(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+
+	quiet := subcommandConfig(cfg, true)
+	assert.Equal(t, "stderr", quiet.Logging.Output)
+	assert.Equal(t, "warn", quiet.Logging.Level)
+	assert.Equal(t, "stdout", cfg.Logging.Output, "original config should be unchanged")
+
+	verbose := subcommandConfig(cfg, false)
+	assert.Equal(t, "info", verbose.Logging.Level)
+
+	cfg.Logging.Level = "debug"
+	cfg.Logging.Output = "file"
+	cfg.Logging.File = "speedup.log"
+	debug := subcommandConfig(cfg, true)
+	assert.Equal(t, "debug", debug.Logging.Level)
+	assert.Equal(t, "file", debug.Logging.Output)
+}
+
+// 测试多条线路退出码的合并
+func TestWorseExit(t *testing.T) {
+	assert.Equal(t, exitOK, worseExit(exitOK, exitOK))
+	assert.Equal(t, exitInactive, worseExit(exitOK, exitInactive))
+	assert.Equal(t, exitFailure, worseExit(exitInactive, exitFailure))
+	assert.Equal(t, exitFailure, worseExit(exitFailure, exitInactive))
+}
+
+// 测试剩余时间的输出格式
+func TestRemaining(t *testing.T) {
+	assert.Equal(t, "expired", remaining(-time.Minute))
+	assert.Equal(t, "45m", remaining(45*time.Minute))
+	assert.Equal(t, "3h15m", remaining(3*time.Hour+15*time.Minute))
+	assert.Equal(t, "2d3h", remaining(51*time.Hour+20*time.Minute))
+}
+
+// 测试未知命令和多余参数
+func TestRunSubcommandUsage(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	assert.Equal(t, exitUsage, runSubcommand(cfg, "unknown", nil))
+	assert.Equal(t, exitUsage, runSubcommand(cfg, "status", []string{"extra"}))
+	assert.Equal(t, exitUsage, runSubcommand(cfg, "run-once", []string{"-json"}))
+	assert.Equal(t, exitUsage, runSubcommand(cfg, "ip", []string{"-line", "missing"}))
+	assert.Equal(t, exitUsage, runSubcommand(cfg, "mock-server", []string{"-scenario", "unknown"}))
+}
+
+// 测试 status 的表格和 JSON 输出
+func TestStatusOutput(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	resp := &api.SpeedupQueryResponse{}
+	resp.Data.IP = "1.2.3.4"
+	resp.Data.CanSpeed = 1
+	resp.Data.Download = 500
+	resp.Data.TargetUpH = 50 * 1024
+	resp.Data.DownExpireT = float64(now.Add(50 * time.Hour).Unix())
+	resp.Data.UpHExpireT = "false"
+	resp.Data.Up100ExpireT = "false"
+	resp.Data.DownUp50ExpireT = "false"
+	resp.Data.DownUpExpireT = "false"
+
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.DownAcc = true
+	cfg.Speedup.UpAcc = false
+	client, err := api.NewSpeedTestCNClient("")
+	require.NoError(t, err)
+	svc := service.NewSpeedupService(client, cfg)
+	results := []statusResult{
+		newStatusResult("default", svc, resp),
+		{Line: "unicom", Error: "请求提速查询接口失败"},
+	}
+
+	var out bytes.Buffer
+	c := &cli{out: &out, now: func() time.Time { return now }}
+	c.writeStatusTable(results)
+	text := out.String()
+	assert.Contains(t, text, "LINE")
+	assert.Regexp(t, `default\s+1\.2\.3\.4\s+yes\s+500M\s+50M\s+0M\s+active\s+-`, text)
+	assert.Contains(t, text, "unicom   error: 请求提速查询接口失败")
+	assert.Regexp(t, `default\s+down\s+\S+ \S+\s+2d2h`, text)
+	assert.Equal(t, 1, strings.Count(text, "EXPIRES_AT"))
+
+	out.Reset()
+	c.json = true
+	c.writeJSON(results)
+	var decoded []statusResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Len(t, decoded, 2)
+	assert.True(t, decoded[0].DownActive)
+	assert.Equal(t, 500, decoded[0].DownloadMbps)
+	assert.Contains(t, decoded[0].Expiries, api.ExpiryDown)
+	assert.Equal(t, "请求提速查询接口失败", decoded[1].Error)
+}
+
+// 测试 reopen 和 run-once 读取并更新持久化的运行状态
+func TestRunSubcommandState(t *testing.T) {
+	steps, err := mockserver.ParseScript("active")
+	require.NoError(t, err)
+	ts := httptest.NewServer(mockserver.New(steps))
+	defer ts.Close()
+
+	cfg := config.NewDefaultConfig()
+	cfg.Logging.Level = "error"
+	cfg.Endpoints.BaseURL = ts.URL
+	cfg.HTTPClient.Retry.MaxRetries = 0
+	cfg.Speedup.MinReopenInterval = 0
+	cfg.Speedup.IPDetection.Providers = []config.IPProviderConfig{
+		{Name: "mock", Type: config.IPProviderText, URL: ts.URL + mockserver.IPPath, Family: config.FamilyIPv4, Timeout: time.Second},
+	}
+	cfg.State.File = filepath.Join(t.TempDir(), "state.json")
+
+	assert.Equal(t, exitOK, runSubcommand(cfg, "reopen", nil))
+	store, err := state.Open(cfg.State.File)
+	require.NoError(t, err)
+	saved := store.Line(cfg.ForLine(cfg.EffectiveLines()[0]).Line)
+	assert.False(t, saved.LastReopen.IsZero(), "reopen should persist last_reopen")
+
+	assert.Equal(t, exitOK, runSubcommand(cfg, "run-once", nil))
+	store, err = state.Open(cfg.State.File)
+	require.NoError(t, err)
+	saved = store.Line(cfg.ForLine(cfg.EffectiveLines()[0]).Line)
+	require.NotNil(t, saved.LastRun, "run-once should persist last_run")
+	assert.Equal(t, string(service.TriggerCommand), saved.LastRun.Trigger)
+}
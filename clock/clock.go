@@ -0,0 +1,179 @@
+// Package clock 时钟抽象
+// 服务组件通过 Clock 获取时间、等待和安排定时器，测试中可替换为 Simulated 以虚拟时间运行
+package clock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 时钟
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	// SleepContext 等待 d，ctx 先被取消时提前返回 ctx.Err()
+	SleepContext(ctx context.Context, d time.Duration) error
+	// AfterFunc 在 d 之后调用 f
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer 由 AfterFunc 创建的定时器
+type Timer interface {
+	// Stop 取消定时器，定时器已触发或已取消时返回 false
+	Stop() bool
+}
+
+// System 使用系统时间的时钟
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+func (systemClock) SleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Simulated 虚拟时间的时钟
+// 时间只在调用 Advance、AdvanceTo 或 Sleep 时前进；到期的定时器在 Advance 的调用方协程中按时间顺序同步执行，
+// 因此一周的定时任务可以在毫秒内确定性地运行完
+type Simulated struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*simTimer
+	seq    uint64
+}
+
+// simTimer 虚拟时间的定时器
+type simTimer struct {
+	c   *Simulated
+	at  time.Time
+	seq uint64 // 创建顺序，同一时间到期的定时器按创建顺序执行
+	f   func()
+}
+
+// NewSimulated 创建从 start 开始的虚拟时钟
+func NewSimulated(start time.Time) *Simulated {
+	return &Simulated{now: start}
+}
+
+// Now 当前的虚拟时间
+func (c *Simulated) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep 立即将虚拟时间推进 d，不执行期间到期的定时器
+// 这些定时器会在下一次 Advance 时执行
+func (c *Simulated) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > 0 {
+		c.now = c.now.Add(d)
+	}
+}
+
+// SleepContext 与 Sleep 相同，立即将虚拟时间推进 d，不会阻塞调用方
+// ctx 已被取消时不推进时间，直接返回 ctx.Err()
+func (c *Simulated) SleepContext(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.Sleep(d)
+	return nil
+}
+
+// AfterFunc 在虚拟时间经过 d 之后调用 f
+func (c *Simulated) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	t := &simTimer{c: c, at: c.now.Add(d), seq: c.seq, f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Stop 取消定时器
+func (t *simTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	for i, pending := range t.c.timers {
+		if pending == t {
+			t.c.timers = append(t.c.timers[:i], t.c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Advance 将虚拟时间推进 d，并按时间顺序执行期间到期的定时器
+func (c *Simulated) Advance(d time.Duration) {
+	c.AdvanceTo(c.Now().Add(d))
+}
+
+// AdvanceTo 将虚拟时间推进到 target，并按时间顺序执行期间到期的定时器
+// 执行定时器时虚拟时间为定时器的到期时间；定时器中新安排的、在 target 之前到期的定时器也会执行
+func (c *Simulated) AdvanceTo(target time.Time) {
+	for {
+		t := c.popDue(target)
+		if t == nil {
+			break
+		}
+		t.f()
+	}
+
+	c.mu.Lock()
+	if target.After(c.now) {
+		c.now = target
+	}
+	c.mu.Unlock()
+}
+
+// popDue 取出在 target 之前最早到期的定时器，并将虚拟时间推进到其到期时间
+func (c *Simulated) popDue(target time.Time) *simTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.timers) == 0 {
+		return nil
+	}
+	sort.Slice(c.timers, func(i, j int) bool {
+		a, b := c.timers[i], c.timers[j]
+		if !a.at.Equal(b.at) {
+			return a.at.Before(b.at)
+		}
+		return a.seq < b.seq
+	})
+
+	t := c.timers[0]
+	if t.at.After(target) {
+		return nil
+	}
+	c.timers = c.timers[1:]
+	if t.at.After(c.now) {
+		c.now = t.at
+	}
+	return t
+}
+
+// Pending 尚未触发的定时器数量
+func (c *Simulated) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
@@ -0,0 +1,108 @@
+package clock
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSimulated_AdvanceRunsTimersInOrder(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewSimulated(start)
+
+	var fired []string
+	record := func(name string) func() {
+		return func() { fired = append(fired, fmt.Sprintf("%s@%v", name, c.Now().Sub(start))) }
+	}
+	c.AfterFunc(2*time.Minute, record("b"))
+	c.AfterFunc(time.Minute, record("a"))
+	c.AfterFunc(2*time.Minute, record("c")) // 同一时间到期时按创建顺序执行
+	stopped := c.AfterFunc(90*time.Second, record("stopped"))
+	c.AfterFunc(time.Hour, record("later"))
+
+	if !stopped.Stop() {
+		t.Error("Expected Stop to cancel a pending timer")
+	}
+	if stopped.Stop() {
+		t.Error("Expected second Stop to return false")
+	}
+
+	c.Advance(5 * time.Minute)
+	if got := fmt.Sprint(fired); got != "[a@1m0s b@2m0s c@2m0s]" {
+		t.Errorf("Unexpected timer order: %s", got)
+	}
+	if !c.Now().Equal(start.Add(5 * time.Minute)) {
+		t.Errorf("Expected clock at +5m, got %v", c.Now().Sub(start))
+	}
+	if c.Pending() != 1 {
+		t.Errorf("Expected 1 pending timer, got %d", c.Pending())
+	}
+}
+
+func TestSimulated_NestedTimers(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewSimulated(start)
+
+	// 周期任务在回调中安排下一次执行
+	var ticks []time.Duration
+	var tick func()
+	tick = func() {
+		ticks = append(ticks, c.Now().Sub(start))
+		c.AfterFunc(10*time.Minute, tick)
+	}
+	c.AfterFunc(10*time.Minute, tick)
+
+	c.Advance(time.Hour)
+	if len(ticks) != 6 || ticks[5] != time.Hour {
+		t.Errorf("Expected 6 ticks ending at 1h, got %v", ticks)
+	}
+}
+
+func TestSimulated_Sleep(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewSimulated(start)
+
+	fired := false
+	c.AfterFunc(time.Minute, func() { fired = true })
+
+	// Sleep 只推进时间，到期的定时器在下一次 Advance 时执行
+	c.Sleep(5 * time.Minute)
+	if fired {
+		t.Error("Expected Sleep not to run timers")
+	}
+	if !c.Now().Equal(start.Add(5 * time.Minute)) {
+		t.Errorf("Expected clock at +5m, got %v", c.Now().Sub(start))
+	}
+
+	c.Advance(0)
+	if !fired {
+		t.Error("Expected overdue timer to run on Advance")
+	}
+	if !c.Now().Equal(start.Add(5 * time.Minute)) {
+		t.Errorf("Expected overdue timer not to move the clock back, got %v", c.Now().Sub(start))
+	}
+}
+
+func TestClock_SleepContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := System.SleepContext(ctx, time.Minute); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if err := System.SleepContext(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewSimulated(start)
+	if err := c.SleepContext(context.Background(), time.Hour); err != nil || !c.Now().Equal(start.Add(time.Hour)) {
+		t.Errorf("Expected simulated sleep to advance 1h, got %v (err %v)", c.Now().Sub(start), err)
+	}
+	if err := c.SleepContext(ctx, time.Hour); err != context.Canceled || !c.Now().Equal(start.Add(time.Hour)) {
+		t.Errorf("Expected canceled sleep not to advance the clock, got %v (err %v)", c.Now().Sub(start), err)
+	}
+}
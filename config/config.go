@@ -1,7 +1,12 @@
 package config
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
+
+	"speedtestup/api"
 )
 
 // Config 定义应用程序配置结构
@@ -9,8 +14,75 @@ type Config struct {
 	// 提速服务配置
 	Speedup SpeedupConfig `json:"speedup" yaml:"speedup"`
 
+	// 多线路配置（每条线路未设置的字段继承 speedup 中的值）
+	Lines []LineConfig `json:"lines" yaml:"lines"`
+
+	// HTTP 客户端配置（所有线路共用）
+	HTTPClient HTTPClientConfig `json:"http_client" yaml:"http_client"`
+
+	// speedtest.cn 接口地址（所有线路共用）
+	Endpoints EndpointsConfig `json:"endpoints" yaml:"endpoints"`
+
 	// 日志配置
 	Logging LoggingConfig `json:"logging" yaml:"logging"`
+
+	// HTTP 状态与控制接口配置
+	Server ServerConfig `json:"server" yaml:"server"`
+
+	// 持久化状态配置
+	State StateConfig `json:"state" yaml:"state"`
+
+	// 事件通知配置
+	Notify NotifyConfig `json:"notify" yaml:"notify"`
+
+	// 配置热重载
+	Reload ReloadConfig `json:"reload" yaml:"reload"`
+
+	// 优雅关闭配置
+	Shutdown ShutdownConfig `json:"shutdown" yaml:"shutdown"`
+
+	// 当前线路名称（运行时设置，不参与序列化）
+	Line string `json:"-" yaml:"-"`
+
+	// 加载时发现的未知配置项路径（由 Validate 报告）
+	unknownKeys []string
+}
+
+// LineConfig 单条线路配置（多线路 / 多 WAN）
+type LineConfig struct {
+	Name          string `json:"name" yaml:"name"`
+	SpeedupConfig `yaml:",inline"`
+}
+
+// DefaultLineName 未配置多线路时使用的线路名称
+const DefaultLineName = "default"
+
+// EffectiveLines 返回实际生效的线路列表
+// 未配置 lines 时，整个 speedup 配置视为一条名为 default 的线路
+func (c *Config) EffectiveLines() []LineConfig {
+	if len(c.Lines) == 0 {
+		return []LineConfig{{Name: DefaultLineName, SpeedupConfig: c.Speedup}}
+	}
+	return c.Lines
+}
+
+// ForLine 返回指定线路的配置副本（speedup 替换为线路配置）
+func (c *Config) ForLine(line LineConfig) *Config {
+	lineCfg := *c
+	lineCfg.Speedup = line.SpeedupConfig
+	lineCfg.Lines = nil
+	lineCfg.Line = line.Name
+	return &lineCfg
+}
+
+// AnyLineEnabled 检查是否至少有一条线路启用了提速
+func (c *Config) AnyLineEnabled() bool {
+	for _, line := range c.EffectiveLines() {
+		if line.Enabled {
+			return true
+		}
+	}
+	return false
 }
 
 // SpeedupConfig 提速服务配置
@@ -24,15 +96,27 @@ type SpeedupConfig struct {
 	// 重新开启提速的定时任务（cron 表达式）
 	ReopenSchedule string `json:"reopen_schedule" yaml:"reopen_schedule"`
 
+	// 两次调用重新开启提速接口的最短间隔，0 表示不限制
+	MinReopenInterval time.Duration `json:"min_reopen_interval" yaml:"min_reopen_interval"`
+
+	// 启动时的首次提速配置
+	Startup StartupConfig `json:"startup" yaml:"startup"`
+
 	// IP 绑定配置
 	IPBinding IPBindingConfig `json:"ip_binding" yaml:"ip_binding"`
 
+	// 公网 IP 检测配置
+	IPDetection IPDetectionConfig `json:"ip_detection" yaml:"ip_detection"`
+
 	// 自动恢复配置
 	AutoRecovery AutoRecoveryConfig `json:"auto_recovery" yaml:"auto_recovery"`
 
 	// 自检配置
 	SelfCheck SelfCheckConfig `json:"self_check" yaml:"self_check"`
 
+	// 按截止时间续期配置
+	ExpiryRenewal ExpiryRenewalConfig `json:"expiry_renewal" yaml:"expiry_renewal"`
+
 	// 提速功能开关
 	Enabled     bool `json:"enabled" yaml:"enabled"`
 	DownAcc     bool `json:"down_acc" yaml:"down_acc"`
@@ -47,28 +131,267 @@ type IPBindingConfig struct {
 	Enabled   bool   `json:"enabled" yaml:"enabled"`
 	Interface string `json:"interface" yaml:"interface"` // 网络接口名称（如 wan、pppoe-wan）
 	BindIP    string `json:"bind_ip" yaml:"bind_ip"`     // 绑定的 IP 地址
+	Family    string `json:"family" yaml:"family"`       // 地址族（any, ipv4, ipv6）
+}
+
+// IPDetectionConfig 公网 IP 检测配置
+type IPDetectionConfig struct {
+	Providers []IPProviderConfig `json:"providers" yaml:"providers"` // 按顺序回退的 IP 提供者
+	Quorum    int                `json:"quorum" yaml:"quorum"`       // 需要多少个提供者结果一致（0 或 1 表示取第一个成功结果）
+}
+
+// IPProviderConfig 单个 IP 提供者配置
+type IPProviderConfig struct {
+	Name     string        `json:"name" yaml:"name"`
+	Type     string        `json:"type" yaml:"type"`         // 提供者类型（text, json, dns）
+	URL      string        `json:"url" yaml:"url"`           // text/json 类型的请求地址
+	Field    string        `json:"field" yaml:"field"`       // json 类型中 IP 所在字段（支持 a.b 嵌套）
+	Resolver string        `json:"resolver" yaml:"resolver"` // dns 类型使用的 DNS 服务器
+	Host     string        `json:"host" yaml:"host"`         // dns 类型查询的域名
+	Family   string        `json:"family" yaml:"family"`     // 提供者支持的地址族（any, ipv4, ipv6）
+	Timeout  time.Duration `json:"timeout" yaml:"timeout"`   // 单个提供者的超时时间
+}
+
+// IP 提供者类型常量
+const (
+	IPProviderText = "text"
+	IPProviderJSON = "json"
+	IPProviderDNS  = "dns"
+)
+
+// 地址族常量
+const (
+	FamilyAny  = "any"
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// DefaultIPProviders 默认的 IP 提供者列表
+func DefaultIPProviders() []IPProviderConfig {
+	return []IPProviderConfig{
+		{Name: "ipinfo", Type: IPProviderText, URL: "https://ipinfo.io/ip/", Family: FamilyAny, Timeout: 10 * time.Second},
+		{Name: "ipify", Type: IPProviderJSON, URL: "https://api64.ipify.org?format=json", Field: "ip", Family: FamilyAny, Timeout: 10 * time.Second},
+		{Name: "opendns", Type: IPProviderDNS, Resolver: "resolver1.opendns.com:53", Host: "myip.opendns.com", Family: FamilyAny, Timeout: 5 * time.Second},
+	}
 }
 
 // AutoRecoveryConfig 自动恢复配置
+// 提速流程失败后按退避策略等待并重试，重试次数用尽后进入冷却期，冷却期内的失败不再触发自动恢复
 type AutoRecoveryConfig struct {
 	Enabled       bool          `json:"enabled" yaml:"enabled"`
-	MaxRetries    int           `json:"max_retries" yaml:"max_retries"`        // 最大重试次数
-	RetryInterval time.Duration `json:"retry_interval" yaml:"retry_interval"` // 重试间隔
+	MaxRetries    int           `json:"max_retries" yaml:"max_retries"`       // 最大重试次数
+	RetryInterval time.Duration `json:"retry_interval" yaml:"retry_interval"` // 重试间隔（指数退避时为首次重试的间隔）
+	Backoff       string        `json:"backoff" yaml:"backoff"`               // 退避策略（fixed, exponential）
+	MaxInterval   time.Duration `json:"max_interval" yaml:"max_interval"`     // 退避间隔的上限（不含 jitter），0 表示不限制
+	Jitter        time.Duration `json:"jitter" yaml:"jitter"`                 // 每次等待随机增加的最大时长
+	Cooldown      time.Duration `json:"cooldown" yaml:"cooldown"`             // 重试次数用尽后的冷却时间，0 表示不冷却
+}
+
+// 自动恢复的退避策略常量
+const (
+	BackoffFixed       = "fixed"
+	BackoffExponential = "exponential"
+)
+
+// StartupConfig 启动时的首次提速配置
+type StartupConfig struct {
+	Policy      string        `json:"policy" yaml:"policy"`             // 首次提速策略（always, if_inactive, skip）
+	MinInterval time.Duration `json:"min_interval" yaml:"min_interval"` // 距离上一次提速不足该时长时跳过首次提速，0 表示不限制
 }
 
+// 首次提速策略常量
+const (
+	StartupAlways     = "always"      // 总是执行提速
+	StartupIfInactive = "if_inactive" // 先查询，启用的方向未全部激活时才执行提速
+	StartupSkip       = "skip"        // 不执行首次提速
+)
+
 // SelfCheckConfig 自检配置
 type SelfCheckConfig struct {
 	Enabled  bool          `json:"enabled" yaml:"enabled"`
 	Interval time.Duration `json:"interval" yaml:"interval"` // 自检间隔（默认 7 天）
 }
 
+// ExpiryRenewalConfig 按截止时间续期配置
+// 启用后每次查询成功都会根据最早的提速截止时间安排一次续期，代替固定的 reopen_schedule
+type ExpiryRenewalConfig struct {
+	Enabled bool          `json:"enabled" yaml:"enabled"`
+	Lead    time.Duration `json:"lead" yaml:"lead"`     // 在截止前多久续期
+	Jitter  time.Duration `json:"jitter" yaml:"jitter"` // 随机提前的最大时长，避免多台设备同时请求
+}
+
+// HTTPClientConfig HTTP 客户端配置
+// speedtest.cn 接口和 text/json 类型的 IP 提供者共用代理、证书、请求头和重试策略
+type HTTPClientConfig struct {
+	Timeouts  HTTPTimeoutsConfig `json:"timeouts" yaml:"timeouts"`
+	Retry     HTTPRetryConfig    `json:"retry" yaml:"retry"`
+	Proxy     string             `json:"proxy" yaml:"proxy"`           // 代理地址（http://、https://、socks5://、socks5h://），为空时使用 HTTP_PROXY 等环境变量
+	UserAgent string             `json:"user_agent" yaml:"user_agent"` // User-Agent
+	Headers   map[string]string  `json:"headers" yaml:"headers"`       // 附加到每个请求的请求头
+	CAFile    string             `json:"ca_file" yaml:"ca_file"`       // 额外信任的 CA 证书文件（PEM）
+}
+
+// HTTPTimeoutsConfig speedtest.cn 各接口的请求超时时间（IP 提供者的超时在 ip_detection 中按提供者设置）
+type HTTPTimeoutsConfig struct {
+	Query  time.Duration `json:"query" yaml:"query"`   // 提速查询接口
+	Reopen time.Duration `json:"reopen" yaml:"reopen"` // 重新开启提速接口
+}
+
+// HTTPRetryConfig 临时性失败（连接错误、5xx、429）的重试策略
+// 等待时间从 wait 开始按指数增长并加入随机抖动，不超过 max_wait；响应带有 Retry-After 时按其等待
+type HTTPRetryConfig struct {
+	MaxRetries int           `json:"max_retries" yaml:"max_retries"` // 最大重试次数，0 表示不重试
+	Wait       time.Duration `json:"wait" yaml:"wait"`               // 首次重试前的等待时间
+	MaxWait    time.Duration `json:"max_wait" yaml:"max_wait"`       // 单次重试的最长等待时间
+}
+
+// EndpointsConfig speedtest.cn 接口地址
+// 可指向镜像或本地的模拟服务，上游接口路径变化时无需等待新版本
+type EndpointsConfig struct {
+	BaseURL string `json:"base_url" yaml:"base_url"` // 设置后替换各接口地址的协议和主机，并作为路径前缀
+	Query   string `json:"query" yaml:"query"`       // 提速查询接口（完整地址，设置 base_url 时也可以只写路径）
+	Reopen  string `json:"reopen" yaml:"reopen"`     // 重新开启提速接口（完整地址，设置 base_url 时也可以只写路径）
+}
+
+// URLs 返回实际使用的提速查询和重新开启提速接口地址
+func (e *EndpointsConfig) URLs() (query, reopen string, err error) {
+	var base *url.URL
+	if e.BaseURL != "" {
+		if base, err = parseHTTPURL(e.BaseURL); err != nil {
+			return "", "", fmt.Errorf("base_url: %v", err)
+		}
+	}
+	if query, err = resolveEndpoint(base, e.Query); err != nil {
+		return "", "", fmt.Errorf("query: %v", err)
+	}
+	if reopen, err = resolveEndpoint(base, e.Reopen); err != nil {
+		return "", "", fmt.Errorf("reopen: %v", err)
+	}
+	return query, reopen, nil
+}
+
+// resolveEndpoint 按 base_url 解析单个接口地址
+// base 不为空时保留接口地址的路径和查询参数，协议和主机使用 base 的，路径以 base 的路径为前缀
+func resolveEndpoint(base *url.URL, endpoint string) (string, error) {
+	if base == nil {
+		u, err := parseHTTPURL(endpoint)
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("地址 %q 无效: %v", endpoint, err)
+	}
+	resolved := *base
+	resolved.Path = strings.TrimRight(base.Path, "/") + "/" + strings.TrimLeft(u.Path, "/")
+	resolved.RawPath = ""
+	resolved.RawQuery = u.RawQuery
+	return resolved.String(), nil
+}
+
+// parseHTTPURL 解析带主机的 http/https 地址
+func parseHTTPURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("地址 %q 无效: %v", raw, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("地址 %q 不是完整的 http/https 地址", raw)
+	}
+	return u, nil
+}
+
 // LoggingConfig 日志配置
 type LoggingConfig struct {
-	Level  string `json:"level" yaml:"level"`  // 日志级别（debug, info, warn, error）
-	Output string `json:"output" yaml:"output"` // 输出方式（stdout, file）
+	Level  string `json:"level" yaml:"level"`   // 日志级别（debug, info, warn, error）
+	Output string `json:"output" yaml:"output"` // 输出方式（stdout, stderr, file）
 	File   string `json:"file" yaml:"file"`     // 日志文件路径
+	Format string `json:"format" yaml:"format"` // 输出格式（text, json）
+
+	// 按组件覆盖日志级别（组件名称 -> 日志级别），如 {"Scheduler": "debug"}
+	Components map[string]string `json:"components" yaml:"components"`
+
+	// 日志文件轮转（仅 output 为 file 时生效）
+	MaxSize    int           `json:"max_size" yaml:"max_size"`       // 单个文件最大大小（MB），0 表示不按大小轮转
+	MaxAge     time.Duration `json:"max_age" yaml:"max_age"`         // 单个文件最长写入时间，0 表示不按时间轮转
+	MaxBackups int           `json:"max_backups" yaml:"max_backups"` // 保留的历史文件数量，0 表示全部保留
+	Compress   bool          `json:"compress" yaml:"compress"`       // 是否使用 gzip 压缩历史文件
+}
+
+// ServerConfig HTTP 状态与控制接口配置
+type ServerConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Listen  string `json:"listen" yaml:"listen"`   // 监听地址（默认仅本机访问）
+	Token   string `json:"token" yaml:"token"`     // Bearer Token，监听非本机地址时必须设置
+	Metrics bool   `json:"metrics" yaml:"metrics"` // 是否提供 /metrics（Prometheus 文本格式）
+}
+
+// StateConfig 持久化状态配置
+type StateConfig struct {
+	File string `json:"file" yaml:"file"` // 状态文件路径（为空时不持久化）
+}
+
+// ReloadConfig 配置热重载
+// 收到 SIGHUP 时总是重新加载配置；启用 watch 时还会定期检查配置文件是否变化
+type ReloadConfig struct {
+	Watch    bool          `json:"watch" yaml:"watch"`       // 是否监视配置文件变化
+	Interval time.Duration `json:"interval" yaml:"interval"` // 检查配置文件的间隔
+}
+
+// ShutdownConfig 优雅关闭配置
+// 收到退出信号后先取消正在进行的请求和自动恢复的等待，再等待正在运行的任务结束
+type ShutdownConfig struct {
+	Timeout time.Duration `json:"timeout" yaml:"timeout"` // 等待 HTTP 接口和正在运行的任务结束的最长时间
 }
 
+// NotifyConfig 事件通知配置
+type NotifyConfig struct {
+	Sinks []NotifySinkConfig `json:"sinks" yaml:"sinks"`
+}
+
+// NotifySinkConfig 单个通知渠道配置
+type NotifySinkConfig struct {
+	Name      string        `json:"name" yaml:"name"`
+	Type      string        `json:"type" yaml:"type"`             // 渠道类型（webhook, telegram, serverchan, pushplus, smtp）
+	Events    []string      `json:"events" yaml:"events"`         // 接收的事件类型（为空时接收所有事件）
+	RateLimit time.Duration `json:"rate_limit" yaml:"rate_limit"` // 同一线路同一事件的最短通知间隔
+
+	// webhook
+	URL      string            `json:"url" yaml:"url"`
+	Method   string            `json:"method" yaml:"method"`
+	Headers  map[string]string `json:"headers" yaml:"headers"`
+	Template string            `json:"template" yaml:"template"` // 请求体模板（text/template，为空时发送事件 JSON）
+
+	// telegram / serverchan / pushplus
+	APIBase string `json:"api_base" yaml:"api_base"` // 接口地址（为空时使用官方地址）
+	Token   string `json:"token" yaml:"token"`       // Telegram Bot Token 或 PushPlus Token
+	ChatID  string `json:"chat_id" yaml:"chat_id"`   // Telegram 会话 ID
+	SendKey string `json:"send_key" yaml:"send_key"` // Server 酱 SendKey
+
+	// smtp
+	Host     string   `json:"host" yaml:"host"`
+	Port     int      `json:"port" yaml:"port"`
+	Username string   `json:"username" yaml:"username"`
+	Password string   `json:"password" yaml:"password"`
+	From     string   `json:"from" yaml:"from"`
+	To       []string `json:"to" yaml:"to"`
+}
+
+// 通知渠道类型常量
+const (
+	NotifyWebhook    = "webhook"
+	NotifyTelegram   = "telegram"
+	NotifyServerChan = "serverchan"
+	NotifyPushPlus   = "pushplus"
+	NotifySMTP       = "smtp"
+)
+
+// DefaultServerListen HTTP 接口默认监听地址
+const DefaultServerListen = "127.0.0.1:8090"
+
 // NewDefaultConfig 创建默认配置
 func NewDefaultConfig() *Config {
 	cfg := &Config{}
@@ -76,22 +399,41 @@ func NewDefaultConfig() *Config {
 	// 设置默认提速配置
 	cfg.Speedup.CheckInterval = 10 * time.Minute
 	cfg.Speedup.StatusCheckInterval = 2 * time.Hour // 每2小时检查一次提速状态
-	cfg.Speedup.ReopenSchedule = "0 0 * * 1" // 每周一 0:00
+	cfg.Speedup.ReopenSchedule = "0 0 * * 1"        // 每周一 0:00
+	cfg.Speedup.MinReopenInterval = time.Minute
+
+	// 设置默认首次提速配置
+	cfg.Speedup.Startup.Policy = StartupAlways
+	cfg.Speedup.Startup.MinInterval = 10 * time.Minute
 
 	// 设置默认 IP 绑定配置
 	cfg.Speedup.IPBinding.Enabled = false
 	cfg.Speedup.IPBinding.Interface = "wan"
 	cfg.Speedup.IPBinding.BindIP = ""
+	cfg.Speedup.IPBinding.Family = FamilyAny
+
+	// 设置默认 IP 检测配置
+	cfg.Speedup.IPDetection.Providers = DefaultIPProviders()
+	cfg.Speedup.IPDetection.Quorum = 1
 
 	// 设置默认自动恢复配置
 	cfg.Speedup.AutoRecovery.Enabled = true
 	cfg.Speedup.AutoRecovery.MaxRetries = 3
 	cfg.Speedup.AutoRecovery.RetryInterval = 5 * time.Minute
+	cfg.Speedup.AutoRecovery.Backoff = BackoffFixed
+	cfg.Speedup.AutoRecovery.MaxInterval = 30 * time.Minute
+	cfg.Speedup.AutoRecovery.Jitter = 0
+	cfg.Speedup.AutoRecovery.Cooldown = 30 * time.Minute
 
 	// 设置默认自检配置
 	cfg.Speedup.SelfCheck.Enabled = true
 	cfg.Speedup.SelfCheck.Interval = 168 * time.Hour // 7 天
 
+	// 设置默认按截止时间续期配置
+	cfg.Speedup.ExpiryRenewal.Enabled = false
+	cfg.Speedup.ExpiryRenewal.Lead = 10 * time.Minute
+	cfg.Speedup.ExpiryRenewal.Jitter = 2 * time.Minute
+
 	// 设置默认功能开关
 	cfg.Speedup.Enabled = false
 	cfg.Speedup.DownAcc = true
@@ -100,10 +442,40 @@ func NewDefaultConfig() *Config {
 	cfg.Speedup.Verbose = false
 	cfg.Speedup.MoreOptions = false
 
+	// 设置默认 HTTP 客户端配置
+	cfg.HTTPClient.Timeouts.Query = 30 * time.Second
+	cfg.HTTPClient.Timeouts.Reopen = 30 * time.Second
+	cfg.HTTPClient.Retry.MaxRetries = 2
+	cfg.HTTPClient.Retry.Wait = time.Second
+	cfg.HTTPClient.Retry.MaxWait = 10 * time.Second
+	cfg.HTTPClient.UserAgent = api.DefaultUserAgent
+
+	// 设置默认 speedtest.cn 接口地址
+	cfg.Endpoints.Query = api.DefaultQueryURL
+	cfg.Endpoints.Reopen = api.DefaultReopenURL
+
 	// 设置默认日志配置
 	cfg.Logging.Level = "info"
 	cfg.Logging.Output = "stdout"
 	cfg.Logging.File = ""
+	cfg.Logging.Format = "text"
+	cfg.Logging.MaxSize = 10
+	cfg.Logging.MaxAge = 0
+	cfg.Logging.MaxBackups = 3
+	cfg.Logging.Compress = false
+
+	// 设置默认 HTTP 接口配置
+	cfg.Server.Enabled = false
+	cfg.Server.Listen = DefaultServerListen
+	cfg.Server.Token = ""
+	cfg.Server.Metrics = true
+
+	// 设置默认热重载配置
+	cfg.Reload.Watch = true
+	cfg.Reload.Interval = 10 * time.Second
+
+	// 设置默认优雅关闭配置
+	cfg.Shutdown.Timeout = 15 * time.Second
 
 	return cfg
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"speedtestup/api"
 )
 
 func TestNewDefaultConfig(t *testing.T) {
@@ -200,10 +201,145 @@ func TestConfigWithNegativeValues(t *testing.T) {
 	cfg, err := LoadConfig(tempFile)
 	assert.NoError(t, err, "Should load config even with negative values")
 
-	// 验证负值被处理为默认值
-	assert.Equal(t, 10*time.Minute, cfg.Speedup.CheckInterval, "Negative CheckInterval should default to 10 minutes")
-	assert.Equal(t, 3, cfg.Speedup.AutoRecovery.MaxRetries, "Negative MaxRetries should default to 3")
-	assert.Equal(t, 5*time.Minute, cfg.Speedup.AutoRecovery.RetryInterval, "Negative RetryInterval should default to 5 minutes")
-	assert.Equal(t, 168*time.Hour, cfg.Speedup.SelfCheck.Interval, "Negative SelfCheck interval should default to 168 hours")
+	// 负值不再被静默替换为默认值，而是由 Validate 报告
+	assert.Equal(t, -time.Hour, cfg.Speedup.CheckInterval, "Negative CheckInterval should be kept")
+	assert.Equal(t, -1, cfg.Speedup.AutoRecovery.MaxRetries, "Negative MaxRetries should be kept")
 	assert.Equal(t, "info", cfg.Logging.Level, "Empty log level should default to info")
-}
\ No newline at end of file
+
+	err = cfg.Validate()
+	assert.Error(t, err, "Negative values should fail validation")
+
+	verr, ok := err.(*ValidationError)
+	assert.True(t, ok, "Validate should return *ValidationError")
+	var paths []string
+	for _, fe := range verr.Errors {
+		paths = append(paths, fe.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		"speedup.check_interval",
+		"speedup.auto_recovery.max_retries",
+		"speedup.auto_recovery.retry_interval",
+		"speedup.self_check.interval",
+	}, paths)
+}
+func TestLoadConfigWithLines(t *testing.T) {
+	linesConfig := `{
+		"speedup": {
+			"enabled": true,
+			"check_interval": "15m",
+			"ip_binding": {
+				"enabled": true,
+				"family": "ipv4"
+			}
+		},
+		"lines": [
+			{
+				"name": "telecom",
+				"ip_binding": {
+					"interface": "pppoe-wan"
+				}
+			},
+			{
+				"name": "unicom",
+				"enabled": false,
+				"check_interval": "5m",
+				"up_acc": false
+			},
+			{
+				"reopen_schedule": "0 3 * * *"
+			}
+		]
+	}`
+
+	tempFile := "temp_lines_config.json"
+	err := os.WriteFile(tempFile, []byte(linesConfig), 0644)
+	assert.NoError(t, err)
+
+	defer os.Remove(tempFile)
+
+	cfg, err := LoadConfig(tempFile)
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Lines, 3, "All lines should be loaded")
+
+	// 线路继承 speedup 中的配置
+	telecom := cfg.Lines[0]
+	assert.Equal(t, "telecom", telecom.Name)
+	assert.True(t, telecom.Enabled, "Line should inherit enabled flag")
+	assert.Equal(t, 15*time.Minute, telecom.CheckInterval, "Line should inherit check interval")
+	assert.True(t, telecom.IPBinding.Enabled, "Line should inherit nested binding fields")
+	assert.Equal(t, "ipv4", telecom.IPBinding.Family, "Line should inherit nested binding fields")
+	assert.Equal(t, "pppoe-wan", telecom.IPBinding.Interface, "Line should override interface")
+
+	// 线路覆盖 speedup 中的配置
+	unicom := cfg.Lines[1]
+	assert.False(t, unicom.Enabled, "Line should override enabled flag")
+	assert.False(t, unicom.UpAcc, "Line should override up_acc")
+	assert.True(t, unicom.DownAcc, "Line should keep default down_acc")
+	assert.Equal(t, 5*time.Minute, unicom.CheckInterval, "Line should override check interval")
+
+	// 未命名的线路自动命名
+	assert.Equal(t, "line-3", cfg.Lines[2].Name)
+	assert.Equal(t, "0 3 * * *", cfg.Lines[2].ReopenSchedule)
+
+	assert.True(t, cfg.AnyLineEnabled())
+
+	lineCfg := cfg.ForLine(telecom)
+	assert.Equal(t, "telecom", lineCfg.Line)
+	assert.Equal(t, "pppoe-wan", lineCfg.Speedup.IPBinding.Interface)
+	assert.Nil(t, lineCfg.Lines)
+}
+
+func TestLoadConfigWithDuplicateLines(t *testing.T) {
+	duplicateConfig := `{
+		"lines": [
+			{"name": "wan"},
+			{"name": "wan"}
+		]
+	}`
+
+	tempFile := "temp_duplicate_lines_config.json"
+	err := os.WriteFile(tempFile, []byte(duplicateConfig), 0644)
+	assert.NoError(t, err)
+
+	defer os.Remove(tempFile)
+
+	_, err = LoadConfig(tempFile)
+	assert.Error(t, err, "Duplicate line names should be rejected")
+}
+
+func TestEffectiveLinesWithoutLines(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Speedup.Enabled = true
+
+	lines := cfg.EffectiveLines()
+	assert.Len(t, lines, 1)
+	assert.Equal(t, DefaultLineName, lines[0].Name)
+	assert.True(t, lines[0].Enabled)
+	assert.True(t, cfg.AnyLineEnabled())
+}
+
+func TestEndpointsURLs(t *testing.T) {
+	// 默认使用官方接口地址
+	query, reopen, err := NewDefaultConfig().Endpoints.URLs()
+	assert.NoError(t, err)
+	assert.Equal(t, api.DefaultQueryURL, query)
+	assert.Equal(t, api.DefaultReopenURL, reopen)
+
+	// base_url 替换协议和主机，并作为路径前缀
+	e := EndpointsConfig{BaseURL: "http://127.0.0.1:8080/mock/", Query: api.DefaultQueryURL, Reopen: "/api/v3/speedup/reopen?src=router"}
+	query, reopen, err = e.URLs()
+	assert.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:8080/mock/speedUp/query", query)
+	assert.Equal(t, "http://127.0.0.1:8080/mock/api/v3/speedup/reopen?src=router", reopen)
+
+	// 未设置 base_url 时只写路径无效
+	cfg := NewDefaultConfig()
+	cfg.Endpoints.Reopen = "/api/v3/speedup/reopen"
+	cfg.Endpoints.BaseURL = ""
+	_, _, err = cfg.Endpoints.URLs()
+	assert.Error(t, err)
+	assert.Equal(t, []string{"endpoints.reopen"}, errorPaths(t, cfg.Validate()))
+
+	cfg.Endpoints.BaseURL = "ftp://mirror"
+	assert.Equal(t, []string{"endpoints.base_url"}, errorPaths(t, cfg.Validate()))
+}
@@ -1,34 +1,165 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"os"
+	"reflect"
 	"time"
 
+	"speedtestup/api"
+
 	"gopkg.in/yaml.v2"
 )
 
 // LoadConfig 从文件加载配置
 func LoadConfig(filePath string) (*Config, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
+	return Load(filePath)
+}
+
+// Load 加载配置，优先级为：默认值 < 配置文件 < 覆盖（按参数顺序，后者优先）
+// filePath 为空时不读取配置文件，只使用默认值和覆盖
+func Load(filePath string, overrides ...*Overrides) (*Config, error) {
+	cfg := NewDefaultConfig()
+
+	var data []byte
+	if filePath != "" {
+		var err error
+		data, err = os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if err := parseConfig(data, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// 应用环境变量和命令行参数的覆盖（在线路继承之前，线路会继承覆盖后的 speedup 配置）
+	for _, o := range overrides {
+		if err := o.apply(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// 验证并设置合理的默认值
+	setDefaults(cfg)
+
+	// 解析多线路配置（线路继承 speedup 中的配置）
+	if err := loadLines(data, cfg); err != nil {
 		return nil, err
 	}
+	linesFrom := -1 // 线路配置的来源，-1 表示配置文件
+	for i := len(overrides) - 1; i >= 0; i-- {
+		if value, ok := overrides[i].values[linesPath]; ok {
+			if err := loadLines([]byte(linesPath+": "+value), cfg); err != nil {
+				return nil, fmt.Errorf("%s: %v", overrides[i].sources[linesPath], err)
+			}
+			linesFrom = i
+			break
+		}
+	}
 
-	cfg := NewDefaultConfig()
+	// 优先级高于线路来源的 speedup.* 覆盖同样作用于每条线路，
+	// 否则线路中显式设置的同名字段会使环境变量和命令行参数失效
+	for _, o := range overrides[linesFrom+1:] {
+		if err := o.applyToLines(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// parseConfig 解析配置文件内容
+func parseConfig(data []byte, cfg *Config) error {
+	// JSON 格式的配置先检查语法，避免回退到 YAML 解析后掩盖真正的错误位置
+	if looksLikeJSON(data) && !json.Valid(data) {
+		return jsonSyntaxError(data)
+	}
 
 	// 尝试解析JSON格式
 	if err := json.Unmarshal(data, cfg); err != nil {
-		// 如果JSON解析失败，尝试YAML格式
+		// 如果JSON解析失败（如时长写成 "10m"），按YAML格式解析（JSON 是 YAML 的子集）
 		if err := yaml.Unmarshal(data, cfg); err != nil {
-			return nil, err
+			return err
 		}
 	}
 
-	// 验证并设置合理的默认值
-	validateAndSetDefaults(cfg)
+	// 记录未知的配置项，由 Validate 报告
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err == nil {
+		cfg.unknownKeys = findUnknownKeys("", raw, reflect.TypeOf(cfg))
+	}
+	return nil
+}
 
-	return cfg, nil
+// looksLikeJSON 检查内容是否为 JSON 格式（以 { 开头）
+func looksLikeJSON(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// jsonSyntaxError 返回带行列号的 JSON 语法错误
+func jsonSyntaxError(data []byte) error {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	syntaxErr, ok := err.(*json.SyntaxError)
+	if !ok {
+		return fmt.Errorf("JSON 语法错误: %v", err)
+	}
+
+	line, col := 1, 1
+	for _, b := range data[:syntaxErr.Offset] {
+		if b == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return fmt.Errorf("JSON 语法错误（第 %d 行第 %d 列）: %v", line, col, syntaxErr)
+}
+
+// loadLines 解析多线路配置
+// 每条线路以已生效的 speedup 配置为基础，只覆盖线路中显式设置的字段
+func loadLines(data []byte, cfg *Config) error {
+	var raw struct {
+		Lines []yaml.MapSlice `yaml:"lines"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	lines := make([]LineConfig, 0, len(raw.Lines))
+	seen := make(map[string]bool)
+	for i, item := range raw.Lines {
+		line := LineConfig{SpeedupConfig: cfg.Speedup}
+		// 切片需要复制，避免线路之间共享底层数组
+		line.IPDetection.Providers = append([]IPProviderConfig(nil), cfg.Speedup.IPDetection.Providers...)
+
+		out, err := yaml.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(out, &line); err != nil {
+			return fmt.Errorf("解析线路 %d 配置失败: %v", i+1, err)
+		}
+
+		if line.Name == "" {
+			line.Name = fmt.Sprintf("line-%d", i+1)
+		}
+		if seen[line.Name] {
+			return fmt.Errorf("线路名称重复: %s", line.Name)
+		}
+		seen[line.Name] = true
+
+		setSpeedupDefaults(&line.SpeedupConfig)
+		lines = append(lines, line)
+	}
+
+	cfg.Lines = lines
+	return nil
 }
 
 // SaveConfig 将配置保存到文件
@@ -41,33 +172,37 @@ func SaveConfig(cfg *Config, filePath string) error {
 	return os.WriteFile(filePath, data, 0644)
 }
 
-// validateAndSetDefaults 验证配置并设置合理的默认值
-func validateAndSetDefaults(cfg *Config) {
-	// 验证提速配置
-	if cfg.Speedup.CheckInterval <= 0 {
-		cfg.Speedup.CheckInterval = 10 * time.Minute
+// setDefaults 为未设置（零值）的配置项设置合理的默认值
+// 显式设置的无效值（如负数间隔）保持原样，由 Validate 报告
+func setDefaults(cfg *Config) {
+	setSpeedupDefaults(&cfg.Speedup)
+	for i := range cfg.Lines {
+		setSpeedupDefaults(&cfg.Lines[i].SpeedupConfig)
 	}
 
-	if cfg.Speedup.ReopenSchedule == "" {
-		cfg.Speedup.ReopenSchedule = "0 0 * * 1"
+	// 设置默认 HTTP 客户端配置
+	if cfg.HTTPClient.Timeouts.Query == 0 {
+		cfg.HTTPClient.Timeouts.Query = 30 * time.Second
 	}
-
-	// 验证IP绑定配置
-	if cfg.Speedup.IPBinding.Interface == "" {
-		cfg.Speedup.IPBinding.Interface = "wan"
+	if cfg.HTTPClient.Timeouts.Reopen == 0 {
+		cfg.HTTPClient.Timeouts.Reopen = 30 * time.Second
 	}
-
-	// 验证自动恢复配置
-	if cfg.Speedup.AutoRecovery.MaxRetries <= 0 {
-		cfg.Speedup.AutoRecovery.MaxRetries = 3
+	if cfg.HTTPClient.Retry.Wait == 0 {
+		cfg.HTTPClient.Retry.Wait = time.Second
+	}
+	if cfg.HTTPClient.Retry.MaxWait == 0 {
+		cfg.HTTPClient.Retry.MaxWait = 10 * time.Second
 	}
-	if cfg.Speedup.AutoRecovery.RetryInterval <= 0 {
-		cfg.Speedup.AutoRecovery.RetryInterval = 5 * time.Minute
+	if cfg.HTTPClient.UserAgent == "" {
+		cfg.HTTPClient.UserAgent = api.DefaultUserAgent
 	}
 
-	// 验证自检配置
-	if cfg.Speedup.SelfCheck.Interval <= 0 {
-		cfg.Speedup.SelfCheck.Interval = 168 * time.Hour // 7 天
+	// 设置默认 speedtest.cn 接口地址
+	if cfg.Endpoints.Query == "" {
+		cfg.Endpoints.Query = api.DefaultQueryURL
+	}
+	if cfg.Endpoints.Reopen == "" {
+		cfg.Endpoints.Reopen = api.DefaultReopenURL
 	}
 
 	// 设置默认日志级别
@@ -77,4 +212,97 @@ func validateAndSetDefaults(cfg *Config) {
 	if cfg.Logging.Output == "" {
 		cfg.Logging.Output = "stdout"
 	}
+	if cfg.Logging.Format == "" {
+		cfg.Logging.Format = "text"
+	}
+
+	// 设置默认 HTTP 接口监听地址
+	if cfg.Server.Listen == "" {
+		cfg.Server.Listen = DefaultServerListen
+	}
+
+	// 设置默认配置文件检查间隔
+	if cfg.Reload.Interval == 0 {
+		cfg.Reload.Interval = 10 * time.Second
+	}
+
+	// 设置默认优雅关闭等待时间
+	if cfg.Shutdown.Timeout == 0 {
+		cfg.Shutdown.Timeout = 15 * time.Second
+	}
+
+	// 设置默认通知渠道名称
+	for i := range cfg.Notify.Sinks {
+		sink := &cfg.Notify.Sinks[i]
+		if sink.Name == "" {
+			sink.Name = fmt.Sprintf("%s-%d", sink.Type, i+1)
+		}
+	}
+}
+
+// setSpeedupDefaults 为未设置（零值）的提速配置项设置合理的默认值
+func setSpeedupDefaults(sc *SpeedupConfig) {
+	// 验证提速配置
+	if sc.CheckInterval == 0 {
+		sc.CheckInterval = 10 * time.Minute
+	}
+
+	if sc.ReopenSchedule == "" {
+		sc.ReopenSchedule = "0 0 * * 1"
+	}
+	if sc.Startup.Policy == "" {
+		sc.Startup.Policy = StartupAlways
+	}
+
+	// 验证IP绑定配置
+	if sc.IPBinding.Interface == "" {
+		sc.IPBinding.Interface = "wan"
+	}
+	if sc.IPBinding.Family == "" {
+		sc.IPBinding.Family = FamilyAny
+	}
+
+	// 验证 IP 检测配置
+	if len(sc.IPDetection.Providers) == 0 {
+		sc.IPDetection.Providers = DefaultIPProviders()
+	}
+	for i := range sc.IPDetection.Providers {
+		provider := &sc.IPDetection.Providers[i]
+		if provider.Type == "" {
+			provider.Type = IPProviderText
+		}
+		if provider.Name == "" {
+			provider.Name = fmt.Sprintf("%s-%d", provider.Type, i+1)
+		}
+		if provider.Family == "" {
+			provider.Family = FamilyAny
+		}
+		if provider.Timeout == 0 {
+			provider.Timeout = 10 * time.Second
+		}
+	}
+	if sc.IPDetection.Quorum == 0 {
+		sc.IPDetection.Quorum = 1
+	}
+
+	// 验证自动恢复配置
+	if sc.AutoRecovery.MaxRetries == 0 {
+		sc.AutoRecovery.MaxRetries = 3
+	}
+	if sc.AutoRecovery.RetryInterval == 0 {
+		sc.AutoRecovery.RetryInterval = 5 * time.Minute
+	}
+	if sc.AutoRecovery.Backoff == "" {
+		sc.AutoRecovery.Backoff = BackoffFixed
+	}
+
+	// 验证自检配置
+	if sc.SelfCheck.Interval == 0 {
+		sc.SelfCheck.Interval = 168 * time.Hour // 7 天
+	}
+
+	// 验证按截止时间续期配置
+	if sc.ExpiryRenewal.Lead == 0 {
+		sc.ExpiryRenewal.Lead = 10 * time.Minute
+	}
 }
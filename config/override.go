@@ -0,0 +1,303 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix 覆盖配置项的环境变量前缀
+const EnvPrefix = "SPEEDTESTUP_"
+
+// EnvConfigPath 指定配置文件路径的环境变量
+const EnvConfigPath = EnvPrefix + "CONFIG"
+
+// linesPath 多线路配置的路径，需要在合并线路继承时单独处理
+const linesPath = "lines"
+
+// speedupPrefix speedup 配置项路径的前缀，这些覆盖在线路继承后还会应用到每条线路
+const speedupPrefix = "speedup."
+
+// durationType time.Duration 的类型
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Field 可覆盖的配置项
+type Field struct {
+	Path string // 配置项路径，如 speedup.check_interval
+	Type reflect.Type
+}
+
+// EnvName 配置项对应的环境变量名，如 SPEEDTESTUP_SPEEDUP_CHECK_INTERVAL
+func (f Field) EnvName() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.Path, ".", "_"))
+}
+
+// FlagName 配置项对应的命令行参数名，与配置项路径相同
+func (f Field) FlagName() string {
+	return f.Path
+}
+
+// usage 命令行参数的说明
+func (f Field) usage() string {
+	return fmt.Sprintf("覆盖配置项 %s（%s，环境变量 %s）", f.Path, typeHint(f.Type), f.EnvName())
+}
+
+// typeHint 配置项取值格式的说明
+func typeHint(t reflect.Type) string {
+	switch {
+	case t == durationType:
+		return "时长，如 10m"
+	case t.Kind() == reflect.Bool:
+		return "true/false"
+	case t.Kind() == reflect.Int:
+		return "整数"
+	case t.Kind() == reflect.String:
+		return "字符串"
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		return "逗号分隔的列表"
+	case t.Kind() == reflect.Map:
+		return "逗号分隔的 key=value"
+	default:
+		return "JSON"
+	}
+}
+
+// Fields 返回所有可覆盖的配置项，按路径排序
+// 嵌套的结构体展开为各自的字段，结构体列表（如 lines、notify.sinks）作为整体以 JSON 覆盖
+func Fields() []Field {
+	var fields []Field
+	collectOverridable("", reflect.TypeOf(Config{}), &fields)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Path < fields[j].Path })
+	return fields
+}
+
+// collectOverridable 递归收集结构体中的配置项
+func collectOverridable(prefix string, t reflect.Type, fields *[]Field) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" || name == "" {
+			continue
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		if f.Type.Kind() == reflect.Struct && f.Type != durationType {
+			collectOverridable(path, f.Type, fields)
+			continue
+		}
+		*fields = append(*fields, Field{Path: path, Type: f.Type})
+	}
+}
+
+// Overrides 来自环境变量或命令行参数的配置覆盖
+type Overrides struct {
+	values  map[string]string // 配置项路径 -> 值
+	sources map[string]string // 配置项路径 -> 来源（环境变量名或参数名），用于错误信息
+}
+
+// newOverrides 创建空的配置覆盖
+func newOverrides() *Overrides {
+	return &Overrides{values: make(map[string]string), sources: make(map[string]string)}
+}
+
+// set 记录一个配置覆盖
+func (o *Overrides) set(path, source, value string) {
+	o.values[path] = value
+	o.sources[path] = source
+}
+
+// Len 覆盖的配置项数量
+func (o *Overrides) Len() int {
+	return len(o.values)
+}
+
+// EnvOverrides 从环境变量（形如 KEY=VALUE 的列表，通常为 os.Environ()）中读取 SPEEDTESTUP_* 覆盖
+// 无法对应到任何配置项的 SPEEDTESTUP_* 变量视为错误，避免拼写错误被忽略
+func EnvOverrides(environ []string) (*Overrides, error) {
+	byEnv := make(map[string]Field)
+	for _, f := range Fields() {
+		byEnv[f.EnvName()] = f
+	}
+
+	o := newOverrides()
+	var unknown []string
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) || name == EnvConfigPath {
+			continue
+		}
+		f, ok := byEnv[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		o.set(f.Path, "环境变量 "+name, value)
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("未知的环境变量: %s", strings.Join(unknown, ", "))
+	}
+	return o, nil
+}
+
+// RegisterFlags 为每个配置项注册命令行参数（如 -speedup.check_interval=5m），返回解析后的覆盖
+func RegisterFlags(fs *flag.FlagSet) *Overrides {
+	o := newOverrides()
+	for _, f := range Fields() {
+		f := f
+		record := func(value string) error {
+			if err := setField(&Config{}, f.Path, value); err != nil {
+				return err
+			}
+			o.set(f.Path, "参数 -"+f.FlagName(), value)
+			return nil
+		}
+		if f.Type.Kind() == reflect.Bool {
+			fs.BoolFunc(f.FlagName(), f.usage(), record)
+		} else {
+			fs.Func(f.FlagName(), f.usage(), record)
+		}
+	}
+	return o
+}
+
+// apply 将覆盖应用到配置（多线路配置除外）
+func (o *Overrides) apply(cfg *Config) error {
+	paths := make([]string, 0, len(o.values))
+	for path := range o.values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if path == linesPath {
+			continue
+		}
+		if err := setField(cfg, path, o.values[path]); err != nil {
+			return fmt.Errorf("%s: %v", o.sources[path], err)
+		}
+	}
+	return nil
+}
+
+// applyToLines 将 speedup.* 覆盖应用到每条线路
+func (o *Overrides) applyToLines(cfg *Config) error {
+	paths := make([]string, 0, len(o.values))
+	for path := range o.values {
+		if strings.HasPrefix(path, speedupPrefix) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+	sort.Strings(paths)
+
+	for i := range cfg.Lines {
+		line := &cfg.Lines[i].SpeedupConfig
+		for _, path := range paths {
+			if err := setPath(reflect.ValueOf(line).Elem(), strings.TrimPrefix(path, speedupPrefix), o.values[path]); err != nil {
+				return fmt.Errorf("%s: %v", o.sources[path], err)
+			}
+		}
+		setSpeedupDefaults(line)
+	}
+	return nil
+}
+
+// setField 按路径设置配置项的值
+func setField(cfg *Config, path, value string) error {
+	return setPath(reflect.ValueOf(cfg).Elem(), path, value)
+}
+
+// setPath 按相对于 v 的路径设置配置项的值
+func setPath(v reflect.Value, path, value string) error {
+	for _, name := range strings.Split(path, ".") {
+		field, ok := fieldByName(v, name)
+		if !ok {
+			return fmt.Errorf("未知的配置项 %s", path)
+		}
+		v = field
+	}
+	return parseValue(v, value)
+}
+
+// fieldByName 按 yaml 字段名查找结构体字段
+func fieldByName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == name && t.Field(i).PkgPath == "" {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// parseValue 将字符串解析为配置项的值
+func parseValue(v reflect.Value, value string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("无法解析时长 %q", value)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("无法解析布尔值 %q", value)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("无法解析整数 %q", value)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.String:
+		m := reflect.MakeMap(v.Type())
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("无法解析 %q，格式应为 key=value", item)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)), reflect.ValueOf(strings.TrimSpace(val)))
+		}
+		v.Set(m)
+	default:
+		// 结构体列表等复杂类型以 JSON（或 YAML）整体覆盖
+		ptr := reflect.New(v.Type())
+		if err := yaml.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+			return fmt.Errorf("无法解析 JSON %q: %v", value, err)
+		}
+		v.Set(ptr.Elem())
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldsEnvNames(t *testing.T) {
+	names := make(map[string]string)
+	for _, f := range Fields() {
+		names[f.Path] = f.EnvName()
+	}
+
+	assert.Equal(t, "SPEEDTESTUP_SPEEDUP_CHECK_INTERVAL", names["speedup.check_interval"])
+	assert.Equal(t, "SPEEDTESTUP_SPEEDUP_IP_BINDING_BIND_IP", names["speedup.ip_binding.bind_ip"])
+	assert.Equal(t, "SPEEDTESTUP_NOTIFY_SINKS", names["notify.sinks"])
+	assert.Contains(t, names, "lines")
+	assert.NotContains(t, names, "line", "Runtime-only fields should not be overridable")
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeTempConfig(t, "config.json", `{
+		"speedup": {"enabled": true, "check_interval": "15m", "up_acc": true},
+		"logging": {"level": "warn"},
+		"lines": [{"name": "telecom"}, {"name": "unicom", "check_interval": "5m"}]
+	}`)
+
+	env, err := EnvOverrides([]string{
+		"PATH=/usr/bin",
+		"SPEEDTESTUP_CONFIG=/etc/speedup.json",
+		"SPEEDTESTUP_SPEEDUP_CHECK_INTERVAL=20m",
+		"SPEEDTESTUP_LOGGING_LEVEL=debug",
+		"SPEEDTESTUP_SPEEDUP_UP_ACC=false",
+	})
+	assert.NoError(t, err)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	assert.NoError(t, fs.Parse([]string{"-speedup.check_interval=30m", "-logging.components", "Scheduler=debug, IPService=warn"}))
+
+	cfg, err := Load(path, env, flags)
+	assert.NoError(t, err)
+
+	// 命令行参数 > 环境变量 > 配置文件
+	assert.Equal(t, 30*time.Minute, cfg.Speedup.CheckInterval)
+	assert.Equal(t, "debug", cfg.Logging.Level)
+	assert.False(t, cfg.Speedup.UpAcc)
+	assert.Equal(t, map[string]string{"Scheduler": "debug", "IPService": "warn"}, cfg.Logging.Components)
+
+	// 线路继承覆盖后的 speedup 配置；覆盖同样优先于配置文件中线路显式设置的字段
+	assert.Equal(t, 30*time.Minute, cfg.Lines[0].CheckInterval)
+	assert.False(t, cfg.Lines[0].UpAcc)
+	assert.Equal(t, 30*time.Minute, cfg.Lines[1].CheckInterval)
+	assert.False(t, cfg.Lines[1].UpAcc)
+}
+
+// 线路来自环境变量时，环境变量中线路显式设置的字段优先于同级的 speedup.* 覆盖，命令行参数仍然优先
+func TestLoadPrecedence_OverriddenLines(t *testing.T) {
+	path := writeTempConfig(t, "config.json", `{
+		"lines": [{"name": "file", "check_interval": "5m"}]
+	}`)
+
+	env, err := EnvOverrides([]string{
+		`SPEEDTESTUP_LINES=[{"name": "telecom", "check_interval": "5m", "status_check_interval": "2h"}]`,
+		"SPEEDTESTUP_SPEEDUP_CHECK_INTERVAL=20m",
+		"SPEEDTESTUP_SPEEDUP_STATUS_CHECK_INTERVAL=3h",
+	})
+	assert.NoError(t, err)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	assert.NoError(t, fs.Parse([]string{"-speedup.status_check_interval=4h"}))
+
+	cfg, err := Load(path, env, flags)
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Lines, 1)
+	assert.Equal(t, "telecom", cfg.Lines[0].Name)
+	assert.Equal(t, 5*time.Minute, cfg.Lines[0].CheckInterval)
+	assert.Equal(t, 4*time.Hour, cfg.Lines[0].StatusCheckInterval)
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	env, err := EnvOverrides([]string{
+		"SPEEDTESTUP_SPEEDUP_ENABLED=true",
+		`SPEEDTESTUP_LINES=[{"name": "telecom", "ip_binding": {"interface": "pppoe-wan"}}]`,
+		`SPEEDTESTUP_NOTIFY_SINKS=[{"type": "pushplus", "token": "abc"}]`,
+	})
+	assert.NoError(t, err)
+
+	cfg, err := Load("", env)
+	assert.NoError(t, err)
+	assert.True(t, cfg.AnyLineEnabled())
+	assert.Len(t, cfg.Lines, 1)
+	assert.Equal(t, "pppoe-wan", cfg.Lines[0].IPBinding.Interface)
+	assert.True(t, cfg.Lines[0].Enabled, "Line should inherit overridden enabled flag")
+	assert.Equal(t, "pushplus-1", cfg.Notify.Sinks[0].Name, "Defaults should apply to overridden values")
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestOverridesErrors(t *testing.T) {
+	_, err := EnvOverrides([]string{"SPEEDTESTUP_SPEEDUP_CHECK_INTERVALL=5m"})
+	assert.Error(t, err, "Unknown SPEEDTESTUP_* variables should be rejected")
+
+	env, err := EnvOverrides([]string{"SPEEDTESTUP_SPEEDUP_AUTO_RECOVERY_MAX_RETRIES=many"})
+	assert.NoError(t, err)
+	_, err = Load("", env)
+	assert.ErrorContains(t, err, "SPEEDTESTUP_SPEEDUP_AUTO_RECOVERY_MAX_RETRIES")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	RegisterFlags(fs)
+	assert.Error(t, fs.Parse([]string{"-speedup.enabled=maybe"}))
+}
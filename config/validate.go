@@ -0,0 +1,497 @@
+package config
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// FieldError 单个配置项的错误
+type FieldError struct {
+	Path    string // 配置项路径，如 speedup.ip_binding.bind_ip、lines[0].check_interval
+	Message string
+}
+
+// Error 实现 error 接口
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError 配置校验错误，包含发现的所有问题
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error 实现 error 接口，每个问题占一行
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Errors)+1)
+	lines = append(lines, fmt.Sprintf("配置校验失败（%d 个问题）:", len(e.Errors)))
+	for _, fe := range e.Errors {
+		lines = append(lines, "  - "+fe.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validator 收集校验过程中发现的问题
+type validator struct {
+	errors []FieldError
+}
+
+// addf 记录一个问题
+func (v *validator) addf(path, format string, args ...interface{}) {
+	v.errors = append(v.errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// 配置项的取值范围
+const (
+	minCheckInterval       = time.Minute
+	maxCheckInterval       = time.Hour
+	maxStatusCheckInterval = 24 * time.Hour
+)
+
+// Validate 校验配置，返回包含所有问题（带配置项路径）的 *ValidationError
+// 包括未知的配置项、无效的 cron 表达式、无法解析的绑定 IP、无效的网络接口名称和超出范围的间隔等
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	for _, key := range c.unknownKeys {
+		v.addf(key, "未知的配置项")
+	}
+
+	if len(c.Lines) == 0 {
+		validateSpeedup(v, "speedup", &c.Speedup)
+	} else {
+		for i := range c.Lines {
+			validateSpeedup(v, fmt.Sprintf("lines[%d]", i), &c.Lines[i].SpeedupConfig)
+		}
+	}
+
+	validateHTTPClient(v, "http_client", &c.HTTPClient)
+	validateEndpoints(v, "endpoints", &c.Endpoints)
+	validateLogging(v, "logging", &c.Logging)
+
+	if c.Server.Enabled {
+		if _, _, err := net.SplitHostPort(c.Server.Listen); err != nil {
+			v.addf("server.listen", "监听地址无效: %v", err)
+		}
+	}
+
+	for i := range c.Notify.Sinks {
+		validateNotifySink(v, fmt.Sprintf("notify.sinks[%d]", i), &c.Notify.Sinks[i])
+	}
+
+	if c.Reload.Interval <= 0 {
+		v.addf("reload.interval", "必须大于 0，当前为 %v", c.Reload.Interval)
+	}
+	if c.Shutdown.Timeout <= 0 {
+		v.addf("shutdown.timeout", "必须大于 0，当前为 %v", c.Shutdown.Timeout)
+	}
+
+	if len(v.errors) > 0 {
+		return &ValidationError{Errors: v.errors}
+	}
+	return nil
+}
+
+// ValidateCron 校验标准 5 段 cron 表达式
+func ValidateCron(expr string) error {
+	_, err := cron.ParseStandard(expr)
+	return err
+}
+
+// validateSpeedup 校验单条线路的提速配置
+func validateSpeedup(v *validator, path string, sc *SpeedupConfig) {
+	if sc.CheckInterval < minCheckInterval || sc.CheckInterval > maxCheckInterval {
+		v.addf(path+".check_interval", "必须在 %v 到 %v 之间，当前为 %v", minCheckInterval, maxCheckInterval, sc.CheckInterval)
+	}
+	if sc.StatusCheckInterval < 0 || sc.StatusCheckInterval > maxStatusCheckInterval {
+		v.addf(path+".status_check_interval", "必须在 0（禁用）到 %v 之间，当前为 %v", maxStatusCheckInterval, sc.StatusCheckInterval)
+	}
+	if err := ValidateCron(sc.ReopenSchedule); err != nil {
+		v.addf(path+".reopen_schedule", "cron 表达式 %q 无效: %v", sc.ReopenSchedule, err)
+	}
+	if sc.MinReopenInterval < 0 {
+		v.addf(path+".min_reopen_interval", "不能为负数，当前为 %v", sc.MinReopenInterval)
+	}
+	switch sc.Startup.Policy {
+	case StartupAlways, StartupIfInactive, StartupSkip:
+	default:
+		v.addf(path+".startup.policy", "未知的首次提速策略 %q（可选 always, if_inactive, skip）", sc.Startup.Policy)
+	}
+	if sc.Startup.MinInterval < 0 {
+		v.addf(path+".startup.min_interval", "不能为负数，当前为 %v", sc.Startup.MinInterval)
+	}
+
+	validateIPBinding(v, path+".ip_binding", &sc.IPBinding)
+	validateIPDetection(v, path+".ip_detection", &sc.IPDetection)
+
+	if sc.AutoRecovery.MaxRetries < 0 {
+		v.addf(path+".auto_recovery.max_retries", "不能为负数，当前为 %d", sc.AutoRecovery.MaxRetries)
+	}
+	if sc.AutoRecovery.RetryInterval <= 0 {
+		v.addf(path+".auto_recovery.retry_interval", "必须大于 0，当前为 %v", sc.AutoRecovery.RetryInterval)
+	}
+	switch sc.AutoRecovery.Backoff {
+	case BackoffFixed, BackoffExponential:
+	default:
+		v.addf(path+".auto_recovery.backoff", "未知的退避策略 %q（可选 fixed, exponential）", sc.AutoRecovery.Backoff)
+	}
+	if sc.AutoRecovery.MaxInterval < 0 {
+		v.addf(path+".auto_recovery.max_interval", "不能为负数，当前为 %v", sc.AutoRecovery.MaxInterval)
+	} else if sc.AutoRecovery.MaxInterval > 0 && sc.AutoRecovery.MaxInterval < sc.AutoRecovery.RetryInterval {
+		v.addf(path+".auto_recovery.max_interval", "不能小于 retry_interval（%v），当前为 %v", sc.AutoRecovery.RetryInterval, sc.AutoRecovery.MaxInterval)
+	}
+	if sc.AutoRecovery.Jitter < 0 {
+		v.addf(path+".auto_recovery.jitter", "不能为负数，当前为 %v", sc.AutoRecovery.Jitter)
+	}
+	if sc.AutoRecovery.Cooldown < 0 {
+		v.addf(path+".auto_recovery.cooldown", "不能为负数，当前为 %v", sc.AutoRecovery.Cooldown)
+	}
+	if sc.SelfCheck.Interval <= 0 {
+		v.addf(path+".self_check.interval", "必须大于 0，当前为 %v", sc.SelfCheck.Interval)
+	}
+	if sc.ExpiryRenewal.Lead <= 0 {
+		v.addf(path+".expiry_renewal.lead", "必须大于 0，当前为 %v", sc.ExpiryRenewal.Lead)
+	}
+	if sc.ExpiryRenewal.Jitter < 0 {
+		v.addf(path+".expiry_renewal.jitter", "不能为负数，当前为 %v", sc.ExpiryRenewal.Jitter)
+	}
+}
+
+// validateFamily 校验地址族
+func validateFamily(v *validator, path, family string) {
+	switch family {
+	case FamilyAny, FamilyIPv4, FamilyIPv6:
+	default:
+		v.addf(path, "未知的地址族 %q（可选 any, ipv4, ipv6）", family)
+	}
+}
+
+// validateIPBinding 校验 IP 绑定配置
+// 启用绑定时检查绑定 IP 能否解析，按接口绑定时只检查接口名称的格式：
+// 接口在拨号时才解析，PPPoE 接口在开机或重拨期间暂时不存在不影响启动和热重载
+func validateIPBinding(v *validator, path string, b *IPBindingConfig) {
+	validateFamily(v, path+".family", b.Family)
+
+	if b.BindIP != "" {
+		addr, err := netip.ParseAddr(b.BindIP)
+		switch {
+		case err != nil:
+			v.addf(path+".bind_ip", "无法解析 IP 地址 %q", b.BindIP)
+		case b.Family == FamilyIPv4 && !addr.Unmap().Is4():
+			v.addf(path+".bind_ip", "%s 不是 IPv4 地址，与 family=ipv4 不符", b.BindIP)
+		case b.Family == FamilyIPv6 && addr.Unmap().Is4():
+			v.addf(path+".bind_ip", "%s 不是 IPv6 地址，与 family=ipv6 不符", b.BindIP)
+		}
+		return
+	}
+
+	if b.Enabled {
+		if b.Interface == "" {
+			v.addf(path+".interface", "启用 IP 绑定时必须设置 interface 或 bind_ip")
+		} else if err := validateInterfaceName(b.Interface); err != nil {
+			v.addf(path+".interface", "网络接口名称 %q 无效: %v", b.Interface, err)
+		}
+	}
+}
+
+// maxInterfaceNameLen 网络接口名称的最大长度（Linux IFNAMSIZ - 1）
+const maxInterfaceNameLen = 15
+
+// validateInterfaceName 检查网络接口名称的格式
+func validateInterfaceName(name string) error {
+	if len(name) > maxInterfaceNameLen {
+		return fmt.Errorf("长度不能超过 %d", maxInterfaceNameLen)
+	}
+	if name == "." || name == ".." {
+		return fmt.Errorf("不能为 . 或 ..")
+	}
+	if strings.ContainsAny(name, "/ \t\n") {
+		return fmt.Errorf("不能包含 / 或空白字符")
+	}
+	return nil
+}
+
+// Warnings 返回不影响启动的配置问题，如按接口绑定时接口当前不存在
+func (c *Config) Warnings() []string {
+	var warnings []string
+	for i, line := range c.EffectiveLines() {
+		b := line.IPBinding
+		if !line.Enabled || !b.Enabled || b.BindIP != "" || b.Interface == "" {
+			continue
+		}
+		if _, err := net.InterfaceByName(b.Interface); err != nil {
+			path := "speedup"
+			if len(c.Lines) > 0 {
+				path = fmt.Sprintf("lines[%d]", i)
+			}
+			warnings = append(warnings, fmt.Sprintf("%s.ip_binding.interface: 网络接口 %q 当前不存在，将在拨号时重新查找", path, b.Interface))
+		}
+	}
+	return warnings
+}
+
+// validateIPDetection 校验公网 IP 检测配置
+func validateIPDetection(v *validator, path string, d *IPDetectionConfig) {
+	if len(d.Providers) == 0 {
+		v.addf(path+".providers", "至少需要一个 IP 提供者")
+	}
+	if d.Quorum < 1 || (len(d.Providers) > 0 && d.Quorum > len(d.Providers)) {
+		v.addf(path+".quorum", "必须在 1 到提供者数量 %d 之间，当前为 %d", len(d.Providers), d.Quorum)
+	}
+
+	for i := range d.Providers {
+		p := &d.Providers[i]
+		pp := fmt.Sprintf("%s.providers[%d]", path, i)
+		switch p.Type {
+		case IPProviderText, IPProviderJSON:
+			if p.URL == "" {
+				v.addf(pp+".url", "%s 类型的提供者必须设置 url", p.Type)
+			}
+		case IPProviderDNS:
+			if p.Resolver == "" {
+				v.addf(pp+".resolver", "dns 类型的提供者必须设置 resolver")
+			}
+			if p.Host == "" {
+				v.addf(pp+".host", "dns 类型的提供者必须设置 host")
+			}
+		default:
+			v.addf(pp+".type", "未知的提供者类型 %q（可选 text, json, dns）", p.Type)
+		}
+		validateFamily(v, pp+".family", p.Family)
+		if p.Timeout <= 0 {
+			v.addf(pp+".timeout", "必须大于 0，当前为 %v", p.Timeout)
+		}
+	}
+}
+
+// validateHTTPClient 校验 HTTP 客户端配置
+func validateHTTPClient(v *validator, path string, h *HTTPClientConfig) {
+	if h.Timeouts.Query <= 0 {
+		v.addf(path+".timeouts.query", "必须大于 0，当前为 %v", h.Timeouts.Query)
+	}
+	if h.Timeouts.Reopen <= 0 {
+		v.addf(path+".timeouts.reopen", "必须大于 0，当前为 %v", h.Timeouts.Reopen)
+	}
+
+	if h.Retry.MaxRetries < 0 {
+		v.addf(path+".retry.max_retries", "不能为负数，当前为 %d", h.Retry.MaxRetries)
+	}
+	if h.Retry.Wait <= 0 {
+		v.addf(path+".retry.wait", "必须大于 0，当前为 %v", h.Retry.Wait)
+	}
+	if h.Retry.MaxWait < h.Retry.Wait {
+		v.addf(path+".retry.max_wait", "不能小于 retry.wait（%v），当前为 %v", h.Retry.Wait, h.Retry.MaxWait)
+	}
+
+	if h.Proxy != "" {
+		proxyURL, err := url.Parse(h.Proxy)
+		switch {
+		case err != nil:
+			v.addf(path+".proxy", "代理地址无效: %v", err)
+		case proxyURL.Scheme != "http" && proxyURL.Scheme != "https" && proxyURL.Scheme != "socks5" && proxyURL.Scheme != "socks5h":
+			v.addf(path+".proxy", "代理协议 %q 不受支持（可选 http, https, socks5, socks5h）", proxyURL.Scheme)
+		case proxyURL.Host == "":
+			v.addf(path+".proxy", "代理地址 %q 缺少主机", h.Proxy)
+		}
+	}
+
+	for name := range h.Headers {
+		if strings.TrimSpace(name) == "" {
+			v.addf(path+".headers", "请求头名称不能为空")
+		}
+	}
+
+	if h.CAFile != "" {
+		data, err := os.ReadFile(h.CAFile)
+		if err != nil {
+			v.addf(path+".ca_file", "读取 CA 证书文件失败: %v", err)
+		} else if !x509.NewCertPool().AppendCertsFromPEM(data) {
+			v.addf(path+".ca_file", "文件 %s 中没有有效的 PEM 证书", h.CAFile)
+		}
+	}
+}
+
+// validateEndpoints 校验 speedtest.cn 接口地址
+func validateEndpoints(v *validator, path string, e *EndpointsConfig) {
+	var base *url.URL
+	if e.BaseURL != "" {
+		u, err := parseHTTPURL(e.BaseURL)
+		if err != nil {
+			v.addf(path+".base_url", "%v", err)
+			return
+		}
+		base = u
+	}
+	if _, err := resolveEndpoint(base, e.Query); err != nil {
+		v.addf(path+".query", "%v", err)
+	}
+	if _, err := resolveEndpoint(base, e.Reopen); err != nil {
+		v.addf(path+".reopen", "%v", err)
+	}
+}
+
+// validateLogging 校验日志配置
+func validateLogging(v *validator, path string, l *LoggingConfig) {
+	if !validLevel(l.Level) {
+		v.addf(path+".level", "未知的日志级别 %q（可选 debug, info, warn, error）", l.Level)
+	}
+	switch l.Output {
+	case "stdout", "stderr":
+	case "file":
+		if l.File == "" {
+			v.addf(path+".file", "output 为 file 时必须设置日志文件路径")
+		}
+	default:
+		v.addf(path+".output", "未知的输出方式 %q（可选 stdout, stderr, file）", l.Output)
+	}
+	switch l.Format {
+	case "text", "json":
+	default:
+		v.addf(path+".format", "未知的日志格式 %q（可选 text, json）", l.Format)
+	}
+
+	components := make([]string, 0, len(l.Components))
+	for name := range l.Components {
+		components = append(components, name)
+	}
+	sort.Strings(components)
+	for _, name := range components {
+		if !validLevel(l.Components[name]) {
+			v.addf(path+".components."+name, "未知的日志级别 %q", l.Components[name])
+		}
+	}
+
+	if l.MaxSize < 0 {
+		v.addf(path+".max_size", "不能为负数，当前为 %d", l.MaxSize)
+	}
+	if l.MaxAge < 0 {
+		v.addf(path+".max_age", "不能为负数，当前为 %v", l.MaxAge)
+	}
+	if l.MaxBackups < 0 {
+		v.addf(path+".max_backups", "不能为负数，当前为 %d", l.MaxBackups)
+	}
+}
+
+// validLevel 检查日志级别是否有效
+func validLevel(level string) bool {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "warning", "error":
+		return true
+	}
+	return false
+}
+
+// validateNotifySink 校验单个通知渠道配置
+func validateNotifySink(v *validator, path string, s *NotifySinkConfig) {
+	switch s.Type {
+	case NotifyWebhook:
+		if s.URL == "" {
+			v.addf(path+".url", "webhook 渠道必须设置 url")
+		}
+	case NotifyTelegram:
+		if s.Token == "" {
+			v.addf(path+".token", "telegram 渠道必须设置 token")
+		}
+		if s.ChatID == "" {
+			v.addf(path+".chat_id", "telegram 渠道必须设置 chat_id")
+		}
+	case NotifyServerChan:
+		if s.SendKey == "" {
+			v.addf(path+".send_key", "serverchan 渠道必须设置 send_key")
+		}
+	case NotifyPushPlus:
+		if s.Token == "" {
+			v.addf(path+".token", "pushplus 渠道必须设置 token")
+		}
+	case NotifySMTP:
+		if s.Host == "" {
+			v.addf(path+".host", "smtp 渠道必须设置 host")
+		}
+		if len(s.To) == 0 {
+			v.addf(path+".to", "smtp 渠道必须设置收件人")
+		}
+	default:
+		v.addf(path+".type", "未知的通知渠道类型 %q（可选 webhook, telegram, serverchan, pushplus, smtp）", s.Type)
+	}
+	if s.RateLimit < 0 {
+		v.addf(path+".rate_limit", "不能为负数，当前为 %v", s.RateLimit)
+	}
+}
+
+// findUnknownKeys 对照配置结构体的 yaml 标签查找未知的配置项，返回配置项路径
+func findUnknownKeys(path string, value interface{}, t reflect.Type) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil
+		}
+		fields := make(map[string]reflect.Type)
+		collectFields(t, fields)
+
+		var unknown []string
+		for k, v := range m {
+			key := fmt.Sprint(k)
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			ft, ok := fields[key]
+			if !ok {
+				unknown = append(unknown, keyPath)
+				continue
+			}
+			unknown = append(unknown, findUnknownKeys(keyPath, v, ft)...)
+		}
+		sort.Strings(unknown)
+		return unknown
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		var unknown []string
+		for i, item := range items {
+			unknown = append(unknown, findUnknownKeys(fmt.Sprintf("%s[%d]", path, i), item, t.Elem())...)
+		}
+		return unknown
+	}
+	return nil
+}
+
+// collectFields 收集结构体的 yaml 字段名及类型（展开 inline 字段）
+func collectFields(t reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if opts == "inline" {
+			collectFields(f.Type, fields)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTempConfig 写入临时配置文件
+func writeTempConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// errorPaths 返回校验错误中的配置项路径
+func errorPaths(t *testing.T, err error) []string {
+	t.Helper()
+	verr, ok := err.(*ValidationError)
+	if !assert.True(t, ok, "expected *ValidationError, got %v", err) {
+		return nil
+	}
+	paths := make([]string, 0, len(verr.Errors))
+	for _, fe := range verr.Errors {
+		paths = append(paths, fe.Path)
+	}
+	return paths
+}
+
+func TestValidateDefaultConfig(t *testing.T) {
+	assert.NoError(t, NewDefaultConfig().Validate(), "Default config should be valid")
+}
+
+func TestValidateCollectsAllProblems(t *testing.T) {
+	path := writeTempConfig(t, "config.json", `{
+		"speedup": {
+			"enabled": true,
+			"check_intervall": "5m",
+			"reopen_schedule": "0 0 * *",
+			"status_check_interval": "48h",
+			"ip_binding": {"enabled": true, "bind_ip": "10.0.0.256"}
+		},
+		"lines": [
+			{"name": "telecom", "ip_binding": {"enabled": true, "interface": "no-such-if0"}},
+			{"name": "unicom", "ip_binding": {"family": "ipx"}, "check_interval": "2h"}
+		],
+		"logging": {"level": "verbose", "output": "file"},
+		"notify": {"sinks": [{"type": "telegram", "token": "t"}]}
+	}`)
+
+	cfg, err := LoadConfig(path)
+	assert.NoError(t, err)
+
+	err = cfg.Validate()
+	assert.Error(t, err)
+	assert.ElementsMatch(t, []string{
+		"speedup.check_intervall",
+		"lines[0].reopen_schedule",
+		"lines[0].status_check_interval",
+		"lines[0].ip_binding.bind_ip",
+		"lines[1].reopen_schedule",
+		"lines[1].status_check_interval",
+		"lines[1].check_interval",
+		"lines[1].ip_binding.family",
+		"lines[1].ip_binding.bind_ip",
+		"logging.level",
+		"logging.file",
+		"notify.sinks[0].chat_id",
+	}, errorPaths(t, err))
+	assert.Contains(t, err.Error(), "lines[0].ip_binding.bind_ip: 无法解析 IP 地址")
+}
+
+func TestValidateInterfaceBinding(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Speedup.IPBinding.Enabled = true
+	cfg.Speedup.IPBinding.Interface = "bad/name"
+	assert.Equal(t, []string{"speedup.ip_binding.interface"}, errorPaths(t, cfg.Validate()))
+
+	// 接口暂时不存在（如 PPPoE 重拨期间）只产生警告
+	cfg.Speedup.Enabled = true
+	cfg.Speedup.IPBinding.Interface = "no-such-if0"
+	assert.NoError(t, cfg.Validate())
+	warnings := cfg.Warnings()
+	if assert.Len(t, warnings, 1) {
+		assert.Contains(t, warnings[0], "speedup.ip_binding.interface")
+	}
+
+	cfg.Speedup.IPBinding.Interface = "lo"
+	if _, err := os.Stat("/sys/class/net/lo"); err == nil {
+		assert.NoError(t, cfg.Validate(), "Existing interface should pass validation")
+	}
+}
+
+func TestLoadConfigJSONSyntaxError(t *testing.T) {
+	path := writeTempConfig(t, "config.json", "{\n  \"speedup\": {\n    \"enabled\": true,\n  }\n}")
+
+	_, err := LoadConfig(path)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "JSON 语法错误（第 4 行", "Syntax error should report its position")
+}
+
+func TestLoadConfigUnknownKeysYAML(t *testing.T) {
+	path := writeTempConfig(t, "config.yaml", `
+speedup:
+  enabled: true
+  auto_recovery:
+    max_retry: 5
+logging:
+  level: info
+serverr:
+  enabled: true
+`)
+
+	cfg, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"speedup.auto_recovery.max_retry", "serverr"}, errorPaths(t, cfg.Validate()))
+}
+
+func TestValidateHTTPClient(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.HTTPClient.Proxy = "ftp://proxy:21"
+	cfg.HTTPClient.Retry.MaxRetries = -1
+	cfg.HTTPClient.Retry.MaxWait = 100 * time.Millisecond
+	cfg.HTTPClient.CAFile = writeTempConfig(t, "ca.pem", "not a certificate")
+	assert.ElementsMatch(t, []string{
+		"http_client.proxy",
+		"http_client.retry.max_retries",
+		"http_client.retry.max_wait",
+		"http_client.ca_file",
+	}, errorPaths(t, cfg.Validate()))
+
+	cfg = NewDefaultConfig()
+	cfg.HTTPClient.Proxy = "socks5h://127.0.0.1:1080"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateAutoRecovery(t *testing.T) {
+	cfg := NewDefaultConfig()
+	cfg.Speedup.AutoRecovery.Backoff = "linear"
+	cfg.Speedup.AutoRecovery.MaxInterval = time.Minute
+	cfg.Speedup.AutoRecovery.Jitter = -time.Second
+	cfg.Speedup.AutoRecovery.Cooldown = -time.Minute
+	assert.ElementsMatch(t, []string{
+		"speedup.auto_recovery.backoff",
+		"speedup.auto_recovery.max_interval",
+		"speedup.auto_recovery.jitter",
+		"speedup.auto_recovery.cooldown",
+	}, errorPaths(t, cfg.Validate()))
+
+	cfg = NewDefaultConfig()
+	cfg.Speedup.AutoRecovery.Backoff = BackoffExponential
+	cfg.Speedup.AutoRecovery.MaxInterval = 0
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateShutdown(t *testing.T) {
+	cfg := NewDefaultConfig()
+	assert.Equal(t, 15*time.Second, cfg.Shutdown.Timeout)
+
+	cfg.Shutdown.Timeout = 0
+	assert.Equal(t, []string{"shutdown.timeout"}, errorPaths(t, cfg.Validate()))
+}
+
+func TestValidateMinReopenInterval(t *testing.T) {
+	cfg := NewDefaultConfig()
+	assert.Equal(t, time.Minute, cfg.Speedup.MinReopenInterval)
+
+	cfg.Speedup.MinReopenInterval = -time.Second
+	assert.Equal(t, []string{"speedup.min_reopen_interval"}, errorPaths(t, cfg.Validate()))
+
+	cfg.Speedup.MinReopenInterval = 0
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateStartup(t *testing.T) {
+	cfg := NewDefaultConfig()
+	assert.Equal(t, StartupAlways, cfg.Speedup.Startup.Policy)
+
+	cfg.Speedup.Startup.Policy = "never"
+	cfg.Speedup.Startup.MinInterval = -time.Minute
+	assert.ElementsMatch(t, []string{
+		"speedup.startup.policy",
+		"speedup.startup.min_interval",
+	}, errorPaths(t, cfg.Validate()))
+
+	cfg.Speedup.Startup.Policy = StartupIfInactive
+	cfg.Speedup.Startup.MinInterval = 0
+	assert.NoError(t, cfg.Validate())
+}
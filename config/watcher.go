@@ -0,0 +1,80 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"sync"
+	"time"
+)
+
+// Watcher 配置文件监视器
+// 定期读取配置文件并比较内容摘要，内容变化时触发回调
+// 使用轮询而非文件系统通知，兼容编辑器的原子替换和各类文件系统
+type Watcher struct {
+	path     string
+	interval time.Duration
+	sum      []byte
+	stop     chan struct{}
+	done     chan struct{}
+	started  bool
+	once     sync.Once
+}
+
+// NewWatcher 创建配置文件监视器，以当前文件内容为基准
+func NewWatcher(path string, interval time.Duration) *Watcher {
+	w := &Watcher{
+		path:     path,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	w.sum, _ = w.checksum()
+	return w
+}
+
+// checksum 计算配置文件内容的摘要
+func (w *Watcher) checksum() ([]byte, error) {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+// Start 在后台开始监视，文件内容变化时调用 onChange
+// 文件暂时不可读（如正在被替换）时跳过本次检查
+func (w *Watcher) Start(onChange func()) {
+	w.started = true
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				sum, err := w.checksum()
+				if err != nil || bytes.Equal(sum, w.sum) {
+					continue
+				}
+				w.sum = sum
+				onChange()
+			}
+		}
+	}()
+}
+
+// Stop 停止监视，等待正在执行的回调结束
+func (w *Watcher) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	if w.started {
+		<-w.done
+	}
+}
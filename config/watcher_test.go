@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"speedup": {"enabled": true}}`), 0644))
+
+	changed := make(chan struct{}, 1)
+	watcher := NewWatcher(path, 10*time.Millisecond)
+	watcher.Start(func() { changed <- struct{}{} })
+	defer watcher.Stop()
+
+	// 内容未变化时不触发
+	assert.NoError(t, os.WriteFile(path, []byte(`{"speedup": {"enabled": true}}`), 0644))
+	select {
+	case <-changed:
+		t.Fatal("Watcher should not fire when content is unchanged")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"speedup": {"enabled": false}}`), 0644))
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("Watcher should fire when content changes")
+	}
+}
@@ -0,0 +1,330 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// collector 可输出为 Prometheus 文本格式的指标
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// register 注册指标
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText 以 Prometheus 文本格式（0.0.4）输出所有指标
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler 返回输出指标的 HTTP 处理器
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+// desc 指标描述
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+// writeHeader 输出 HELP 和 TYPE 行
+func (d *desc) writeHeader(w *bufio.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, typ)
+}
+
+// key 将标签值编码为内部键
+func (d *desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s 需要 %d 个标签值，实际为 %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// formatLabels 输出标签部分，extra 为额外的标签（如直方图的 le）
+func (d *desc) formatLabels(key string, extra ...string) string {
+	var pairs []string
+	if len(d.labels) > 0 {
+		values := strings.Split(key, "\xff")
+		for i, label := range d.labels {
+			pairs = append(pairs, label+`="`+escapeLabel(values[i])+`"`)
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec 创建并注册计数器
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]float64)}
+	r.register(c)
+	return c
+}
+
+// Inc 计数加一
+func (c *CounterVec) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Add 计数增加 v（v 必须非负）
+func (c *CounterVec) Add(v float64, labels ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labels)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+// Value 获取当前计数（用于测试）
+func (c *CounterVec) Value(labels ...string) float64 {
+	key := c.key(labels)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeHeader(w, "counter")
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.formatLabels(key), formatFloat(c.values[key]))
+	}
+}
+
+// gaugeValue 仪表值，设置了 deadline 时在输出时计算距离该时间的秒数
+type gaugeValue struct {
+	value    float64
+	deadline time.Time
+	dynamic  bool
+}
+
+// GaugeVec 带标签的仪表
+type GaugeVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]gaugeValue
+	now    func() time.Time
+}
+
+// NewGaugeVec 创建并注册仪表
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]gaugeValue), now: time.Now}
+	r.register(g)
+	return g
+}
+
+// Set 设置仪表值
+func (g *GaugeVec) Set(v float64, labels ...string) {
+	key := g.key(labels)
+	g.mu.Lock()
+	g.values[key] = gaugeValue{value: v}
+	g.mu.Unlock()
+}
+
+// SetToTimeUntil 设置为距离指定时间的秒数（每次输出时重新计算，过期后为负数）
+func (g *GaugeVec) SetToTimeUntil(t time.Time, labels ...string) {
+	key := g.key(labels)
+	g.mu.Lock()
+	g.values[key] = gaugeValue{deadline: t, dynamic: true}
+	g.mu.Unlock()
+}
+
+// Delete 删除指定标签的序列
+func (g *GaugeVec) Delete(labels ...string) {
+	key := g.key(labels)
+	g.mu.Lock()
+	delete(g.values, key)
+	g.mu.Unlock()
+}
+
+// Value 获取当前仪表值（用于测试）
+func (g *GaugeVec) Value(labels ...string) float64 {
+	key := g.key(labels)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resolve(g.values[key])
+}
+
+func (g *GaugeVec) resolve(v gaugeValue) float64 {
+	if v.dynamic {
+		return v.deadline.Sub(g.now()).Seconds()
+	}
+	return v.value
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.writeHeader(w, "gauge")
+	for _, key := range sortedKeys(g.values) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, g.formatLabels(key), formatFloat(g.resolve(g.values[key])))
+	}
+}
+
+// histogramValue 单个直方图序列
+type histogramValue struct {
+	counts []uint64 // 每个桶的计数（非累积）
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+// DefaultBuckets 默认的延迟桶（秒）
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// NewHistogramVec 创建并注册直方图
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	h := &HistogramVec{desc: desc{name: name, help: help, labels: labels}, buckets: sorted, values: make(map[string]*histogramValue)}
+	r.register(h)
+	return h
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(v float64, labels ...string) {
+	key := h.key(labels)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			hv.counts[i]++
+			break
+		}
+	}
+	hv.sum += v
+	hv.count++
+}
+
+// Count 获取观测次数（用于测试）
+func (h *HistogramVec) Count(labels ...string) uint64 {
+	key := h.key(labels)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hv, ok := h.values[key]; ok {
+		return hv.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w, "histogram")
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += hv.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.formatLabels(key, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.formatLabels(key, "le", "+Inf"), hv.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.formatLabels(key), formatFloat(hv.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.formatLabels(key), hv.count)
+	}
+}
+
+// sortedKeys 返回排序后的键，保证输出稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatFloat 按 Prometheus 文本格式输出浮点数
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeLabel 转义标签值
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// escapeHelp 转义 HELP 文本
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
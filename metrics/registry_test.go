@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+	counter := r.NewCounterVec("test_total", "测试计数", "line", "code")
+	gauge := r.NewGaugeVec("test_gauge", "测试仪表", "line")
+	histogram := r.NewHistogramVec("test_seconds", "测试耗时", []float64{0.1, 1}, "line")
+
+	counter.Inc("a", "0")
+	counter.Add(2, "a", "10021")
+	counter.Add(-1, "a", "0") // 负数被忽略
+	gauge.Set(300, `b"x`)
+	histogram.Observe(0.05, "a")
+	histogram.Observe(0.5, "a")
+	histogram.Observe(5, "a")
+
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatalf("WriteText returned error: %v", err)
+	}
+	out := sb.String()
+
+	expected := []string{
+		"# TYPE test_total counter",
+		`test_total{line="a",code="0"} 1`,
+		`test_total{line="a",code="10021"} 2`,
+		"# TYPE test_gauge gauge",
+		`test_gauge{line="b\"x"} 300`,
+		"# TYPE test_seconds histogram",
+		`test_seconds_bucket{line="a",le="0.1"} 1`,
+		`test_seconds_bucket{line="a",le="1"} 2`,
+		`test_seconds_bucket{line="a",le="+Inf"} 3`,
+		`test_seconds_sum{line="a"} 5.55`,
+		`test_seconds_count{line="a"} 3`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, out)
+		}
+	}
+}
+
+func TestGaugeVec_SetToTimeUntil(t *testing.T) {
+	r := NewRegistry()
+	gauge := r.NewGaugeVec("test_expiry_seconds", "测试截止时间", "kind")
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	gauge.now = func() time.Time { return now }
+
+	gauge.SetToTimeUntil(now.Add(time.Hour), "down")
+	if v := gauge.Value("down"); v != 3600 {
+		t.Errorf("Expected 3600, got %v", v)
+	}
+
+	// 时间流逝后重新计算
+	now = now.Add(2 * time.Hour)
+	if v := gauge.Value("down"); v != -3600 {
+		t.Errorf("Expected -3600, got %v", v)
+	}
+
+	gauge.Delete("down")
+	var sb strings.Builder
+	_ = r.WriteText(&sb)
+	if strings.Contains(sb.String(), `kind="down"`) {
+		t.Errorf("Expected deleted series to be absent, got:\n%s", sb.String())
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "测试计数").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type: %s", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "test_total 1\n") {
+		t.Errorf("Unexpected body: %s", rec.Body.String())
+	}
+}
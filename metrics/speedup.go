@@ -0,0 +1,51 @@
+package metrics
+
+// Default 默认指标注册表
+var Default = NewRegistry()
+
+// 提速相关指标
+var (
+	// ExecuteTotal 提速执行次数，result 为 success/failure，code 为接口错误码或错误类型
+	ExecuteTotal = Default.NewCounterVec("speedtestup_execute_total",
+		"提速执行次数", "line", "result", "code")
+
+	// ExecuteTriggers 提速触发次数，source 为触发来源，coalesced 表示是否合并到已在进行的提速
+	ExecuteTriggers = Default.NewCounterVec("speedtestup_execute_triggers_total",
+		"提速触发次数", "line", "source", "coalesced")
+
+	// RequestDuration 提速接口请求耗时
+	RequestDuration = Default.NewHistogramVec("speedtestup_request_duration_seconds",
+		"提速接口请求耗时（秒）", nil, "line", "endpoint")
+
+	// DownloadBandwidth 下行提速带宽（Mbps）
+	DownloadBandwidth = Default.NewGaugeVec("speedtestup_download_bandwidth_mbps",
+		"下行提速带宽（Mbps）", "line")
+
+	// UploadBandwidth 上行提速带宽（Kbps），class 为 h（一类）或 100（二类）
+	UploadBandwidth = Default.NewGaugeVec("speedtestup_upload_bandwidth_kbps",
+		"上行提速带宽（Kbps）", "line", "class")
+
+	// ExpirySeconds 距离提速截止的秒数，已过期时为负数
+	ExpirySeconds = Default.NewGaugeVec("speedtestup_expiry_seconds",
+		"距离提速截止的秒数", "line", "kind")
+
+	// IPChangesTotal 公网 IP 或绑定接口地址变化次数
+	IPChangesTotal = Default.NewCounterVec("speedtestup_ip_changes_total",
+		"IP 变化次数", "line", "family")
+
+	// LastHeartbeat 最近一次心跳检测的 Unix 时间戳
+	LastHeartbeat = Default.NewGaugeVec("speedtestup_last_heartbeat_timestamp_seconds",
+		"最近一次心跳检测的 Unix 时间戳", "line")
+)
+
+// 接口名称
+const (
+	EndpointReopen = "reopen"
+	EndpointQuery  = "query"
+)
+
+// 执行结果
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
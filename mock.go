@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"speedtestup/mockserver"
+)
+
+// runMockServer 启动模拟的 speedtest.cn 服务，直到收到退出信号
+// 不需要加载配置，用于离线测试和演示
+func runMockServer(args []string) int {
+	fs := flag.NewFlagSet("mock-server", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	listen := fs.String("listen", "127.0.0.1:8090", "监听地址")
+	script := fs.String("scenario", "active", "场景脚本，逗号分隔的 name[:count]，如 expired:2,active")
+	ip := fs.String("ip", mockserver.DefaultIP, "返回的出口 IP")
+	delay := fs.Duration("delay", 0, "每次响应前的延迟，覆盖场景的默认延迟")
+	list := fs.Bool("list", false, "列出内置场景后退出")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "❌ 多余的参数: %v\n", fs.Args())
+		return exitUsage
+	}
+
+	if *list {
+		printScenarios()
+		return exitOK
+	}
+
+	steps, err := mockserver.ParseScript(*script)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return exitUsage
+	}
+	if *delay > 0 {
+		for i := range steps {
+			steps[i].Scenario.Delay = *delay
+		}
+	}
+
+	handler := mockserver.New(steps,
+		mockserver.WithIP(*ip),
+		mockserver.WithRequestLog(func(endpoint, scenario string) {
+			fmt.Printf("%s 📥 %s -> %s\n", time.Now().Format("2006/01/02 15:04:05"), endpoint, scenario)
+		}))
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 监听 %s 失败: %v\n", *listen, err)
+		return exitFailure
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	baseURL := "http://" + ln.Addr().String()
+	fmt.Printf("🧪 模拟 speedtest.cn 服务已启动: %s\n", baseURL)
+	fmt.Printf("   场景: %s\n", *script)
+	fmt.Printf("   使用方式: SPEEDTESTUP_ENDPOINTS_BASE_URL=%s ./speedup status\n", baseURL)
+	fmt.Printf("   公网 IP 检测: %s%s\n", baseURL, mockserver.IPPath)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ln) }()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-errCh:
+		fmt.Fprintf(os.Stderr, "❌ 模拟服务异常退出: %v\n", err)
+		return exitFailure
+	case <-sigChan:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+	fmt.Println("✅ 模拟服务已关闭")
+	return exitOK
+}
+
+// printScenarios 输出内置场景
+func printScenarios() {
+	fmt.Println("内置场景:")
+	for _, sc := range mockserver.Presets() {
+		fmt.Printf("  %-16s %s\n", sc.Name, sc.Description)
+	}
+}
@@ -0,0 +1,243 @@
+// Package mockserver 模拟 speedtest.cn 的提速查询和重新开启提速接口
+// 用于离线测试和演示，响应与 api.SpeedupQueryResponse、api.SpeedupReopenResponse 的结构一致
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"speedtestup/api"
+	"speedtestup/clock"
+)
+
+// 模拟的接口
+const (
+	EndpointQuery  = "query"  // 提速查询
+	EndpointReopen = "reopen" // 重新开启提速
+	EndpointIP     = "ip"     // 公网 IP（纯文本）
+)
+
+// 接口路径，与 speedtest.cn 默认地址的路径一致
+// 将 endpoints.base_url 指向模拟服务即可
+const (
+	QueryPath  = "/speedUp/query"
+	ReopenPath = "/api/v2/speedup/reopen"
+	IPPath     = "/ip"
+)
+
+// DefaultIP 默认返回的出口 IP
+const DefaultIP = "203.0.113.10"
+
+// Option 模拟服务选项
+type Option func(*Server)
+
+// WithIP 设置返回的出口 IP
+func WithIP(ip string) Option {
+	return func(s *Server) {
+		s.ip = ip
+	}
+}
+
+// WithRequestLog 每次请求提速接口时回调，参数为接口和使用的场景名称
+func WithRequestLog(fn func(endpoint, scenario string)) Option {
+	return func(s *Server) {
+		s.onRequest = fn
+	}
+}
+
+// WithClock 设置生成截止时间和更新时间使用的时钟，默认使用系统时间
+// 配合 clock.Simulated 可按虚拟时间测试续期等依赖截止时间的流程
+func WithClock(c clock.Clock) Option {
+	return func(s *Server) {
+		s.clock = c
+	}
+}
+
+// Server 模拟的 speedtest.cn 服务，实现 http.Handler
+type Server struct {
+	mu        sync.Mutex
+	steps     []Step
+	served    int // 当前步骤已响应的请求数
+	counts    map[string]int
+	ip        string
+	clock     clock.Clock
+	onRequest func(endpoint, scenario string)
+}
+
+// New 创建按脚本响应的模拟服务，未指定脚本时使用 active 场景
+func New(steps []Step, opts ...Option) *Server {
+	s := &Server{
+		counts: make(map[string]int),
+		ip:     DefaultIP,
+		clock:  clock.System,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.SetScript(steps...)
+	return s
+}
+
+// SetScript 替换场景脚本，并从第一步开始执行
+func (s *Server) SetScript(steps ...Step) {
+	if len(steps) == 0 {
+		active, _ := Preset("active")
+		steps = []Step{{Scenario: active}}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append([]Step(nil), steps...)
+	s.served = 0
+}
+
+// SetScenario 之后的请求一直使用指定场景
+func (s *Server) SetScenario(sc Scenario) {
+	s.SetScript(Step{Scenario: sc})
+}
+
+// SetIP 设置返回的出口 IP（模拟 PPPoE 重拨等 IP 变化）
+func (s *Server) SetIP(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ip = ip
+}
+
+// Requests 指定接口已收到的请求数
+func (s *Server) Requests(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[endpoint]
+}
+
+// next 取得本次请求使用的场景，并推进脚本
+func (s *Server) next(endpoint string) (Scenario, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[endpoint]++
+	step := s.steps[0]
+	s.served++
+	if step.Count > 0 && s.served >= step.Count && len(s.steps) > 1 {
+		s.steps = s.steps[1:]
+		s.served = 0
+	}
+	return step.Scenario, s.ip
+}
+
+// ServeHTTP 按路径分发请求
+// 路径不区分大小写且按后缀匹配，兼容不同版本接口的路径前缀
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.ToLower(strings.TrimRight(r.URL.Path, "/"))
+	switch {
+	case strings.HasSuffix(path, "/speedup/query"):
+		s.serveSpeedup(w, r, EndpointQuery)
+	case strings.HasSuffix(path, "/speedup/reopen"):
+		s.serveSpeedup(w, r, EndpointReopen)
+	case path == IPPath:
+		s.mu.Lock()
+		s.counts[EndpointIP]++
+		ip := s.ip
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, ip)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveSpeedup 按当前场景响应提速接口
+func (s *Server) serveSpeedup(w http.ResponseWriter, r *http.Request, endpoint string) {
+	sc, ip := s.next(endpoint)
+	if s.onRequest != nil {
+		s.onRequest(endpoint, sc.Name)
+	}
+
+	if sc.Delay > 0 {
+		select {
+		case <-time.After(sc.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if sc.StatusCode != 0 && sc.StatusCode != http.StatusOK {
+		w.WriteHeader(sc.StatusCode)
+		fmt.Fprintf(w, `{"code":%d,"message":%q}`, sc.StatusCode, http.StatusText(sc.StatusCode))
+		return
+	}
+	if sc.Malformed {
+		fmt.Fprint(w, `{"code":0,"message":"ok","data":{"ip":`)
+		return
+	}
+
+	var body interface{}
+	if endpoint == EndpointQuery {
+		body = QueryResponse(sc, ip, s.clock.Now())
+	} else {
+		body = ReopenResponse(sc)
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// QueryResponse 按场景生成提速查询响应
+func QueryResponse(sc Scenario, ip string, now time.Time) *api.SpeedupQueryResponse {
+	resp := &api.SpeedupQueryResponse{Message: "ok"}
+	resp.Data.IP = ip
+	resp.Data.UpdatedAt = now.In(shanghai()).Format(timeLayout)
+	resp.Data.CanSpeed = sc.CanSpeed
+	resp.Data.Download = sc.Download
+	resp.Data.TargetUpH = sc.TargetUpH
+
+	expire, expireT := expiry(sc, now)
+	resp.Data.DownExpire, resp.Data.DownExpireT = expire, expireT
+	resp.Data.UpHExpire, resp.Data.UpHExpireT = expire, expireT
+	// 二类上行和套餐均未开通
+	resp.Data.Up100ExpireT = false
+	resp.Data.DownUp50ExpireT = false
+	resp.Data.DownUpExpireT = false
+	return resp
+}
+
+// ReopenResponse 按场景生成重新开启提速响应
+func ReopenResponse(sc Scenario) *api.SpeedupReopenResponse {
+	resp := &api.SpeedupReopenResponse{Code: sc.ReopenCode, Message: sc.Message}
+	if resp.Message == "" {
+		resp.Message = "ok"
+	}
+	if resp.Accepted() {
+		resp.Data.Result = "success"
+	}
+	return resp
+}
+
+// timeLayout speedtest.cn 返回的日期时间格式
+const timeLayout = "2006-01-02 15:04:05"
+
+// expiry 按场景生成截止时间的展示值和时间戳
+func expiry(sc Scenario, now time.Time) (string, interface{}) {
+	if sc.Expiry == 0 || sc.Format == FormatFalse {
+		return "", false
+	}
+
+	at := now.Add(sc.Expiry).Truncate(time.Second)
+	display := at.In(shanghai()).Format(timeLayout)
+	if sc.Format == FormatString {
+		return display, display
+	}
+	return display, at.Unix()
+}
+
+// shanghai speedtest.cn 服务器使用的上海时区，加载失败时使用本地时区
+func shanghai() *time.Location {
+	location, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		return time.Local
+	}
+	return location
+}
@@ -0,0 +1,266 @@
+package mockserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"speedtestup/api"
+	"speedtestup/clock"
+)
+
+// newTestClient 创建指向模拟服务的 speedtest.cn 客户端
+func newTestClient(t *testing.T, s *Server, opts ...api.ClientOption) *api.SpeedTestCNClient {
+	t.Helper()
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	opts = append([]api.ClientOption{api.WithEndpoints(ts.URL+QueryPath, ts.URL+ReopenPath)}, opts...)
+	client, err := api.NewSpeedTestCNClient("", opts...)
+	if err != nil {
+		t.Fatalf("NewSpeedTestCNClient returned error: %v", err)
+	}
+	return client
+}
+
+// mustScript 解析场景脚本
+func mustScript(t *testing.T, spec string) []Step {
+	t.Helper()
+	steps, err := ParseScript(spec)
+	if err != nil {
+		t.Fatalf("ParseScript(%q) returned error: %v", spec, err)
+	}
+	return steps
+}
+
+func TestParseScript(t *testing.T) {
+	steps := mustScript(t, "expired:2, active")
+	if len(steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d", len(steps))
+	}
+	if steps[0].Scenario.Name != "expired" || steps[0].Count != 2 {
+		t.Errorf("Unexpected first step: %+v", steps[0])
+	}
+	if steps[1].Scenario.Name != "active" || steps[1].Count != 0 {
+		t.Errorf("Unexpected second step: %+v", steps[1])
+	}
+
+	for _, spec := range []string{"", "unknown", "active:0", "active:x"} {
+		if _, err := ParseScript(spec); err == nil {
+			t.Errorf("Expected error for script %q", spec)
+		}
+	}
+}
+
+func TestServer_ExpiryFormats(t *testing.T) {
+	tests := []struct {
+		scenario   string
+		wantActive bool
+		wantType   string
+	}{
+		{"active", true, "float64"},
+		{"active-string", true, "string"},
+		{"expired", false, "float64"},
+		{"expired-string", false, "string"},
+		{"inactive", false, "bool"},
+	}
+
+	for _, tt := range tests {
+		client := newTestClient(t, New(mustScript(t, tt.scenario)))
+		resp, err := client.QuerySpeedupStatus(context.Background())
+		if err != nil {
+			t.Fatalf("%s: QuerySpeedupStatus returned error: %v", tt.scenario, err)
+		}
+		if got := typeName(resp.Data.DownExpireT); got != tt.wantType {
+			t.Errorf("%s: Expected downExpireT of type %s, got %s", tt.scenario, tt.wantType, got)
+		}
+		down, err := resp.IsDownloadSpeedupActive()
+		if err != nil {
+			t.Fatalf("%s: IsDownloadSpeedupActive returned error: %v", tt.scenario, err)
+		}
+		up, err := resp.IsUpSpeedupActive()
+		if err != nil {
+			t.Fatalf("%s: IsUpSpeedupActive returned error: %v", tt.scenario, err)
+		}
+		if down != tt.wantActive || up != tt.wantActive {
+			t.Errorf("%s: Expected active=%v, got down=%v up=%v", tt.scenario, tt.wantActive, down, up)
+		}
+		if resp.Data.IP != DefaultIP {
+			t.Errorf("%s: Expected IP %s, got %s", tt.scenario, DefaultIP, resp.Data.IP)
+		}
+	}
+}
+
+// TestServer_Clock 截止时间和更新时间按注入的时钟生成
+func TestServer_Clock(t *testing.T) {
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	sim := clock.NewSimulated(start)
+	client := newTestClient(t, New(mustScript(t, "active"), WithClock(sim)))
+
+	resp, err := client.QuerySpeedupStatus(context.Background())
+	if err != nil {
+		t.Fatalf("QuerySpeedupStatus returned error: %v", err)
+	}
+	if resp.Data.UpdatedAt != "2026-10-14 20:00:00" {
+		t.Errorf("Expected updatedAt from the simulated clock, got %q", resp.Data.UpdatedAt)
+	}
+	_, expireAt, ok := resp.NextExpiry(start)
+	if !ok || !expireAt.Equal(start.Add(48*time.Hour)) {
+		t.Errorf("Expected expiry at %v, got %v (ok=%v)", start.Add(48*time.Hour), expireAt, ok)
+	}
+
+	sim.Advance(49 * time.Hour)
+	if active, _ := resp.IsDownloadSpeedupActiveAt(sim.Now()); active {
+		t.Error("Expected speedup to be expired after advancing past the expiry")
+	}
+}
+
+// typeName JSON 解码后的值类型
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "float64"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	default:
+		return "other"
+	}
+}
+
+func TestServer_ReopenCodes(t *testing.T) {
+	tests := map[string]int{"active": 0, "busy-10002": 10002, "error-10021": 10021}
+	for scenario, code := range tests {
+		client := newTestClient(t, New(mustScript(t, scenario)))
+		resp, err := client.ReopenSpeedup(context.Background())
+		if err != nil {
+			t.Fatalf("%s: ReopenSpeedup returned error: %v", scenario, err)
+		}
+		if resp.Code != code {
+			t.Errorf("%s: Expected code %d, got %d", scenario, code, resp.Code)
+		}
+		if resp.Accepted() != (code != 10021) {
+			t.Errorf("%s: Unexpected Accepted() = %v", scenario, resp.Accepted())
+		}
+	}
+}
+
+func TestServer_Unsupported(t *testing.T) {
+	client := newTestClient(t, New(mustScript(t, "unsupported")))
+	resp, err := client.QuerySpeedupStatus(context.Background())
+	if err != nil {
+		t.Fatalf("QuerySpeedupStatus returned error: %v", err)
+	}
+	if resp.IsSpeedupAvailable() || resp.GetDownloadBandwidth() != 0 {
+		t.Errorf("Expected canSpeed=0 without bandwidth, got canSpeed=%d download=%d", resp.Data.CanSpeed, resp.Data.Download)
+	}
+}
+
+func TestServer_Failures(t *testing.T) {
+	client := newTestClient(t, New(mustScript(t, "malformed")))
+	if _, err := client.QuerySpeedupStatus(context.Background()); err == nil || !strings.Contains(err.Error(), "解析提速查询响应失败") {
+		t.Errorf("Expected parse error for malformed JSON, got %v", err)
+	}
+
+	client = newTestClient(t, New(mustScript(t, "http-500")))
+	if _, err := client.ReopenSpeedup(context.Background()); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Expected status code error, got %v", err)
+	}
+}
+
+func TestServer_Slow(t *testing.T) {
+	slow, _ := Preset("slow")
+	slow.Delay = 200 * time.Millisecond
+	s := New([]Step{{Scenario: slow}})
+
+	client := newTestClient(t, s, api.WithTimeouts(50*time.Millisecond, time.Second))
+	if _, err := client.QuerySpeedupStatus(context.Background()); err == nil {
+		t.Error("Expected timeout for slow query")
+	}
+	start := time.Now()
+	if _, err := client.ReopenSpeedup(context.Background()); err != nil {
+		t.Errorf("Expected slow reopen within timeout to succeed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < slow.Delay {
+		t.Errorf("Expected response after at least %v, took %v", slow.Delay, elapsed)
+	}
+}
+
+func TestServer_SlowCanceled(t *testing.T) {
+	slow, _ := Preset("slow")
+	slow.Delay = time.Second
+	client := newTestClient(t, New([]Step{{Scenario: slow}}), api.WithTimeouts(5*time.Second, 5*time.Second))
+
+	// ctx 取消时不等待超时即返回
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.QuerySpeedupStatus(ctx); err == nil {
+		t.Error("Expected error for canceled query")
+	}
+	if elapsed := time.Since(start); elapsed >= slow.Delay {
+		t.Errorf("Expected canceled query to return before the delay, took %v", elapsed)
+	}
+}
+
+func TestServer_Script(t *testing.T) {
+	var log []string
+	s := New(mustScript(t, "error-10021:1,expired:2,active"), WithRequestLog(func(endpoint, scenario string) {
+		log = append(log, endpoint+"="+scenario)
+	}))
+	client := newTestClient(t, s)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ReopenSpeedup(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.QuerySpeedupStatus(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := "reopen=error-10021 query=expired reopen=expired query=active"
+	if got := strings.Join(log, " "); got != want {
+		t.Errorf("Expected requests %q, got %q", want, got)
+	}
+	if s.Requests(EndpointQuery) != 2 || s.Requests(EndpointReopen) != 2 {
+		t.Errorf("Unexpected request counts: query=%d reopen=%d", s.Requests(EndpointQuery), s.Requests(EndpointReopen))
+	}
+}
+
+func TestServer_IP(t *testing.T) {
+	s := New(nil)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	get := func() string {
+		resp, err := http.Get(ts.URL + IPPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return strings.TrimSpace(string(body))
+	}
+
+	if ip := get(); ip != DefaultIP {
+		t.Errorf("Expected IP %s, got %s", DefaultIP, ip)
+	}
+	s.SetIP("198.51.100.20")
+	if ip := get(); ip != "198.51.100.20" {
+		t.Errorf("Expected IP 198.51.100.20, got %s", ip)
+	}
+
+	resp, err := http.Get(ts.URL + "/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown path, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,159 @@
+package mockserver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExpiryFormat 截止时间戳的格式
+type ExpiryFormat string
+
+// 截止时间戳格式（speedtest.cn 的接口三种格式都会返回）
+const (
+	FormatNumber ExpiryFormat = "number" // Unix 时间戳（秒数）
+	FormatString ExpiryFormat = "string" // 上海时区的 "2006-01-02 15:04:05"
+	FormatFalse  ExpiryFormat = "false"  // false，表示未开通
+)
+
+// DefaultSlowDelay slow 场景默认的响应延迟
+const DefaultSlowDelay = 5 * time.Second
+
+// Scenario 模拟服务的响应场景
+type Scenario struct {
+	Name        string
+	CanSpeed    int           // 0 表示网络不支持提速
+	Download    int           // 下行带宽 (Mbps)
+	TargetUpH   int           // 一类上行带宽 (Kbps)
+	Expiry      time.Duration // 下行和一类上行提速相对当前时间的截止时间，负数表示已过期，0 表示未开通
+	Format      ExpiryFormat  // 截止时间戳的格式
+	ReopenCode  int           // 重新开启提速接口返回的错误码
+	Message     string        // 重新开启提速接口返回的消息
+	Malformed   bool          // 返回无法解析的 JSON
+	StatusCode  int           // HTTP 状态码，0 表示 200
+	Delay       time.Duration // 响应前的延迟
+	Description string
+}
+
+// presets 内置场景
+var presets = map[string]Scenario{
+	"active": {
+		CanSpeed: 1, Download: 500, TargetUpH: 50 * 1024, Expiry: 48 * time.Hour, Format: FormatNumber,
+		Description: "提速已激活，截止时间为数字时间戳",
+	},
+	"active-string": {
+		CanSpeed: 1, Download: 500, TargetUpH: 50 * 1024, Expiry: 48 * time.Hour, Format: FormatString,
+		Description: "提速已激活，截止时间为日期时间字符串",
+	},
+	"expired": {
+		CanSpeed: 1, Download: 500, TargetUpH: 50 * 1024, Expiry: -time.Hour, Format: FormatNumber,
+		Description: "提速已过期",
+	},
+	"expired-string": {
+		CanSpeed: 1, Download: 500, TargetUpH: 50 * 1024, Expiry: -time.Hour, Format: FormatString,
+		Description: "提速已过期，截止时间为日期时间字符串",
+	},
+	"inactive": {
+		CanSpeed: 1, Format: FormatFalse,
+		Description: "支持提速但未开通，截止时间为 false",
+	},
+	"unsupported": {
+		CanSpeed: 0, Format: FormatFalse,
+		Description: "网络不支持提速（canSpeed=0）",
+	},
+	"error-10021": {
+		CanSpeed: 1, Download: 500, TargetUpH: 50 * 1024, Expiry: 48 * time.Hour, Format: FormatNumber,
+		ReopenCode: 10021, Message: "请求接口异常",
+		Description: "重新开启提速返回错误码 10021",
+	},
+	"busy-10002": {
+		CanSpeed: 1, Download: 500, TargetUpH: 50 * 1024, Expiry: 48 * time.Hour, Format: FormatNumber,
+		ReopenCode: 10002, Message: "操作过于频繁",
+		Description: "重新开启提速返回错误码 10002（已受理）",
+	},
+	"malformed": {
+		Malformed:   true,
+		Description: "返回无法解析的 JSON",
+	},
+	"http-500": {
+		StatusCode:  500,
+		Description: "返回 HTTP 500",
+	},
+	"slow": {
+		CanSpeed: 1, Download: 500, TargetUpH: 50 * 1024, Expiry: 48 * time.Hour, Format: FormatNumber,
+		Delay:       DefaultSlowDelay,
+		Description: "提速已激活，但每次响应延迟 5s",
+	},
+}
+
+// Preset 按名称获取内置场景
+func Preset(name string) (Scenario, bool) {
+	sc, ok := presets[name]
+	if ok {
+		sc.Name = name
+	}
+	return sc, ok
+}
+
+// Presets 所有内置场景，按名称排序
+func Presets() []Scenario {
+	names := presetNames()
+	scenarios := make([]Scenario, 0, len(names))
+	for _, name := range names {
+		sc, _ := Preset(name)
+		scenarios = append(scenarios, sc)
+	}
+	return scenarios
+}
+
+// Step 脚本中的一步：用指定场景响应 Count 次请求
+// Count 为 0 表示一直使用该场景；脚本的最后一步在次数用完后也会一直使用
+type Step struct {
+	Scenario Scenario
+	Count    int
+}
+
+// ParseScript 解析场景脚本
+// 格式为逗号分隔的 name[:count]，如 "expired:2,active" 表示前 2 次请求
+// （重新开启提速和提速查询各计一次）使用 expired，之后一直使用 active
+func ParseScript(spec string) ([]Step, error) {
+	var steps []Step
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, count := part, 0
+		if i := strings.IndexByte(part, ':'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("场景 %q 的次数无效，应为正整数", part)
+			}
+			name, count = part[:i], n
+		}
+
+		sc, ok := Preset(name)
+		if !ok {
+			return nil, fmt.Errorf("未知的场景 %q（可选 %s）", name, strings.Join(presetNames(), ", "))
+		}
+		steps = append(steps, Step{Scenario: sc, Count: count})
+	}
+
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("场景脚本为空")
+	}
+	return steps, nil
+}
+
+// presetNames 内置场景名称，按名称排序
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
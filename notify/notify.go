@@ -0,0 +1,192 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"speedtestup/utils"
+)
+
+// EventType 事件类型
+type EventType string
+
+// 事件类型常量
+const (
+	EventSpeedupActivated  EventType = "speedup_activated"  // 提速已激活
+	EventSpeedupLapsed     EventType = "speedup_lapsed"     // 提速已失效
+	EventIPChanged         EventType = "ip_changed"         // IP 发生变化
+	EventRecoveryExhausted EventType = "recovery_exhausted" // 自动恢复达到最大重试次数
+	EventSelfCheck         EventType = "self_check"         // 7 天自检结果
+)
+
+// AllEvents 所有事件类型
+var AllEvents = []EventType{
+	EventSpeedupActivated,
+	EventSpeedupLapsed,
+	EventIPChanged,
+	EventRecoveryExhausted,
+	EventSelfCheck,
+}
+
+// ParseEventType 解析事件类型
+func ParseEventType(s string) (EventType, error) {
+	for _, t := range AllEvents {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("未知的事件类型: %s", s)
+}
+
+// Event 通知事件
+type Event struct {
+	Type    EventType         `json:"type"`
+	Line    string            `json:"line"`
+	Title   string            `json:"title"`
+	Message string            `json:"message"`
+	Time    time.Time         `json:"time"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// NewEvent 创建事件
+func NewEvent(t EventType, line, title, message string) Event {
+	return Event{
+		Type:    t,
+		Line:    line,
+		Title:   title,
+		Message: message,
+		Time:    time.Now(),
+	}
+}
+
+// Subject 通知标题
+func (e Event) Subject() string {
+	return fmt.Sprintf("[SpeedTestUp] %s: %s", e.Line, e.Title)
+}
+
+// Text 通知正文
+func (e Event) Text() string {
+	return fmt.Sprintf("%s\n%s\n时间: %s", e.Subject(), e.Message, e.Time.Format("2006-01-02 15:04:05"))
+}
+
+// Notifier 事件通知接口
+type Notifier interface {
+	Notify(event Event)
+}
+
+// Nop 不发送任何通知
+type Nop struct{}
+
+// Notify 忽略事件
+func (Nop) Notify(Event) {}
+
+// Sink 通知渠道
+type Sink interface {
+	Name() string
+	Send(event Event) error
+}
+
+// route 通知渠道及其事件过滤和频率限制
+type route struct {
+	sink      Sink
+	events    map[EventType]bool // 为空时接收所有事件
+	rateLimit time.Duration      // 同一线路同一事件的最短通知间隔
+	last      map[string]time.Time
+	mu        sync.Mutex
+}
+
+// allow 检查事件是否需要发送到该渠道
+func (r *route) allow(event Event) bool {
+	if len(r.events) > 0 && !r.events[event.Type] {
+		return false
+	}
+	if r.rateLimit <= 0 {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := string(event.Type) + "/" + event.Line
+	if last, ok := r.last[key]; ok && event.Time.Sub(last) < r.rateLimit {
+		return false
+	}
+	r.last[key] = event.Time
+	return true
+}
+
+// Dispatcher 将事件分发到各通知渠道
+// 通知在后台发送，不阻塞提速流程
+type Dispatcher struct {
+	routes []*route
+	logger *utils.Logger
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher 创建事件分发器
+func NewDispatcher(logger *utils.Logger) *Dispatcher {
+	return &Dispatcher{logger: logger}
+}
+
+// Add 添加通知渠道
+// events 为空时接收所有事件，rateLimit 为 0 时不限制频率
+func (d *Dispatcher) Add(sink Sink, events []EventType, rateLimit time.Duration) {
+	r := &route{
+		sink:      sink,
+		events:    make(map[EventType]bool, len(events)),
+		rateLimit: rateLimit,
+		last:      make(map[string]time.Time),
+	}
+	for _, t := range events {
+		r.events[t] = true
+	}
+	d.routes = append(d.routes, r)
+}
+
+// Len 返回通知渠道数量
+func (d *Dispatcher) Len() int {
+	return len(d.routes)
+}
+
+// Notify 将事件发送到所有匹配的通知渠道
+func (d *Dispatcher) Notify(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	for _, r := range d.routes {
+		if !r.allow(event) {
+			d.logger.Debug("通知 %s 已被过滤或限流: %s", r.sink.Name(), event.Type)
+			continue
+		}
+
+		d.wg.Add(1)
+		go func(sink Sink) {
+			defer d.wg.Done()
+			if err := sink.Send(event); err != nil {
+				d.logger.Warn("发送通知 %s 失败: %v", sink.Name(), err)
+			} else {
+				d.logger.Debug("已发送通知 %s: %s", sink.Name(), event.Type)
+			}
+		}(r.sink)
+	}
+}
+
+// Wait 等待所有正在发送的通知完成
+// ctx 先结束时不再等待，返回 ctx.Err()，仍在发送的通知在后台继续
+func (d *Dispatcher) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"speedtestup/utils"
+)
+
+// recordSink 记录收到的事件
+type recordSink struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (s *recordSink) Name() string { return "record" }
+
+func (s *recordSink) Send(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+func newTestDispatcher(t *testing.T) *Dispatcher {
+	t.Helper()
+	logger, err := utils.NewLogger("error", "stdout", "")
+	if err != nil {
+		t.Fatalf("NewLogger returned error: %v", err)
+	}
+	return NewDispatcher(logger)
+}
+
+func TestDispatcher_EventFilter(t *testing.T) {
+	d := newTestDispatcher(t)
+	all := &recordSink{}
+	lapsedOnly := &recordSink{}
+	d.Add(all, nil, 0)
+	d.Add(lapsedOnly, []EventType{EventSpeedupLapsed}, 0)
+
+	d.Notify(NewEvent(EventIPChanged, "default", "IP 发生变化", "1.1.1.1 -> 2.2.2.2"))
+	d.Notify(NewEvent(EventSpeedupLapsed, "default", "提速已失效", ""))
+	if err := d.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait returned error: %v", err)
+	}
+
+	if all.count() != 2 {
+		t.Errorf("Expected 2 events for unfiltered sink, got %d", all.count())
+	}
+	if lapsedOnly.count() != 1 || lapsedOnly.events[0].Type != EventSpeedupLapsed {
+		t.Errorf("Expected only lapsed event, got %+v", lapsedOnly.events)
+	}
+}
+
+func TestDispatcher_RateLimit(t *testing.T) {
+	d := newTestDispatcher(t)
+	sink := &recordSink{}
+	d.Add(sink, nil, time.Hour)
+
+	now := time.Now()
+	event := NewEvent(EventIPChanged, "telecom", "IP 发生变化", "")
+	event.Time = now
+	d.Notify(event)
+
+	// 限流时间内的同类事件被丢弃
+	event.Time = now.Add(time.Minute)
+	d.Notify(event)
+
+	// 不同线路分别计算
+	other := NewEvent(EventIPChanged, "unicom", "IP 发生变化", "")
+	other.Time = now.Add(time.Minute)
+	d.Notify(other)
+
+	// 超过限流时间后再次发送
+	event.Time = now.Add(2 * time.Hour)
+	d.Notify(event)
+	if err := d.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait returned error: %v", err)
+	}
+
+	if sink.count() != 3 {
+		t.Errorf("Expected 3 events after rate limiting, got %d", sink.count())
+	}
+}
+
+func TestParseEventType(t *testing.T) {
+	if got, err := ParseEventType("speedup_lapsed"); err != nil || got != EventSpeedupLapsed {
+		t.Errorf("ParseEventType returned %v, %v", got, err)
+	}
+	if _, err := ParseEventType("unknown"); err == nil {
+		t.Error("Expected error for unknown event type")
+	}
+}
+
+// blockingSink 在 release 关闭前不返回
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (s *blockingSink) Name() string { return "blocking" }
+
+func (s *blockingSink) Send(Event) error {
+	<-s.release
+	return nil
+}
+
+func TestDispatcher_WaitDeadline(t *testing.T) {
+	d := newTestDispatcher(t)
+	sink := &blockingSink{release: make(chan struct{})}
+	d.Add(sink, nil, 0)
+	d.Notify(NewEvent(EventIPChanged, "default", "IP 发生变化", ""))
+
+	// 通知未发送完成时按 ctx 的截止时间返回
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+
+	close(sink.release)
+	if err := d.Wait(context.Background()); err != nil {
+		t.Errorf("Expected Wait to return after sending, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"speedtestup/api"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// 国内推送服务默认地址
+const (
+	DefaultServerChanAPIBase = "https://sctapi.ftqq.com"
+	DefaultPushPlusAPIBase   = "https://www.pushplus.plus"
+)
+
+// ServerChanSink Server 酱通知
+type ServerChanSink struct {
+	client  *resty.Client
+	name    string
+	apiBase string
+	sendKey string
+}
+
+// NewServerChanSink 创建 Server 酱通知渠道，HTTP 客户端由 factory 创建
+func NewServerChanSink(factory *api.HTTPClientFactory, name, apiBase, sendKey string) *ServerChanSink {
+	if apiBase == "" {
+		apiBase = DefaultServerChanAPIBase
+	}
+	return &ServerChanSink{
+		client:  newHTTPClient(factory),
+		name:    name,
+		apiBase: strings.TrimRight(apiBase, "/"),
+		sendKey: sendKey,
+	}
+}
+
+// Name 返回渠道名称
+func (s *ServerChanSink) Name() string {
+	return s.name
+}
+
+// Send 发送通知
+func (s *ServerChanSink) Send(event Event) error {
+	url := fmt.Sprintf("%s/%s.send", s.apiBase, s.sendKey)
+
+	resp, err := s.client.R().
+		SetFormData(map[string]string{
+			"title": event.Subject(),
+			"desp":  event.Text(),
+		}).
+		Post(url)
+	if err != nil {
+		return fmt.Errorf("请求 Server 酱接口失败: %v", strings.ReplaceAll(err.Error(), s.sendKey, "***"))
+	}
+	if err := checkStatus("Server 酱接口", resp); err != nil {
+		return err
+	}
+
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return fmt.Errorf("解析 Server 酱响应失败: %v", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("Server 酱发送失败，错误码: %d, 消息: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+// PushPlusSink PushPlus 通知
+type PushPlusSink struct {
+	client  *resty.Client
+	name    string
+	apiBase string
+	token   string
+}
+
+// NewPushPlusSink 创建 PushPlus 通知渠道，HTTP 客户端由 factory 创建
+func NewPushPlusSink(factory *api.HTTPClientFactory, name, apiBase, token string) *PushPlusSink {
+	if apiBase == "" {
+		apiBase = DefaultPushPlusAPIBase
+	}
+	return &PushPlusSink{
+		client:  newHTTPClient(factory),
+		name:    name,
+		apiBase: strings.TrimRight(apiBase, "/"),
+		token:   token,
+	}
+}
+
+// Name 返回渠道名称
+func (s *PushPlusSink) Name() string {
+	return s.name
+}
+
+// Send 发送通知
+func (s *PushPlusSink) Send(event Event) error {
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(map[string]string{
+			"token":    s.token,
+			"title":    event.Subject(),
+			"content":  event.Text(),
+			"template": "txt",
+		}).
+		Post(s.apiBase + "/send")
+	if err != nil {
+		return fmt.Errorf("请求 PushPlus 接口失败: %v", err)
+	}
+	if err := checkStatus("PushPlus 接口", resp); err != nil {
+		return err
+	}
+
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return fmt.Errorf("解析 PushPlus 响应失败: %v", err)
+	}
+	if result.Code != 200 {
+		return fmt.Errorf("PushPlus 发送失败，错误码: %d, 消息: %s", result.Code, result.Msg)
+	}
+	return nil
+}
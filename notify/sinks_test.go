@@ -0,0 +1,275 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"speedtestup/api"
+)
+
+// testEvent 测试使用的事件
+func testEvent() Event {
+	return NewEvent(EventRecoveryExhausted, "telecom", "自动恢复失败", "已达到最大重试次数")
+}
+
+// captureServer 启动记录请求的本地服务
+func captureServer(t *testing.T, response string) (*httptest.Server, *http.Request, *[]byte) {
+	t.Helper()
+	var captured http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = *r
+		body, _ = io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &captured, &body
+}
+
+func TestWebhookSink_JSON(t *testing.T) {
+	srv, req, body := captureServer(t, "ok")
+
+	sink, err := NewWebhookSink(api.DefaultHTTPClientFactory(), "hook", srv.URL+"/hook", "", map[string]string{"X-Token": "abc"}, "")
+	if err != nil {
+		t.Fatalf("NewWebhookSink returned error: %v", err)
+	}
+	if err := sink.Send(testEvent()); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if req.Method != http.MethodPost || req.URL.Path != "/hook" || req.Header.Get("X-Token") != "abc" {
+		t.Errorf("Unexpected request: %s %s %v", req.Method, req.URL.Path, req.Header)
+	}
+	var event Event
+	if err := json.Unmarshal(*body, &event); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if event.Type != EventRecoveryExhausted || event.Line != "telecom" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestWebhookSink_Template(t *testing.T) {
+	srv, req, body := captureServer(t, "ok")
+
+	sink, err := NewWebhookSink(api.DefaultHTTPClientFactory(), "hook", srv.URL, "put", nil, `{"text":"{{.Line}} {{.Title}}"}`)
+	if err != nil {
+		t.Fatalf("NewWebhookSink returned error: %v", err)
+	}
+	if err := sink.Send(testEvent()); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if req.Method != http.MethodPut {
+		t.Errorf("Expected PUT, got %s", req.Method)
+	}
+	if string(*body) != `{"text":"telecom 自动恢复失败"}` {
+		t.Errorf("Unexpected body: %s", *body)
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected content type: %s", req.Header.Get("Content-Type"))
+	}
+
+	if _, err := NewWebhookSink(api.DefaultHTTPClientFactory(), "bad", srv.URL, "", nil, "{{.Line"); err == nil {
+		t.Error("Expected error for invalid template")
+	}
+}
+
+func TestWebhookSink_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	sink, _ := NewWebhookSink(api.DefaultHTTPClientFactory(), "hook", srv.URL, "", nil, "")
+	if err := sink.Send(testEvent()); err == nil {
+		t.Error("Expected error for 500 response")
+	}
+}
+
+// TestWebhookSink_HTTPClientFactory 通知请求使用共享工厂的 User-Agent 和请求头
+func TestWebhookSink_HTTPClientFactory(t *testing.T) {
+	srv, req, _ := captureServer(t, "ok")
+
+	factory, err := api.NewHTTPClientFactory(api.HTTPOptions{
+		UserAgent: "custom-agent/2.0",
+		Headers:   map[string]string{"X-Client": "speedtestup"},
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPClientFactory returned error: %v", err)
+	}
+	sink, err := NewWebhookSink(factory, "hook", srv.URL, "", nil, "")
+	if err != nil {
+		t.Fatalf("NewWebhookSink returned error: %v", err)
+	}
+	if err := sink.Send(testEvent()); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if ua := req.Header.Get("User-Agent"); ua != "custom-agent/2.0" {
+		t.Errorf("Expected User-Agent from factory, got %q", ua)
+	}
+	if h := req.Header.Get("X-Client"); h != "speedtestup" {
+		t.Errorf("Expected X-Client header from factory, got %q", h)
+	}
+}
+
+func TestTelegramSink(t *testing.T) {
+	srv, req, body := captureServer(t, `{"ok":true}`)
+
+	sink := NewTelegramSink(api.DefaultHTTPClientFactory(), "tg", srv.URL, "123:token", "42")
+	if err := sink.Send(testEvent()); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if req.URL.Path != "/bot123:token/sendMessage" {
+		t.Errorf("Unexpected path: %s", req.URL.Path)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(*body, &payload); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if payload["chat_id"] != "42" || !strings.Contains(payload["text"], "自动恢复失败") {
+		t.Errorf("Unexpected payload: %v", payload)
+	}
+
+	failing, _, _ := captureServer(t, `{"ok":false,"description":"chat not found"}`)
+	if err := NewTelegramSink(api.DefaultHTTPClientFactory(), "tg", failing.URL, "123:token", "42").Send(testEvent()); err == nil {
+		t.Error("Expected error when Telegram returns ok=false")
+	}
+}
+
+func TestServerChanSink(t *testing.T) {
+	srv, req, body := captureServer(t, `{"code":0,"message":""}`)
+
+	sink := NewServerChanSink(api.DefaultHTTPClientFactory(), "sc", srv.URL, "SCTKEY")
+	if err := sink.Send(testEvent()); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if req.URL.Path != "/SCTKEY.send" {
+		t.Errorf("Unexpected path: %s", req.URL.Path)
+	}
+	if !strings.Contains(string(*body), "title=") || !strings.Contains(string(*body), "desp=") {
+		t.Errorf("Unexpected form body: %s", *body)
+	}
+
+	failing, _, _ := captureServer(t, `{"code":40001,"message":"bad key"}`)
+	if err := NewServerChanSink(api.DefaultHTTPClientFactory(), "sc", failing.URL, "SCTKEY").Send(testEvent()); err == nil {
+		t.Error("Expected error for non-zero code")
+	}
+}
+
+func TestPushPlusSink(t *testing.T) {
+	srv, req, body := captureServer(t, `{"code":200,"msg":"ok"}`)
+
+	sink := NewPushPlusSink(api.DefaultHTTPClientFactory(), "pp", srv.URL, "pptoken")
+	if err := sink.Send(testEvent()); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if req.URL.Path != "/send" {
+		t.Errorf("Unexpected path: %s", req.URL.Path)
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(*body, &payload); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if payload["token"] != "pptoken" || payload["title"] == "" {
+		t.Errorf("Unexpected payload: %v", payload)
+	}
+
+	failing, _, _ := captureServer(t, `{"code":900,"msg":"limit"}`)
+	if err := NewPushPlusSink(api.DefaultHTTPClientFactory(), "pp", failing.URL, "pptoken").Send(testEvent()); err == nil {
+		t.Error("Expected error for non-200 code")
+	}
+}
+
+// fakeSMTPServer 最小化的 SMTP 服务，返回收到的邮件内容
+func fakeSMTPServer(t *testing.T) (host string, port int, messages <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		write := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		write("220 localhost ESMTP")
+
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				write("250-localhost")
+				write("250 AUTH PLAIN")
+			case strings.HasPrefix(cmd, "AUTH"):
+				write("235 OK")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				write("250 OK")
+			case cmd == "DATA":
+				write("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				ch <- data.String()
+				write("250 OK")
+			case cmd == "QUIT":
+				write("221 Bye")
+				return
+			default:
+				write("250 OK")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return "127.0.0.1", addr.Port, ch
+}
+
+func TestSMTPSink(t *testing.T) {
+	host, port, messages := fakeSMTPServer(t)
+
+	sink := NewSMTPSink("mail", host, port, "user@example.com", "secret", "", []string{"admin@example.com"})
+	if err := sink.Send(testEvent()); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	msg := <-messages
+	for _, want := range []string{"From: user@example.com", "To: admin@example.com", "Subject: =?UTF-8?b?", "Content-Transfer-Encoding: base64"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected message to contain %q, got:\n%s", want, msg)
+		}
+	}
+	if sink.addr != "127.0.0.1:"+strconv.Itoa(port) {
+		t.Errorf("Unexpected address: %s", sink.addr)
+	}
+}
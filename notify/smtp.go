@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPSink 邮件通知
+// 服务器支持 STARTTLS 时自动启用加密
+type SMTPSink struct {
+	name     string
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// NewSMTPSink 创建邮件通知渠道
+func NewSMTPSink(name, host string, port int, username, password, from string, to []string) *SMTPSink {
+	if port == 0 {
+		port = 587
+	}
+	if from == "" {
+		from = username
+	}
+	return &SMTPSink{
+		name:     name,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+		to:       to,
+	}
+}
+
+// Name 返回渠道名称
+func (s *SMTPSink) Name() string {
+	return s.name
+}
+
+// Send 发送通知
+func (s *SMTPSink) Send(event Event) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	if err := smtp.SendMail(s.addr, auth, s.from, s.to, s.message(event)); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	return nil
+}
+
+// message 构造邮件内容
+func (s *SMTPSink) message(event Event) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", event.Subject()))
+	fmt.Fprintf(&buf, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(event.Text()))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes()
+}
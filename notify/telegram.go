@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"speedtestup/api"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// DefaultTelegramAPIBase Telegram Bot API 默认地址
+const DefaultTelegramAPIBase = "https://api.telegram.org"
+
+// TelegramSink Telegram 机器人通知
+type TelegramSink struct {
+	client  *resty.Client
+	name    string
+	apiBase string
+	token   string
+	chatID  string
+}
+
+// NewTelegramSink 创建 Telegram 通知渠道
+// apiBase 为空时使用官方地址，可设置为自建的反向代理；HTTP 客户端由 factory 创建
+func NewTelegramSink(factory *api.HTTPClientFactory, name, apiBase, token, chatID string) *TelegramSink {
+	if apiBase == "" {
+		apiBase = DefaultTelegramAPIBase
+	}
+	return &TelegramSink{
+		client:  newHTTPClient(factory),
+		name:    name,
+		apiBase: strings.TrimRight(apiBase, "/"),
+		token:   token,
+		chatID:  chatID,
+	}
+}
+
+// Name 返回渠道名称
+func (s *TelegramSink) Name() string {
+	return s.name
+}
+
+// Send 发送通知
+func (s *TelegramSink) Send(event Event) error {
+	url := fmt.Sprintf("%s/bot%s/sendMessage", s.apiBase, s.token)
+
+	resp, err := s.client.R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(map[string]string{
+			"chat_id": s.chatID,
+			"text":    event.Text(),
+		}).
+		Post(url)
+	if err != nil {
+		// 错误信息中的 URL 包含 token，不直接输出
+		return fmt.Errorf("请求 Telegram 接口失败: %v", strings.ReplaceAll(err.Error(), s.token, "***"))
+	}
+	if err := checkStatus("Telegram 接口", resp); err != nil {
+		return err
+	}
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return fmt.Errorf("解析 Telegram 响应失败: %v", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("Telegram 发送失败: %s", result.Description)
+	}
+	return nil
+}
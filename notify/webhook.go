@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"speedtestup/api"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// defaultTimeout 通知请求的默认超时时间
+const defaultTimeout = 10 * time.Second
+
+// newHTTPClient 通过共享的 HTTP 客户端工厂创建通知渠道使用的客户端
+// 使用与 speedtest.cn 客户端相同的代理、证书和请求头；通知请求不是幂等的，失败时不重试
+func newHTTPClient(factory *api.HTTPClientFactory) *resty.Client {
+	return factory.SingleAttemptClient(defaultTimeout, factory.Transport(nil))
+}
+
+// checkStatus 检查 HTTP 响应状态码
+func checkStatus(name string, resp *resty.Response) error {
+	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
+		return fmt.Errorf("%s 返回错误，状态码: %d", name, resp.StatusCode())
+	}
+	return nil
+}
+
+// WebhookSink 通用 Webhook 通知
+// 未设置模板时发送事件的 JSON，设置模板时使用 text/template 渲染请求体
+type WebhookSink struct {
+	client   *resty.Client
+	name     string
+	url      string
+	method   string
+	headers  map[string]string
+	template *template.Template
+}
+
+// NewWebhookSink 创建 Webhook 通知渠道，HTTP 客户端由 factory 创建
+func NewWebhookSink(factory *api.HTTPClientFactory, name, url, method string, headers map[string]string, tmpl string) (*WebhookSink, error) {
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	s := &WebhookSink{
+		client:  newHTTPClient(factory),
+		name:    name,
+		url:     url,
+		method:  strings.ToUpper(method),
+		headers: headers,
+	}
+
+	if tmpl != "" {
+		t, err := template.New(name).Parse(tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("解析 Webhook 模板失败: %v", err)
+		}
+		s.template = t
+	}
+	return s, nil
+}
+
+// Name 返回渠道名称
+func (s *WebhookSink) Name() string {
+	return s.name
+}
+
+// Send 发送通知
+func (s *WebhookSink) Send(event Event) error {
+	var body []byte
+	contentType := "application/json"
+
+	if s.template != nil {
+		var buf bytes.Buffer
+		if err := s.template.Execute(&buf, event); err != nil {
+			return fmt.Errorf("渲染 Webhook 模板失败: %v", err)
+		}
+		body = buf.Bytes()
+		if !json.Valid(body) {
+			contentType = "text/plain; charset=utf-8"
+		}
+	} else {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("序列化事件失败: %v", err)
+		}
+		body = data
+	}
+
+	req := s.client.R().
+		SetHeader("Content-Type", contentType).
+		SetHeaders(s.headers).
+		SetBody(body)
+
+	resp, err := req.Execute(s.method, s.url)
+	if err != nil {
+		return fmt.Errorf("请求 Webhook 失败: %v", err)
+	}
+	return checkStatus("Webhook", resp)
+}
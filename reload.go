@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"speedtestup/config"
+	"speedtestup/service"
+	"speedtestup/utils"
+)
+
+// reloader 配置热重载
+// 重新加载配置文件并应用到所有线路，新配置无效时保持原配置运行
+// 重新加载时同样应用启动时的环境变量和命令行参数覆盖
+type reloader struct {
+	path      string
+	overrides []*config.Overrides
+	logger    *utils.Logger
+	manager   *service.Manager
+	current   *config.Config
+	mu        sync.Mutex
+}
+
+// newReloader 创建配置热重载器
+func newReloader(path string, cfg *config.Config, logger *utils.Logger, manager *service.Manager, overrides ...*config.Overrides) *reloader {
+	return &reloader{
+		path:      path,
+		overrides: overrides,
+		logger:    logger,
+		manager:   manager,
+		current:   cfg,
+	}
+}
+
+// reload 重新加载配置文件，reason 为触发原因（如 SIGHUP、文件变化）
+func (r *reloader) reload(reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	logger := r.logger.Event("config_reload").With("reason", reason)
+	logger.Info("🔄 %s，重新加载配置 %s", reason, r.path)
+
+	cfg, err := config.Load(r.path, r.overrides...)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		logger.With("error", err.Error()).Error("❌ 加载配置失败，继续使用原配置: %v", err)
+		return err
+	}
+
+	if err := r.manager.Reload(cfg); err != nil {
+		logger.With("error", err.Error()).Error("❌ 应用配置失败，继续使用原配置: %v", err)
+		return err
+	}
+
+	for _, warning := range cfg.Warnings() {
+		logger.Warn("⚠️  %s", warning)
+	}
+	if sections := restartRequired(r.current, cfg); len(sections) > 0 {
+		logger.Warn("⚠️  以下配置修改需要重启后生效: %s", strings.Join(sections, ", "))
+	}
+
+	r.current = cfg
+	logger.Info("✅ 配置已重新加载")
+	return nil
+}
+
+// config 获取当前生效的配置
+func (r *reloader) config() *config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// restartRequired 返回发生变化但不支持热重载的配置项
+func restartRequired(old, cfg *config.Config) []string {
+	var sections []string
+	if !reflect.DeepEqual(old.Logging, cfg.Logging) {
+		sections = append(sections, "logging")
+	}
+	if !reflect.DeepEqual(old.Server, cfg.Server) {
+		sections = append(sections, "server")
+	}
+	if !reflect.DeepEqual(old.State, cfg.State) {
+		sections = append(sections, "state")
+	}
+	if !reflect.DeepEqual(old.Notify, cfg.Notify) {
+		sections = append(sections, "notify")
+	}
+	if !reflect.DeepEqual(old.Reload, cfg.Reload) {
+		sections = append(sections, "reload")
+	}
+	return sections
+}
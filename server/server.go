@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"speedtestup/config"
+	"speedtestup/metrics"
+	"speedtestup/service"
+	"speedtestup/utils"
+)
+
+// Server HTTP 状态与控制接口
+type Server struct {
+	config  *config.ServerConfig
+	manager *service.Manager
+	logger  *utils.Logger
+	server  *http.Server
+}
+
+// New 创建 HTTP 接口服务，日志输出到 logger
+func New(cfg *config.Config, manager *service.Manager, logger *utils.Logger) (*Server, error) {
+	if err := checkListen(&cfg.Server); err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		config:  &cfg.Server,
+		manager: manager,
+		logger:  logger.WithPrefix("Server"),
+	}
+	s.server = &http.Server{
+		Addr:              cfg.Server.Listen,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s, nil
+}
+
+// checkListen 检查监听地址，监听非本机地址时必须设置 Token
+func checkListen(cfg *config.ServerConfig) error {
+	host, _, err := net.SplitHostPort(cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("HTTP 接口监听地址无效: %v", err)
+	}
+
+	if cfg.Token != "" {
+		return nil
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("HTTP 接口监听非本机地址 %s 时必须设置 token", cfg.Listen)
+}
+
+// Handler 返回 HTTP 处理器
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.HandleFunc("/api/lines/", s.handleLine)
+	if s.config.Metrics {
+		mux.Handle("/metrics", metrics.Default.Handler())
+	}
+	return s.authenticate(mux)
+}
+
+// Start 在后台启动 HTTP 接口
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.config.Listen)
+	if err != nil {
+		return fmt.Errorf("HTTP 接口监听失败: %v", err)
+	}
+
+	s.logger.Info("HTTP 接口已启动: http://%s", listener.Addr())
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("HTTP 接口异常退出: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown 优雅关闭 HTTP 接口
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// authenticate Bearer Token 认证中间件
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.Token != "" {
+			// 只接受 Bearer 方案，不带方案的裸 token 同样拒绝
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="speedtestup"`)
+				writeError(w, http.StatusUnauthorized, "未授权")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleStatus GET /api/status 返回所有线路的状态
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "仅支持 GET")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"lines": s.manager.GetStatus(),
+	})
+}
+
+// handleLine 处理单条线路的请求
+//
+//	GET  /api/lines/{name}/status
+//	POST /api/lines/{name}/execute
+//	POST /api/lines/{name}/query
+//	POST /api/lines/{name}/reset-ip
+func (s *Server) handleLine(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/lines/"), "/"), "/")
+	if len(parts) != 2 {
+		writeError(w, http.StatusNotFound, "未知的接口")
+		return
+	}
+
+	line := s.manager.Line(parts[0])
+	if line == nil {
+		writeError(w, http.StatusNotFound, "未知的线路: "+parts[0])
+		return
+	}
+
+	action := parts[1]
+	if action == "status" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "仅支持 GET")
+			return
+		}
+		writeJSON(w, http.StatusOK, line.Scheduler.GetStatus())
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "仅支持 POST")
+		return
+	}
+
+	switch action {
+	case "execute":
+		// 提速可能包含自动恢复的长时间等待，作为调度器的任务在后台执行，停止服务时会被取消
+		s.logger.Info("收到手动提速请求: 线路 %s", line.Name)
+		if !line.Scheduler.ExecuteInBackground("手动提速") {
+			writeError(w, http.StatusConflict, "线路 "+line.Name+" 未在运行")
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{
+			"line":   line.Name,
+			"status": "accepted",
+		})
+	case "query":
+		resp, err := line.SpeedupService.Query(r.Context())
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case "reset-ip":
+		line.IPService.ResetIP()
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"line":   line.Name,
+			"status": "ok",
+		})
+	default:
+		writeError(w, http.StatusNotFound, "未知的操作: "+action)
+	}
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError 输出 JSON 错误响应
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
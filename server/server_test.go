@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"speedtestup/config"
+	"speedtestup/service"
+	"speedtestup/utils"
+)
+
+// newTestServer 创建使用默认配置的测试服务
+func newTestServer(t *testing.T, token string) *Server {
+	t.Helper()
+
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.Enabled = true
+	cfg.Server.Token = token
+
+	manager, err := service.NewManager(cfg)
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+
+	logger, err := service.NewLogger(cfg, utils.NewWriterSink(io.Discard))
+	if err != nil {
+		t.Fatalf("NewLogger returned error: %v", err)
+	}
+	s, err := New(cfg, manager, logger)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	return s
+}
+
+func TestServer_Status(t *testing.T) {
+	s := newTestServer(t, "")
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+
+	var body struct {
+		Lines []map[string]interface{} `json:"lines"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	if len(body.Lines) != 1 || body.Lines[0]["line"] != config.DefaultLineName {
+		t.Errorf("Unexpected status body: %s", rec.Body.String())
+	}
+	for _, key := range []string{"last_execute", "last_query", "ipv4", "next_runs"} {
+		if _, ok := body.Lines[0][key]; !ok {
+			t.Errorf("Expected status field %s", key)
+		}
+	}
+
+	// 单条线路状态
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/lines/default/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for line status, got %d", rec.Code)
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	s := newTestServer(t, "secret")
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with wrong token, got %d", rec.Code)
+	}
+
+	// 不带 Bearer 方案的裸 token 不被接受
+	req = httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	req.Header.Set("Authorization", "secret")
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with raw token, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 with valid token, got %d", rec.Code)
+	}
+}
+
+func TestServer_LineActions(t *testing.T) {
+	s := newTestServer(t, "")
+
+	// 重置 IP
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/lines/default/reset-ip", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for reset-ip, got %d", rec.Code)
+	}
+
+	// 操作必须使用 POST
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/lines/default/execute", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET execute, got %d", rec.Code)
+	}
+
+	// 调度器未运行时不执行提速
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/lines/default/execute", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for execute on a stopped line, got %d", rec.Code)
+	}
+
+	// 未知线路和操作
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/lines/unknown/execute", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown line, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/lines/default/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown action, got %d", rec.Code)
+	}
+}
+
+func TestCheckListen(t *testing.T) {
+	cases := []struct {
+		listen  string
+		token   string
+		wantErr bool
+	}{
+		{"127.0.0.1:8090", "", false},
+		{"localhost:8090", "", false},
+		{"[::1]:8090", "", false},
+		{"0.0.0.0:8090", "", true},
+		{":8090", "", true},
+		{"0.0.0.0:8090", "secret", false},
+		{"invalid", "", true},
+	}
+
+	for _, c := range cases {
+		err := checkListen(&config.ServerConfig{Listen: c.listen, Token: c.token})
+		if (err != nil) != c.wantErr {
+			t.Errorf("checkListen(%q, token=%q) error = %v, wantErr %v", c.listen, c.token, err, c.wantErr)
+		}
+	}
+}
+
+func TestServer_Metrics(t *testing.T) {
+	s := newTestServer(t, "secret")
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "# TYPE speedtestup_execute_total counter") {
+		t.Errorf("Unexpected metrics body: %s", rec.Body.String())
+	}
+
+	// 关闭指标接口
+	s.config.Metrics = false
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 when metrics disabled, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"speedtestup/clock"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Cron 定时任务调度，*cron.Cron 实现了该接口
+type Cron interface {
+	AddFunc(spec string, cmd func()) (cron.EntryID, error)
+	Remove(id cron.EntryID)
+	Entry(id cron.EntryID) cron.Entry
+	Entries() []cron.Entry
+	Start()
+	Stop() context.Context
+}
+
+// newCron 创建定时任务调度
+// 使用系统时钟时为 robfig/cron，否则按注入的时钟通过 AfterFunc 触发任务
+func newCron(c clock.Clock) Cron {
+	if c == clock.System {
+		return cron.New()
+	}
+	return newClockCron(c)
+}
+
+// clockCron 按注入的时钟运行的定时任务调度
+// 任务在时钟的定时器回调中同步执行，配合 clock.Simulated 可确定性地快进
+type clockCron struct {
+	clock   clock.Clock
+	entries map[cron.EntryID]*clockEntry
+	lastID  cron.EntryID
+	running bool
+	mu      sync.Mutex
+}
+
+// clockEntry clockCron 中的一个任务
+type clockEntry struct {
+	id       cron.EntryID
+	schedule cron.Schedule
+	cmd      func()
+	next     time.Time
+	timer    clock.Timer
+}
+
+// newClockCron 创建按指定时钟运行的定时任务调度
+func newClockCron(c clock.Clock) *clockCron {
+	return &clockCron{clock: c, entries: make(map[cron.EntryID]*clockEntry)}
+}
+
+// AddFunc 添加按 cron 表达式执行的任务
+func (c *clockCron) AddFunc(spec string, cmd func()) (cron.EntryID, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastID++
+	e := &clockEntry{id: c.lastID, schedule: schedule, cmd: cmd}
+	c.entries[e.id] = e
+	if c.running {
+		c.armLocked(e)
+	}
+	return e.id, nil
+}
+
+// Remove 移除任务
+func (c *clockCron) Remove(id cron.EntryID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[id]; ok {
+		if e.timer != nil {
+			e.timer.Stop()
+		}
+		delete(c.entries, id)
+	}
+}
+
+// Entry 获取任务信息，任务不存在时返回零值
+func (c *clockCron) Entry(id cron.EntryID) cron.Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[id]
+	if !ok {
+		return cron.Entry{}
+	}
+	return cron.Entry{ID: e.id, Schedule: e.schedule, Next: e.next}
+}
+
+// Entries 获取所有任务，按下一次执行时间排序
+func (c *clockCron) Entries() []cron.Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := make([]cron.Entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, cron.Entry{ID: e.id, Schedule: e.schedule, Next: e.next})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Next.Equal(entries[j].Next) {
+			return entries[i].Next.Before(entries[j].Next)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// Start 开始调度所有任务
+func (c *clockCron) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		return
+	}
+	c.running = true
+	for _, e := range c.entries {
+		c.armLocked(e)
+	}
+}
+
+// Stop 停止调度，正在执行的任务不受影响
+func (c *clockCron) Stop() context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.running = false
+	for _, e := range c.entries {
+		if e.timer != nil {
+			e.timer.Stop()
+			e.timer = nil
+		}
+		e.next = time.Time{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+// armLocked 按当前时间安排任务的下一次执行（调用方需持有锁）
+func (c *clockCron) armLocked(e *clockEntry) {
+	now := c.clock.Now()
+	e.next = e.schedule.Next(now)
+	e.timer = c.clock.AfterFunc(e.next.Sub(now), func() { c.run(e) })
+}
+
+// run 执行任务并安排下一次执行
+func (c *clockCron) run(e *clockEntry) {
+	c.mu.Lock()
+	if !c.running || c.entries[e.id] != e {
+		c.mu.Unlock()
+		return
+	}
+	c.armLocked(e)
+	c.mu.Unlock()
+
+	e.cmd()
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/netip"
+
+	"speedtestup/api"
+	"speedtestup/clock"
+	"speedtestup/config"
+	"speedtestup/utils"
+)
+
+// SpeedupAPI speedtest.cn 的提速接口
+// *api.SpeedTestCNClient 实现了该接口，测试中可替换为模拟实现
+type SpeedupAPI interface {
+	QuerySpeedupStatus(ctx context.Context) (*api.SpeedupQueryResponse, error)
+	ReopenSpeedup(ctx context.Context) (*api.SpeedupReopenResponse, error)
+	Rebind()
+}
+
+// InterfaceResolver 网络接口地址解析，用于按接口绑定时跟踪接口地址的变化
+type InterfaceResolver interface {
+	InterfaceAddr(name string, family api.IPFamily) (netip.Addr, error)
+}
+
+// systemInterfaces 读取本机网络接口的地址
+type systemInterfaces struct{}
+
+func (systemInterfaces) InterfaceAddr(name string, family api.IPFamily) (netip.Addr, error) {
+	return api.InterfaceAddr(name, family)
+}
+
+// Option 服务组件的依赖注入选项
+// 未指定的依赖使用默认实现（系统时间、本机网络接口、按日志配置输出到控制台的日志器）
+type Option func(*deps)
+
+// deps 服务组件的依赖
+type deps struct {
+	clock      clock.Clock
+	interfaces InterfaceResolver
+	logHandler slog.Handler
+	logSink    *utils.LogSink
+}
+
+// WithClock 使用指定的时钟
+// 定时任务和续期定时器也按该时钟触发，传入 clock.Simulated 可以虚拟时间快进
+func WithClock(c clock.Clock) Option {
+	return func(d *deps) {
+		d.clock = c
+	}
+}
+
+// WithInterfaceResolver 使用指定的网络接口地址解析
+func WithInterfaceResolver(r InterfaceResolver) Option {
+	return func(d *deps) {
+		d.interfaces = r
+	}
+}
+
+// WithLogHandler 将日志输出到指定的 slog.Handler，不再按日志配置创建日志器
+// 处理器会收到所有级别的日志
+func WithLogHandler(h slog.Handler) Option {
+	return func(d *deps) {
+		d.logHandler = h
+	}
+}
+
+// WithLogSink 将日志写入共用的输出目标
+// 输出目标由调用方按日志配置打开一次（见 OpenLogSink），并在退出时关闭
+func WithLogSink(sink *utils.LogSink) Option {
+	return func(d *deps) {
+		d.logSink = sink
+	}
+}
+
+// newDeps 应用选项并为未指定的依赖填充默认实现
+func newDeps(opts []Option) *deps {
+	d := &deps{
+		clock:      clock.System,
+		interfaces: systemInterfaces{},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// logger 为服务组件创建日志器
+func (d *deps) logger(cfg *config.Config, component string) *utils.Logger {
+	if d.logHandler == nil {
+		return newLogger(cfg, d.logSink, component)
+	}
+
+	logger := utils.NewHandlerLogger(d.logHandler)
+	if cfg.Line != "" {
+		logger = logger.WithLine(cfg.Line)
+	}
+	return logger.WithPrefix(component)
+}
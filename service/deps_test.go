@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"speedtestup/api"
+	"speedtestup/clock"
+	"speedtestup/config"
+	"speedtestup/mockserver"
+	"speedtestup/state"
+)
+
+// newFakeClock 从当前时间（取整到秒）开始的虚拟时钟
+func newFakeClock() *clock.Simulated {
+	return clock.NewSimulated(time.Now().Truncate(time.Second))
+}
+
+// apiCall 一次提速接口调用
+type apiCall struct {
+	at   time.Time // 调用时的时钟时间，未设置 clock 时为零值
+	name string
+}
+
+func (c apiCall) String() string {
+	return c.at.Format("Mon 15:04") + " " + c.name
+}
+
+// fakeSpeedupAPI 按顺序返回预设结果的提速接口
+// 钩子在记录调用之后执行（不持有锁），可用于阻塞、取消或按时钟生成结果
+type fakeSpeedupAPI struct {
+	mu      sync.Mutex
+	reopens []error // 每次重新开启提速返回的错误，用完后返回成功
+	reopen  *api.SpeedupReopenResponse
+	query   *api.SpeedupQueryResponse
+	calls   []apiCall
+	rebinds int
+	clock   clock.Clock // 设置后记录每次调用的时间
+
+	// onReopen 重新开启提速时调用，返回非 nil 错误时直接作为请求结果
+	onReopen func(ctx context.Context) error
+	// onQuery 查询时调用，设置后代替 query 作为查询结果
+	onQuery func(ctx context.Context) (*api.SpeedupQueryResponse, error)
+}
+
+// record 记录一次调用
+func (f *fakeSpeedupAPI) record(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	call := apiCall{name: name}
+	if f.clock != nil {
+		call.at = f.clock.Now()
+	}
+	f.calls = append(f.calls, call)
+}
+
+func (f *fakeSpeedupAPI) ReopenSpeedup(ctx context.Context) (*api.SpeedupReopenResponse, error) {
+	f.record("reopen")
+	if f.onReopen != nil {
+		if err := f.onReopen(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.reopens) > 0 {
+		err := f.reopens[0]
+		f.reopens = f.reopens[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	if f.reopen != nil {
+		return f.reopen, nil
+	}
+	return &api.SpeedupReopenResponse{}, nil
+}
+
+func (f *fakeSpeedupAPI) QuerySpeedupStatus(ctx context.Context) (*api.SpeedupQueryResponse, error) {
+	f.record("query")
+	if f.onQuery != nil {
+		return f.onQuery(ctx)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.query, nil
+}
+
+func (f *fakeSpeedupAPI) Rebind() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rebinds++
+}
+
+func (f *fakeSpeedupAPI) Calls() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := make([]string, len(f.calls))
+	for i, c := range f.calls {
+		names[i] = c.name
+	}
+	return strings.Join(names, ",")
+}
+
+// Timeline 带调用时间的调用记录
+func (f *fakeSpeedupAPI) Timeline() []apiCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]apiCall(nil), f.calls...)
+}
+
+// fakeIPProvider 返回可修改的公网 IP
+type fakeIPProvider struct {
+	mu sync.Mutex
+	ip string
+}
+
+func (p *fakeIPProvider) Name() string { return "fake" }
+
+func (p *fakeIPProvider) GetPublicIP(_ context.Context, family api.IPFamily) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if family == api.IPFamilyV6 {
+		return "", errors.New("no IPv6")
+	}
+	return p.ip, nil
+}
+
+func (p *fakeIPProvider) SetIP(ip string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ip = ip
+}
+
+// fakeInterfaces 返回可修改的接口地址
+type fakeInterfaces struct {
+	addr string
+}
+
+func (f *fakeInterfaces) InterfaceAddr(name string, family api.IPFamily) (netip.Addr, error) {
+	if f.addr == "" {
+		return netip.Addr{}, fmt.Errorf("接口 %s 没有可用地址", name)
+	}
+	return netip.ParseAddr(f.addr)
+}
+
+// recordHandler 记录日志的事件名称
+type recordHandler struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "event" {
+			h.mu.Lock()
+			h.events = append(h.events, a.Value.String())
+			h.mu.Unlock()
+		}
+		return true
+	})
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler      { return h }
+
+func (h *recordHandler) Has(event string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, e := range h.events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// queryResponse 按内置场景生成相对 clock 当前时间的查询结果
+func queryResponse(t *testing.T, scenario string, c clock.Clock) *api.SpeedupQueryResponse {
+	t.Helper()
+	sc, ok := mockserver.Preset(scenario)
+	if !ok {
+		t.Fatalf("unknown scenario %s", scenario)
+	}
+	return mockserver.QueryResponse(sc, mockserver.DefaultIP, c.Now())
+}
+
+func TestSpeedupService_ShouldSelfCheck_Clock(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	clock := newFakeClock()
+	fake := &fakeSpeedupAPI{query: queryResponse(t, "active", clock)}
+	svc := NewSpeedupService(fake, cfg, WithClock(clock), WithLogHandler(&recordHandler{}))
+
+	if err := svc.Execute(context.Background(), TriggerManual); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if !svc.GetLastExecuteTime().Equal(clock.Now()) {
+		t.Errorf("Expected last execute %v, got %v", clock.Now(), svc.GetLastExecuteTime())
+	}
+	if svc.ShouldSelfCheck() {
+		t.Error("ShouldSelfCheck should return false right after execute")
+	}
+
+	clock.Advance(cfg.Speedup.SelfCheck.Interval - time.Second)
+	if svc.ShouldSelfCheck() {
+		t.Error("ShouldSelfCheck should return false before the interval")
+	}
+	clock.Advance(time.Second)
+	if !svc.ShouldSelfCheck() {
+		t.Error("ShouldSelfCheck should return true once the interval has passed")
+	}
+}
+
+func TestSpeedupService_Recovery_Fakes(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	clock := newFakeClock()
+	logs := &recordHandler{}
+	fake := &fakeSpeedupAPI{
+		reopens: []error{errors.New("connection refused")},
+		query:   queryResponse(t, "active", clock),
+	}
+	svc := NewSpeedupService(fake, cfg, WithClock(clock), WithLogHandler(logs))
+	store, err := state.Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	svc.AttachState(store)
+
+	start := clock.Now()
+	if err := svc.Execute(context.Background(), TriggerManual); err != nil {
+		t.Fatalf("Expected recovery to succeed, got %v", err)
+	}
+	if got := fake.Calls(); got != "reopen,reopen,query" {
+		t.Errorf("Unexpected API calls: %s", got)
+	}
+	// 重试前按 retry_interval 等待，虚拟时钟只推进时间不实际阻塞
+	if !svc.GetLastExecuteTime().Equal(start.Add(cfg.Speedup.AutoRecovery.RetryInterval)) {
+		t.Errorf("Expected last execute after the retry interval, got %v", svc.GetLastExecuteTime())
+	}
+	for _, event := range []string{"reopen_failed", "recovery_started", "recovery_succeeded"} {
+		if !logs.Has(event) {
+			t.Errorf("Expected %s log event", event)
+		}
+	}
+	// 自动恢复的尝试记录写入状态存储
+	if history := store.Line(svc.line).RecoveryHistory; len(history) != 1 || !history[0].Success || history[0].Attempt != 1 {
+		t.Errorf("Expected one successful recovery attempt in the state store, got %+v", history)
+	}
+}
+
+func TestSpeedupService_Execute_Codes_Fakes(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	clock := newFakeClock()
+	fake := &fakeSpeedupAPI{
+		reopen: &api.SpeedupReopenResponse{Code: 10021},
+		query:  queryResponse(t, "active", clock),
+	}
+	svc := NewSpeedupService(fake, cfg, WithClock(clock), WithLogHandler(&recordHandler{}))
+
+	if err := svc.Execute(context.Background(), TriggerManual); err == nil || !strings.Contains(err.Error(), "10021") {
+		t.Errorf("Expected error code 10021, got %v", err)
+	}
+	if got := fake.Calls(); got != "reopen" {
+		t.Errorf("Expected no query after 10021, got %s", got)
+	}
+
+	fake.query = queryResponse(t, "unsupported", clock)
+	fake.reopen = &api.SpeedupReopenResponse{Code: 10002}
+	if err := svc.Execute(context.Background(), TriggerManual); err == nil || !strings.Contains(err.Error(), "网络不支持提速") {
+		t.Errorf("Expected unsupported error, got %v", err)
+	}
+}
+
+func TestScheduler_HeartbeatIPChange_Fakes(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.StatusCheckInterval = 0
+	clock := newFakeClock()
+	opts := []Option{WithClock(clock), WithLogHandler(&recordHandler{})}
+
+	provider := &fakeIPProvider{ip: "203.0.113.1"}
+	fake := &fakeSpeedupAPI{query: queryResponse(t, "active", clock)}
+	ipService := NewIPService(provider, cfg, opts...)
+	speedupService := NewSpeedupService(fake, cfg, opts...)
+	scheduler := NewScheduler(ipService, speedupService, cfg, opts...)
+
+	scheduler.heartbeatCheck(context.Background())
+	if got := fake.Calls(); got != "" {
+		t.Errorf("Expected first heartbeat to only record the IP, got calls %s", got)
+	}
+
+	provider.SetIP("203.0.113.2")
+	scheduler.heartbeatCheck(context.Background())
+	if got := fake.Calls(); got != "reopen,query" {
+		t.Errorf("Expected speedup after IP change, got calls %s", got)
+	}
+	if ipv4, _ := ipService.GetLastIPs(); ipv4 != "203.0.113.2" {
+		t.Errorf("Expected last IPv4 203.0.113.2, got %s", ipv4)
+	}
+}
+
+func TestScheduler_InterfaceChange_Fakes(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.StatusCheckInterval = 0
+	cfg.Speedup.IPBinding.Enabled = true
+	cfg.Speedup.IPBinding.Interface = "pppoe-wan"
+	clock := newFakeClock()
+	ifaces := &fakeInterfaces{addr: "100.64.0.1"}
+	opts := []Option{WithClock(clock), WithInterfaceResolver(ifaces), WithLogHandler(&recordHandler{})}
+
+	fake := &fakeSpeedupAPI{query: queryResponse(t, "active", clock)}
+	ipService := NewIPService(&fakeIPProvider{ip: "203.0.113.1"}, cfg, opts...)
+	scheduler := NewScheduler(ipService, NewSpeedupService(fake, cfg, opts...), cfg, opts...)
+
+	scheduler.heartbeatCheck(context.Background())
+	ifaces.addr = "100.64.0.2"
+	scheduler.heartbeatCheck(context.Background())
+
+	if fake.rebinds != 1 {
+		t.Errorf("Expected one rebind after interface address change, got %d", fake.rebinds)
+	}
+	if got := fake.Calls(); got != "reopen,query" {
+		t.Errorf("Expected speedup after interface address change, got calls %s", got)
+	}
+}
+
+func TestScheduler_ScheduleRenewal_Clock(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.ExpiryRenewal.Jitter = 0
+	clock := newFakeClock()
+	opts := []Option{WithClock(clock), WithLogHandler(&recordHandler{})}
+	fake := &fakeSpeedupAPI{}
+	scheduler := NewScheduler(NewIPService(&fakeIPProvider{}, cfg, opts...), NewSpeedupService(fake, cfg, opts...), cfg, opts...)
+
+	resp := queryResponse(t, "active", clock)
+	scheduler.mu.Lock()
+	scheduler.scheduleRenewalLocked(resp)
+	renewAt := scheduler.renewalAt
+	scheduler.stopRenewalLocked()
+	scheduler.mu.Unlock()
+
+	// active 场景的截止时间为 48 小时后，按 lead 提前续期
+	want := clock.Now().Add(48*time.Hour - cfg.Speedup.ExpiryRenewal.Lead)
+	if !renewAt.Equal(want) {
+		t.Errorf("Expected renewal at %v, got %v", want, renewAt)
+	}
+}
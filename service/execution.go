@@ -0,0 +1,143 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// TriggerSource 触发提速的来源
+type TriggerSource string
+
+// 触发提速的来源
+const (
+	TriggerStartup   TriggerSource = "startup"    // 启动时的首次提速
+	TriggerIPChange  TriggerSource = "ip_change"  // 心跳检测发现 IP 或绑定接口地址变化
+	TriggerLapsed    TriggerSource = "lapsed"     // 状态检查发现提速失效
+	TriggerSelfCheck TriggerSource = "self_check" // 7 天自检
+	TriggerSchedule  TriggerSource = "schedule"   // reopen_schedule 定时任务
+	TriggerRenewal   TriggerSource = "renewal"    // 按截止时间续期
+	TriggerManual    TriggerSource = "manual"     // 通过 HTTP 接口手动触发
+	TriggerCommand   TriggerSource = "command"    // run-once 等一次性命令
+)
+
+// maxExecutionHistory 保留的最近提速执行记录数
+const maxExecutionHistory = 20
+
+// Trigger 一次触发
+type Trigger struct {
+	Source TriggerSource `json:"source"`
+	Time   time.Time     `json:"time"`
+}
+
+// ExecutionRecord 一次提速执行
+// 第一个触发发起了这次执行，其余为执行期间合并进来的触发
+type ExecutionRecord struct {
+	ID       uint64    `json:"id"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"` // 仍在执行时为零值
+	Triggers []Trigger `json:"triggers"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// ExecutionStatus 提速执行的状态
+type ExecutionStatus struct {
+	Running    *ExecutionRecord  `json:"running"`     // 正在进行的提速，没有时为 null
+	LastReopen time.Time         `json:"last_reopen"` // 最近一次调用重新开启提速接口的时间
+	History    []ExecutionRecord `json:"history"`     // 最近完成的提速，最新的在前
+}
+
+// flight 一次正在进行的提速，done 关闭后 err 为执行结果
+type flight struct {
+	record ExecutionRecord
+	done   chan struct{}
+	err    error
+}
+
+// executionCoordinator 串行化提速执行
+// 同一时间只有一次提速在进行，期间的其他触发合并到这次提速并共享其结果
+type executionCoordinator struct {
+	mu         sync.Mutex
+	current    *flight
+	lastID     uint64
+	lastReopen time.Time
+	history    []ExecutionRecord
+}
+
+// join 登记一次触发
+// 没有正在进行的提速时开始新的提速并返回 true，由调用方执行并调用 finish；
+// 否则合并到正在进行的提速并返回 false，调用方等待 done 关闭
+func (c *executionCoordinator) join(now time.Time, source TriggerSource) (*flight, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	trigger := Trigger{Source: source, Time: now}
+	if c.current != nil {
+		c.current.record.Triggers = append(c.current.record.Triggers, trigger)
+		return c.current, false
+	}
+
+	c.lastID++
+	c.current = &flight{
+		record: ExecutionRecord{ID: c.lastID, Started: now, Triggers: []Trigger{trigger}},
+		done:   make(chan struct{}),
+	}
+	return c.current, true
+}
+
+// finish 记录提速结果并唤醒合并进来的触发
+func (c *executionCoordinator) finish(f *flight, now time.Time, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f.record.Finished = now
+	if err != nil {
+		f.record.Error = err.Error()
+	}
+	f.err = err
+	c.current = nil
+
+	c.history = append([]ExecutionRecord{f.record}, c.history...)
+	if len(c.history) > maxExecutionHistory {
+		c.history = c.history[:maxExecutionHistory]
+	}
+	close(f.done)
+}
+
+// reopenWait 距离上一次调用重新开启提速接口满 interval 还需等待的时长，interval 不大于 0 时不限制
+func (c *executionCoordinator) reopenWait(now time.Time, interval time.Duration) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if interval <= 0 || c.lastReopen.IsZero() {
+		return 0
+	}
+	if wait := c.lastReopen.Add(interval).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// recordReopen 记录一次重新开启提速接口的调用
+func (c *executionCoordinator) recordReopen(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastReopen = now
+}
+
+// Status 获取提速执行的状态
+func (c *executionCoordinator) Status() ExecutionStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := ExecutionStatus{
+		LastReopen: c.lastReopen,
+		History:    make([]ExecutionRecord, len(c.history)),
+	}
+	copy(status.History, c.history)
+	if c.current != nil {
+		running := c.current.record
+		running.Triggers = append([]Trigger(nil), running.Triggers...)
+		status.Running = &running
+	}
+	return status
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"speedtestup/config"
+)
+
+func TestSpeedupService_Execute_Coalesced(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	clock := newFakeClock()
+	logs := &recordHandler{}
+	// 重新开启提速阻塞到 release 关闭，用于构造并发触发
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	fake := &fakeSpeedupAPI{query: queryResponse(t, "active", clock)}
+	fake.onReopen = func(context.Context) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}
+	svc := NewSpeedupService(fake, cfg, WithClock(clock), WithLogHandler(logs))
+
+	sources := []TriggerSource{TriggerStartup, TriggerIPChange, TriggerSchedule}
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs[0] = svc.Execute(context.Background(), sources[0])
+	}()
+	<-started
+
+	// 提速进行期间的触发合并到这次提速
+	for i := 1; i < len(sources); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = svc.Execute(context.Background(), sources[i])
+		}(i)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if running := svc.ExecutionStatus().Running; running != nil && len(running.Triggers) == len(sources) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for triggers to be coalesced")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Execute from %s returned error: %v", sources[i], err)
+		}
+	}
+	if got := fake.Calls(); got != "reopen,query" {
+		t.Errorf("Expected a single reopen and query, got %s", got)
+	}
+	if !logs.Has("execute_coalesced") {
+		t.Error("Expected execute_coalesced log event")
+	}
+
+	status := svc.ExecutionStatus()
+	if status.Running != nil {
+		t.Errorf("Expected no running execution, got %+v", status.Running)
+	}
+	if len(status.History) != 1 || len(status.History[0].Triggers) != len(sources) {
+		t.Fatalf("Expected one execution with %d triggers, got %+v", len(sources), status.History)
+	}
+	if first := status.History[0].Triggers[0].Source; first != TriggerStartup {
+		t.Errorf("Expected the execution to be started by %s, got %s", TriggerStartup, first)
+	}
+}
+
+func TestSpeedupService_MinReopenInterval(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	clock := newFakeClock()
+	logs := &recordHandler{}
+	fake := &fakeSpeedupAPI{query: queryResponse(t, "active", clock)}
+	svc := NewSpeedupService(fake, cfg, WithClock(clock), WithLogHandler(logs))
+
+	start := clock.Now()
+	if err := svc.Execute(context.Background(), TriggerSelfCheck); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if logs.Has("reopen_throttled") {
+		t.Error("Expected the first reopen not to be throttled")
+	}
+
+	// 紧接着的第二次提速等待到距离上一次重新开启满 min_reopen_interval
+	clock.Advance(10 * time.Second)
+	if err := svc.Execute(context.Background(), TriggerSchedule); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	want := start.Add(cfg.Speedup.MinReopenInterval)
+	if !svc.GetLastExecuteTime().Equal(want) {
+		t.Errorf("Expected second execute at %v, got %v", want, svc.GetLastExecuteTime())
+	}
+	if !logs.Has("reopen_throttled") {
+		t.Error("Expected reopen_throttled log event")
+	}
+
+	status := svc.ExecutionStatus()
+	if !status.LastReopen.Equal(want) {
+		t.Errorf("Expected last reopen at %v, got %v", want, status.LastReopen)
+	}
+	if len(status.History) != 2 || status.History[0].Triggers[0].Source != TriggerSchedule || status.History[1].Triggers[0].Source != TriggerSelfCheck {
+		t.Errorf("Unexpected execution history: %+v", status.History)
+	}
+
+	// 等待期间 ctx 取消时不调用接口，也不更新最近一次重新开启的时间
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := svc.Execute(ctx, TriggerManual); err == nil {
+		t.Error("Expected error for canceled execute")
+	}
+	if got := fake.Calls(); got != "reopen,query,reopen,query" {
+		t.Errorf("Unexpected API calls: %s", got)
+	}
+	if last := svc.ExecutionStatus().LastReopen; !last.Equal(want) {
+		t.Errorf("Expected last reopen to stay at %v, got %v", want, last)
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"speedtestup/config"
+	"speedtestup/mockserver"
+)
+
+// newMockLine 创建接口和 IP 检测都指向模拟服务的线路，不访问网络
+func newMockLine(t *testing.T, script string) (*Line, *mockserver.Server, *config.Config) {
+	t.Helper()
+	steps, err := mockserver.ParseScript(script)
+	if err != nil {
+		t.Fatalf("ParseScript returned error: %v", err)
+	}
+	mock := mockserver.New(steps)
+	ts := httptest.NewServer(mock)
+	t.Cleanup(ts.Close)
+
+	cfg := config.NewDefaultConfig()
+	cfg.Logging.Level = "error"
+	cfg.Endpoints.BaseURL = ts.URL
+	cfg.HTTPClient.Retry.MaxRetries = 0
+	cfg.Speedup.StatusCheckInterval = 0
+	cfg.Speedup.MinReopenInterval = 0
+	cfg.Speedup.AutoRecovery.RetryInterval = 10 * time.Millisecond
+	cfg.Speedup.IPDetection.Providers = []config.IPProviderConfig{
+		{Name: "mock", Type: config.IPProviderText, URL: ts.URL + mockserver.IPPath, Family: config.FamilyIPv4, Timeout: time.Second},
+	}
+
+	line, err := NewLine(cfg)
+	if err != nil {
+		t.Fatalf("NewLine returned error: %v", err)
+	}
+	return line, mock, cfg
+}
+
+// lastQueryActive 最近一次查询结果中启用的方向是否均已激活
+func lastQueryActive(t *testing.T, svc *SpeedupService) bool {
+	t.Helper()
+	resp, _ := svc.GetLastQuery()
+	if resp == nil {
+		t.Fatal("Expected a recorded query")
+	}
+	_, _, active, err := svc.CheckActive(resp)
+	if err != nil {
+		t.Fatalf("CheckActive returned error: %v", err)
+	}
+	return active
+}
+
+// TestIntegration_SchedulerIPChange 首次提速后提速已过期，IP 变化后重新提速成功
+func TestIntegration_SchedulerIPChange(t *testing.T) {
+	line, mock, _ := newMockLine(t, "expired:2,active")
+	scheduler := line.Scheduler
+
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer scheduler.Stop(context.Background())
+
+	if lastQueryActive(t, line.SpeedupService) {
+		t.Error("Expected speedup to be inactive after the first run")
+	}
+
+	// 首次心跳只记录 IP
+	scheduler.heartbeatCheck(context.Background())
+	if mock.Requests(mockserver.EndpointReopen) != 1 {
+		t.Errorf("Expected no reopen on first heartbeat, got %d", mock.Requests(mockserver.EndpointReopen))
+	}
+
+	mock.SetIP("198.51.100.20")
+	scheduler.heartbeatCheck(context.Background())
+
+	if mock.Requests(mockserver.EndpointReopen) != 2 || mock.Requests(mockserver.EndpointQuery) != 2 {
+		t.Errorf("Expected 2 reopen and 2 query requests, got %d and %d",
+			mock.Requests(mockserver.EndpointReopen), mock.Requests(mockserver.EndpointQuery))
+	}
+	if !lastQueryActive(t, line.SpeedupService) {
+		t.Error("Expected speedup to be active after IP change")
+	}
+	if ipv4, _ := line.IPService.GetLastIPs(); ipv4 != "198.51.100.20" {
+		t.Errorf("Expected last IPv4 198.51.100.20, got %s", ipv4)
+	}
+}
+
+// TestIntegration_ExpiryRenewal 按截止时间续期时根据查询结果安排续期
+func TestIntegration_ExpiryRenewal(t *testing.T) {
+	line, _, cfg := newMockLine(t, "active-string")
+	cfg.Speedup.ExpiryRenewal.Enabled = true
+	scheduler := NewScheduler(line.IPService, line.SpeedupService, cfg)
+
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer scheduler.Stop(context.Background())
+
+	renewAt, ok := scheduler.NextRuns()[jobRenewal]
+	if !ok {
+		t.Fatal("Expected a renewal to be scheduled")
+	}
+	// active 场景的截止时间为 48 小时后
+	if until := time.Until(renewAt); until < 47*time.Hour || until > 48*time.Hour {
+		t.Errorf("Expected renewal about 48h minus lead from now, got %v", until)
+	}
+}
+
+// TestIntegration_Execute 各场景下执行提速的结果
+func TestIntegration_Execute(t *testing.T) {
+	tests := []struct {
+		script     string
+		wantErr    string
+		wantActive bool
+	}{
+		{script: "active", wantActive: true},
+		{script: "busy-10002", wantActive: true},
+		{script: "expired"},
+		{script: "inactive"},
+		{script: "error-10021", wantErr: "10021"},
+		{script: "unsupported", wantErr: "网络不支持提速"},
+		// 自动恢复：首次请求返回无法解析的 JSON，重试后成功
+		{script: "malformed:1,active", wantActive: true},
+		{script: "http-500:1,active-string", wantActive: true},
+	}
+
+	for _, tt := range tests {
+		line, _, _ := newMockLine(t, tt.script)
+		err := line.SpeedupService.Execute(context.Background(), TriggerManual)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: Expected error containing %q, got %v", tt.script, tt.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Execute returned error: %v", tt.script, err)
+			continue
+		}
+		if active := lastQueryActive(t, line.SpeedupService); active != tt.wantActive {
+			t.Errorf("%s: Expected active=%v, got %v", tt.script, tt.wantActive, active)
+		}
+	}
+}
+
+// TestIntegration_RecoveryExhausted 接口持续返回无法解析的 JSON 时自动恢复在重试次数用尽后停止，并进入冷却期
+func TestIntegration_RecoveryExhausted(t *testing.T) {
+	line, mock, cfg := newMockLine(t, "malformed")
+	cfg.Speedup.AutoRecovery.MaxRetries = 2
+
+	err := line.SpeedupService.Execute(context.Background(), TriggerManual)
+	if err == nil || !strings.Contains(err.Error(), "自动恢复失败") {
+		t.Fatalf("Expected recovery to be exhausted, got %v", err)
+	}
+	if mock.Requests(mockserver.EndpointReopen) != 3 || mock.Requests(mockserver.EndpointQuery) != 0 {
+		t.Errorf("Expected 3 reopen and 0 query requests, got %d and %d",
+			mock.Requests(mockserver.EndpointReopen), mock.Requests(mockserver.EndpointQuery))
+	}
+	if state := line.SpeedupService.RecoveryStatus().State; state != RecoveryCooldown {
+		t.Errorf("Expected recovery state %s, got %s", RecoveryCooldown, state)
+	}
+
+	// 冷却期内的失败只请求一次，不再重试
+	if err := line.SpeedupService.Execute(context.Background(), TriggerManual); err == nil || !strings.Contains(err.Error(), "冷却") {
+		t.Errorf("Expected cooldown error, got %v", err)
+	}
+	if mock.Requests(mockserver.EndpointReopen) != 4 {
+		t.Errorf("Expected a single reopen during cooldown, got %d in total", mock.Requests(mockserver.EndpointReopen))
+	}
+}
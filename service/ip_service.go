@@ -1,62 +1,215 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"net"
+	"net/netip"
+	"sync"
 
 	"speedtestup/api"
 	"speedtestup/config"
+	"speedtestup/metrics"
+	"speedtestup/state"
 	"speedtestup/utils"
 )
 
 // IPService IP 服务
 type IPService struct {
-	apiClient *api.IPAPI
+	apiClient api.IPProvider
 	config    *config.IPBindingConfig
+	line      string
 	logger    *utils.Logger
-	lastIP    string
+	store     *state.Store
+	ifaces    InterfaceResolver
+	lastIPv4  string
+	lastIPv6  string
+
+	lastInterfaceIP string
+	mu              sync.Mutex
 }
 
-// NewIPService 创建新的 IP 服务实例
-func NewIPService(ipAPI *api.IPAPI, cfg *config.Config) *IPService {
-	logger, err := utils.NewLogger(cfg.Logging.Level, cfg.Logging.Output, cfg.Logging.File)
-	if err != nil {
-		// 至少在标准错误输出中打印一条日志
-		fmt.Printf("Failed to initialize logger for IPService: %v\n", err)
-		// 使用默认日志器或panic，根据业务逻辑决定
-		// 这里选择panic，因为日志器初始化失败是严重问题
-		panic(fmt.Sprintf("failed to initialize logger: %v", err))
+// NewIPProvider 根据配置创建按顺序回退的 IP 提供者链
+// text/json 类型的提供者使用 httpFactory 创建 HTTP 客户端
+func NewIPProvider(cfg *config.IPDetectionConfig, httpFactory *api.HTTPClientFactory) (api.IPProvider, error) {
+	opt := api.WithProviderHTTPClientFactory(httpFactory)
+	providers := make([]api.IPProvider, 0, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		family, err := api.ParseIPFamily(p.Family)
+		if err != nil {
+			return nil, fmt.Errorf("IP 提供者 %s: %v", p.Name, err)
+		}
+
+		switch p.Type {
+		case config.IPProviderText, "":
+			if p.URL == "" {
+				return nil, fmt.Errorf("IP 提供者 %s 未配置 url", p.Name)
+			}
+			providers = append(providers, api.NewTextIPProvider(p.Name, p.URL, family, p.Timeout, opt))
+		case config.IPProviderJSON:
+			if p.URL == "" {
+				return nil, fmt.Errorf("IP 提供者 %s 未配置 url", p.Name)
+			}
+			providers = append(providers, api.NewJSONIPProvider(p.Name, p.URL, p.Field, family, p.Timeout, opt))
+		case config.IPProviderDNS:
+			if p.Resolver == "" || p.Host == "" {
+				return nil, fmt.Errorf("IP 提供者 %s 未配置 resolver 或 host", p.Name)
+			}
+			providers = append(providers, api.NewDNSIPProvider(p.Name, p.Resolver, p.Host, family, p.Timeout))
+		default:
+			return nil, fmt.Errorf("未知的 IP 提供者类型: %s", p.Type)
+		}
 	}
-	logger = logger.WithPrefix("IPService")
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("未配置任何 IP 提供者")
+	}
+
+	return api.NewIPProviderChain(cfg.Quorum, providers...), nil
+}
+
+// NewIPService 创建新的 IP 服务实例
+// opts 可注入网络接口地址解析和日志处理器
+func NewIPService(ipAPI api.IPProvider, cfg *config.Config, opts ...Option) *IPService {
+	d := newDeps(opts)
+	logger := d.logger(cfg, "IPService")
 
 	return &IPService{
 		apiClient: ipAPI,
 		config:    &cfg.Speedup.IPBinding,
+		line:      lineLabel(cfg),
 		logger:    logger,
-		lastIP:    "",
+		ifaces:    d.interfaces,
+	}
+}
+
+// AttachState 关联状态存储，恢复上次记录的 IP，之后 IP 记录的变化会写入存储
+func (s *IPService) AttachState(store *state.Store) {
+	saved := store.Line(s.line)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.store = store
+	s.lastIPv4 = saved.LastIPv4
+	s.lastIPv6 = saved.LastIPv6
+	s.lastInterfaceIP = saved.LastInterfaceIP
+	if saved.LastIPv4 != "" || saved.LastIPv6 != "" {
+		s.logger.Info("已恢复上次记录的 IP: IPv4=%s, IPv6=%s", displayIP(saved.LastIPv4), displayIP(saved.LastIPv6))
 	}
 }
 
+// Reload 应用新的线路配置
+// provider 不为 nil 时替换 IP 提供者（IP 检测配置变化时）
+func (s *IPService) Reload(cfg *config.Config, provider api.IPProvider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if provider != nil {
+		s.apiClient = provider
+	}
+	s.config = &cfg.Speedup.IPBinding
+}
+
+// settings 获取当前的 IP 提供者和 IP 绑定配置
+func (s *IPService) settings() (api.IPProvider, *config.IPBindingConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiClient, s.config
+}
+
+// saveStateLocked 将 IP 记录写入状态存储（调用方需持有锁）
+func (s *IPService) saveStateLocked() {
+	if s.store == nil {
+		return
+	}
+
+	ipv4, ipv6, ifaceIP := s.lastIPv4, s.lastIPv6, s.lastInterfaceIP
+	err := s.store.Update(s.line, func(ls *state.LineState) {
+		ls.LastIPv4 = ipv4
+		ls.LastIPv6 = ipv6
+		ls.LastInterfaceIP = ifaceIP
+	})
+	if err != nil {
+		s.logger.Warn("保存 IP 记录失败: %v", err)
+	}
+}
+
+// family 返回配置的地址族，配置无效时按任意地址族处理
+func (s *IPService) family() api.IPFamily {
+	_, binding := s.settings()
+	family, err := api.ParseIPFamily(binding.Family)
+	if err != nil {
+		s.logger.Warn("地址族配置无效，按 any 处理: %v", err)
+		return api.IPFamilyAny
+	}
+	return family
+}
+
 // GetCurrentIP 获取当前公网 IP
-func (s *IPService) GetCurrentIP() (string, error) {
-	ip, err := s.apiClient.GetPublicIP()
+// 地址族为 any 时优先返回 IPv4，获取失败再尝试 IPv6
+func (s *IPService) GetCurrentIP(ctx context.Context) (string, error) {
+	ipv4, ipv6, err := s.GetCurrentIPs(ctx)
 	if err != nil {
-		s.logger.Error("获取当前公网 IP 失败: %v", err)
 		return "", err
 	}
-	s.logger.Info("获取当前公网 IP: %s", ip)
-	return ip, nil
+	if ipv4 != "" {
+		return ipv4, nil
+	}
+	return ipv6, nil
+}
+
+// GetCurrentIPs 分别获取当前的公网 IPv4 和 IPv6 地址
+// 地址族为 any 时任一地址族获取成功即可
+func (s *IPService) GetCurrentIPs(ctx context.Context) (ipv4, ipv6 string, err error) {
+	family := s.family()
+	provider, _ := s.settings()
+
+	var errs []error
+	if family != api.IPFamilyV6 {
+		ipv4, err = provider.GetPublicIP(ctx, api.IPFamilyV4)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("IPv4: %v", err))
+		}
+	}
+	if family != api.IPFamilyV4 {
+		ipv6, err = provider.GetPublicIP(ctx, api.IPFamilyV6)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("IPv6: %v", err))
+		}
+	}
+
+	if ipv4 == "" && ipv6 == "" {
+		err = fmt.Errorf("获取当前公网 IP 失败: %v", errs)
+		s.logger.Error("%v", err)
+		return "", "", err
+	}
+	for _, e := range errs {
+		// 双栈模式下单个地址族失败不影响整体结果
+		s.logger.Debug("获取公网 IP 部分失败: %v", e)
+	}
+
+	s.logger.Info("获取当前公网 IP: IPv4=%s IPv6=%s", displayIP(ipv4), displayIP(ipv6))
+	return ipv4, ipv6, nil
+}
+
+// displayIP 用于日志输出的 IP 字符串
+func displayIP(ip string) string {
+	if ip == "" {
+		return "-"
+	}
+	return ip
 }
 
 // ValidateBinding 验证 IP 绑定
 func (s *IPService) ValidateBinding(ip string) error {
-	if !s.config.Enabled {
+	_, binding := s.settings()
+	if !binding.Enabled {
 		s.logger.Debug("IP 绑定未启用，跳过验证")
 		return nil
 	}
 
-	if s.config.BindIP != "" && ip != s.config.BindIP {
-		err := fmt.Errorf("IP 绑定验证失败: 当前 IP %s != 绑定 IP %s", ip, s.config.BindIP)
+	if binding.BindIP != "" && !sameIP(ip, binding.BindIP) {
+		err := fmt.Errorf("IP 绑定验证失败: 当前 IP %s != 绑定 IP %s", ip, binding.BindIP)
 		s.logger.Error("%v", err)
 		return err
 	}
@@ -65,28 +218,66 @@ func (s *IPService) ValidateBinding(ip string) error {
 	return nil
 }
 
+// sameIP 比较两个 IP 是否相同（忽略 IPv6 的不同书写形式）
+func sameIP(a, b string) bool {
+	addrA, errA := netip.ParseAddr(a)
+	addrB, errB := netip.ParseAddr(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return addrA.Unmap() == addrB.Unmap()
+}
+
 // CheckIPChange 检查 IP 是否发生变化
-func (s *IPService) CheckIPChange() (bool, error) {
-	currentIP, err := s.GetCurrentIP()
+// IPv4 与 IPv6 地址分别跟踪，任一地址族变化都视为 IP 变化
+func (s *IPService) CheckIPChange(ctx context.Context) (bool, error) {
+	ipv4, ipv6, err := s.GetCurrentIPs(ctx)
 	if err != nil {
 		return false, err
 	}
 
+	changedV4 := s.trackIP("IPv4", "ipv4", &s.lastIPv4, ipv4)
+	changedV6 := s.trackIP("IPv6", "ipv6", &s.lastIPv6, ipv6)
+	return changedV4 || changedV6, nil
+}
+
+// trackIP 更新单个地址族的 IP 记录，返回是否发生变化
+// family 用作 IP 变化计数指标的标签
+func (s *IPService) trackIP(label, family string, last *string, current string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 本次未获取到该地址族的 IP，保留原记录
+	if current == "" {
+		return false
+	}
+
 	// 首次获取 IP
-	if s.lastIP == "" {
-		s.lastIP = currentIP
-		s.logger.Info("初始化 IP: %s", currentIP)
-		return false, nil
+	if *last == "" {
+		*last = current
+		s.logger.Event("ip_initialized").With("family", family, "ip", current).Info("初始化 %s: %s", label, current)
+		s.saveStateLocked()
+		return false
 	}
 
 	// 检查 IP 是否变化
-	if currentIP != s.lastIP {
-		s.logger.Info("检测到 IP 变化: %s -> %s", s.lastIP, currentIP)
-		s.lastIP = currentIP
-		return true, nil
+	if !sameIP(current, *last) {
+		s.logger.Event("ip_changed").With("family", family, "old_ip", *last, "ip", current).
+			Info("检测到 %s 变化: %s -> %s", label, *last, current)
+		*last = current
+		metrics.IPChangesTotal.Inc(s.line, family)
+		s.saveStateLocked()
+		return true
 	}
 
-	return false, nil
+	return false
+}
+
+// GetLastIPs 获取最近一次记录的 IPv4 和 IPv6 地址
+func (s *IPService) GetLastIPs() (ipv4, ipv6 string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastIPv4, s.lastIPv6
 }
 
 // GetInterfaceIP 获取指定网络接口的 IP 地址
@@ -94,51 +285,45 @@ func (s *IPService) CheckIPChange() (bool, error) {
 func (s *IPService) GetInterfaceIP(interfaceName string) (string, error) {
 	s.logger.Debug("尝试获取接口 %s 的 IP 地址", interfaceName)
 
-	// 获取所有网络接口
-	interfaces, err := net.Interfaces()
+	addr, err := s.ifaces.InterfaceAddr(interfaceName, s.family())
 	if err != nil {
-		return "", fmt.Errorf("获取网络接口列表失败: %v", err)
+		return "", err
 	}
 
-	// 遍历网络接口
-	for _, iface := range interfaces {
-		// 如果指定了接口名称，匹配接口名称
-		if interfaceName != "" && iface.Name != interfaceName {
-			continue
-		}
-
-		// 获取接口的地址
-		addrs, err := iface.Addrs()
-		if err != nil {
-			s.logger.Debug("获取接口 %s 地址失败: %v", iface.Name, err)
-			continue
-		}
-
-		// 遍历接口的地址
-		for _, addr := range addrs {
-			ip, ok := addr.(*net.IPNet)
-			if !ok {
-				continue
-			}
-
-			// 过滤回环地址和 IPv6
-			if ip.IP.IsLoopback() || ip.IP.To4() == nil {
-				continue
-			}
+	s.logger.Debug("找到接口 %s 的 IP 地址: %s", interfaceName, addr)
+	return addr.String(), nil
+}
 
-			s.logger.Debug("找到接口 %s 的 IP 地址: %s", iface.Name, ip.IP.String())
-			return ip.IP.String(), nil
-		}
+// CheckInterfaceIPChange 检查绑定接口的地址是否发生变化
+// 仅在按接口绑定（启用 IP 绑定且未设置 bind_ip）时有效
+func (s *IPService) CheckInterfaceIPChange() (bool, error) {
+	if !s.UsesInterfaceBinding() {
+		return false, nil
 	}
 
-	if interfaceName != "" {
-		return "", fmt.Errorf("未找到接口 %s 的有效网络 IP", interfaceName)
+	_, binding := s.settings()
+	current, err := s.GetInterfaceIP(binding.Interface)
+	if err != nil {
+		return false, err
 	}
-	return "", fmt.Errorf("未找到任何有效的网络接口 IP")
+
+	return s.trackIP("接口 "+binding.Interface+" 地址", "interface", &s.lastInterfaceIP, current), nil
+}
+
+// UsesInterfaceBinding 是否按网络接口绑定提速请求
+func (s *IPService) UsesInterfaceBinding() bool {
+	_, binding := s.settings()
+	return binding.Enabled && binding.BindIP == "" && binding.Interface != ""
 }
 
 // ResetIP 重置 IP 记录（用于测试或特殊情况）
 func (s *IPService) ResetIP() {
-	s.lastIP = ""
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastIPv4 = ""
+	s.lastIPv6 = ""
+	s.lastInterfaceIP = ""
+	s.saveStateLocked()
 	s.logger.Info("IP 记录已重置")
 }
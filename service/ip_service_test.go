@@ -1,11 +1,15 @@
 package service
 
 import (
+	"context"
+	"fmt"
 	"net"
+	"path/filepath"
 	"testing"
 
 	"speedtestup/api"
 	"speedtestup/config"
+	"speedtestup/state"
 )
 
 func TestNewIPService(t *testing.T) {
@@ -31,16 +35,14 @@ func TestIPService_ResetIP(t *testing.T) {
 	ipAPI := api.NewIPAPI()
 	ipService := NewIPService(ipAPI, cfg)
 
-	// 设置一个测试 IP
-	ipService.lastIP = "192.168.1.1"
-	if ipService.lastIP == "" {
-		t.Error("Expected lastIP to be set")
-	}
+	// 设置测试 IP
+	ipService.lastIPv4 = "192.168.1.1"
+	ipService.lastIPv6 = "2001:db8::1"
 
 	// 重置 IP
 	ipService.ResetIP()
-	if ipService.lastIP != "" {
-		t.Error("Expected lastIP to be empty after ResetIP")
+	if ipService.lastIPv4 != "" || ipService.lastIPv6 != "" {
+		t.Error("Expected last IPs to be empty after ResetIP")
 	}
 }
 
@@ -121,3 +123,172 @@ func TestIPService_GetInterfaceIP(t *testing.T) {
 		}
 	}
 }
+
+// TestNewIPProvider 测试根据配置创建 IP 提供者链
+func TestNewIPProvider(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	provider, err := NewIPProvider(&cfg.Speedup.IPDetection, api.DefaultHTTPClientFactory())
+	if err != nil {
+		t.Fatalf("NewIPProvider returned error for default config: %v", err)
+	}
+	if provider == nil {
+		t.Fatal("NewIPProvider should not return nil")
+	}
+
+	// 未知类型
+	_, err = NewIPProvider(&config.IPDetectionConfig{
+		Providers: []config.IPProviderConfig{{Name: "bad", Type: "ftp"}},
+	}, api.DefaultHTTPClientFactory())
+	if err == nil {
+		t.Error("Expected error for unknown provider type")
+	}
+
+	// 缺少 URL
+	_, err = NewIPProvider(&config.IPDetectionConfig{
+		Providers: []config.IPProviderConfig{{Name: "text", Type: config.IPProviderText}},
+	}, api.DefaultHTTPClientFactory())
+	if err == nil {
+		t.Error("Expected error for provider without url")
+	}
+}
+
+// familyIPProvider 按地址族返回固定 IP 的提供者
+type familyIPProvider struct {
+	ipv4 string
+	ipv6 string
+}
+
+func (p *familyIPProvider) Name() string { return "family" }
+
+func (p *familyIPProvider) GetPublicIP(_ context.Context, family api.IPFamily) (string, error) {
+	ip := p.ipv4
+	if family == api.IPFamilyV6 {
+		ip = p.ipv6
+	}
+	if ip == "" {
+		return "", fmt.Errorf("no %s address", family)
+	}
+	return ip, nil
+}
+
+// TestIPService_CheckIPChange_DualStack 测试分别跟踪 IPv4 和 IPv6 地址
+func TestIPService_CheckIPChange_DualStack(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	provider := &familyIPProvider{ipv4: "203.0.113.1", ipv6: "2001:db8::1"}
+	ipService := NewIPService(provider, cfg)
+
+	changed, err := ipService.CheckIPChange(context.Background())
+	if err != nil || changed {
+		t.Fatalf("Expected initial check without change, got changed=%v err=%v", changed, err)
+	}
+	ipv4, ipv6 := ipService.GetLastIPs()
+	if ipv4 != "203.0.113.1" || ipv6 != "2001:db8::1" {
+		t.Errorf("Unexpected tracked IPs: %s %s", ipv4, ipv6)
+	}
+
+	// 仅 IPv6 变化（不同书写形式的相同地址不算变化）
+	provider.ipv6 = "2001:0db8:0000::1"
+	if changed, _ := ipService.CheckIPChange(context.Background()); changed {
+		t.Error("Equivalent IPv6 notation should not count as change")
+	}
+	provider.ipv6 = "2001:db8::2"
+	if changed, _ := ipService.CheckIPChange(context.Background()); !changed {
+		t.Error("Expected IPv6 change to be detected")
+	}
+
+	// IPv6 暂时不可用时保留原记录
+	provider.ipv6 = ""
+	if changed, err := ipService.CheckIPChange(context.Background()); err != nil || changed {
+		t.Errorf("Expected no change when IPv6 is unavailable, got changed=%v err=%v", changed, err)
+	}
+	if _, ipv6 := ipService.GetLastIPs(); ipv6 != "2001:db8::2" {
+		t.Errorf("Expected IPv6 record to be kept, got %s", ipv6)
+	}
+}
+
+// TestIPService_GetCurrentIP_Family 测试强制地址族
+func TestIPService_GetCurrentIP_Family(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.IPBinding.Family = config.FamilyIPv6
+	ipService := NewIPService(&familyIPProvider{ipv4: "203.0.113.1", ipv6: "2001:db8::1"}, cfg)
+
+	ip, err := ipService.GetCurrentIP(context.Background())
+	if err != nil {
+		t.Fatalf("GetCurrentIP returned error: %v", err)
+	}
+	if ip != "2001:db8::1" {
+		t.Errorf("Expected IPv6 address, got %s", ip)
+	}
+
+	// 只有 IPv4 时强制 IPv6 应失败
+	ipService = NewIPService(&familyIPProvider{ipv4: "203.0.113.1"}, cfg)
+	if _, err := ipService.GetCurrentIP(context.Background()); err == nil {
+		t.Error("Expected error when forced family is unavailable")
+	}
+}
+
+// TestIPService_CheckInterfaceIPChange 测试接口地址变化检测的启用条件
+func TestIPService_CheckInterfaceIPChange(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	ipService := NewIPService(api.NewIPAPI(), cfg)
+
+	// 未启用 IP 绑定
+	if ipService.UsesInterfaceBinding() {
+		t.Error("Interface binding should be disabled by default")
+	}
+	changed, err := ipService.CheckInterfaceIPChange()
+	if changed || err != nil {
+		t.Errorf("Expected no-op when binding disabled, got changed=%v err=%v", changed, err)
+	}
+
+	// 设置了 bind_ip 时使用固定地址
+	cfg.Speedup.IPBinding.Enabled = true
+	cfg.Speedup.IPBinding.BindIP = "192.168.1.100"
+	if ipService.UsesInterfaceBinding() {
+		t.Error("Interface binding should not be used when bind_ip is set")
+	}
+
+	// 按接口绑定，接口不存在时返回错误
+	cfg.Speedup.IPBinding.BindIP = ""
+	cfg.Speedup.IPBinding.Interface = "nonexistent_interface_12345"
+	if !ipService.UsesInterfaceBinding() {
+		t.Error("Interface binding should be used when bind_ip is empty")
+	}
+	if _, err := ipService.CheckInterfaceIPChange(); err == nil {
+		t.Error("Expected error for nonexistent interface")
+	}
+}
+
+// TestIPService_AttachState 测试重启后恢复 IP 记录
+func TestIPService_AttachState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := state.Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	cfg := config.NewDefaultConfig()
+	provider := &familyIPProvider{ipv4: "203.0.113.1"}
+	ipService := NewIPService(provider, cfg)
+	ipService.AttachState(store)
+	if _, err := ipService.CheckIPChange(context.Background()); err != nil {
+		t.Fatalf("CheckIPChange returned error: %v", err)
+	}
+
+	// 模拟重启：重新打开状态文件
+	store, err = state.Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	provider.ipv4 = "203.0.113.2"
+	ipService = NewIPService(provider, cfg)
+	ipService.AttachState(store)
+
+	if ipv4, _ := ipService.GetLastIPs(); ipv4 != "203.0.113.1" {
+		t.Fatalf("Expected restored IPv4 203.0.113.1, got %s", ipv4)
+	}
+	// 重启后首次检测即可发现变化
+	if changed, err := ipService.CheckIPChange(context.Background()); err != nil || !changed {
+		t.Errorf("Expected IP change after restart, got changed=%v err=%v", changed, err)
+	}
+}
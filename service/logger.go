@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"os"
+
+	"speedtestup/config"
+	"speedtestup/utils"
+)
+
+// OpenLogSink 根据日志配置打开日志输出目标
+// 进程只打开一次，通过 WithLogSink 传给各服务组件，退出时关闭
+func OpenLogSink(cfg *config.Config) (*utils.LogSink, error) {
+	return utils.OpenLogSink(cfg.Logging.Output, cfg.Logging.File, utils.RotateOptions{
+		MaxSize:    int64(cfg.Logging.MaxSize) * 1024 * 1024,
+		MaxAge:     cfg.Logging.MaxAge,
+		MaxBackups: cfg.Logging.MaxBackups,
+		Compress:   cfg.Logging.Compress,
+	})
+}
+
+// NewLogger 根据日志配置创建输出到 sink 的日志器
+func NewLogger(cfg *config.Config, sink *utils.LogSink) (*utils.Logger, error) {
+	return utils.NewLoggerWithOptions(utils.LoggerOptions{
+		Level:           cfg.Logging.Level,
+		Format:          cfg.Logging.Format,
+		ComponentLevels: cfg.Logging.Components,
+		Sink:            sink,
+	})
+}
+
+// newLogger 为服务组件创建日志器
+// 多线路模式下日志带有线路名称，便于区分不同线路的输出
+// 未注入输出目标时只输出到控制台，日志文件由进程统一打开
+func newLogger(cfg *config.Config, sink *utils.LogSink, component string) *utils.Logger {
+	if sink == nil {
+		sink = utils.NewWriterSink(os.Stdout)
+		if cfg.Logging.Output == "stderr" {
+			sink = utils.NewWriterSink(os.Stderr)
+		}
+	}
+
+	logger, err := NewLogger(cfg, sink)
+	if err != nil {
+		// 无法初始化 logger 是一个严重问题，至少需要 panic 或返回错误
+		fmt.Printf("Failed to initialize logger for %s: %v\n", component, err)
+		panic(fmt.Sprintf("failed to initialize logger: %v", err))
+	}
+
+	if cfg.Line != "" {
+		logger = logger.WithLine(cfg.Line)
+	}
+	return logger.WithPrefix(component)
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"speedtestup/api"
+	"speedtestup/config"
+	"speedtestup/notify"
+	"speedtestup/state"
+	"speedtestup/utils"
+)
+
+// Line 单条线路的运行实例
+// 每条线路拥有独立的客户端、状态和调度任务
+type Line struct {
+	Name           string
+	Config         *config.Config
+	IPService      *IPService
+	SpeedupService *SpeedupService
+	Scheduler      *Scheduler
+}
+
+// NewLine 根据线路配置创建线路实例
+// opts 注入到线路的各个服务组件
+func NewLine(cfg *config.Config, opts ...Option) (*Line, error) {
+	httpFactory, err := NewHTTPClientFactory(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ipAPI, err := NewIPProvider(&cfg.Speedup.IPDetection, httpFactory)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 IP 提供者失败: %v", err)
+	}
+
+	speedupAPI, err := NewSpeedTestCNClient(cfg, httpFactory)
+	if err != nil {
+		return nil, err
+	}
+
+	ipService := NewIPService(ipAPI, cfg, opts...)
+	speedupService := NewSpeedupService(speedupAPI, cfg, opts...)
+	scheduler := NewScheduler(ipService, speedupService, cfg, opts...)
+
+	return &Line{
+		Name:           cfg.Line,
+		Config:         cfg,
+		IPService:      ipService,
+		SpeedupService: speedupService,
+		Scheduler:      scheduler,
+	}, nil
+}
+
+// AttachState 为线路关联状态存储并恢复上次的运行状态
+func (l *Line) AttachState(store *state.Store) {
+	l.IPService.AttachState(store)
+	l.SpeedupService.AttachState(store)
+}
+
+// NewHTTPClientFactory 根据 HTTP 客户端配置创建线路共享的 HTTP 客户端工厂
+// 因临时性失败重试的请求会记录警告日志
+func NewHTTPClientFactory(cfg *config.Config, opts ...Option) (*api.HTTPClientFactory, error) {
+	httpCfg := &cfg.HTTPClient
+	logger := newDeps(opts).logger(cfg, "HTTPClient")
+	factory, err := api.NewHTTPClientFactory(api.HTTPOptions{
+		UserAgent:    httpCfg.UserAgent,
+		Headers:      httpCfg.Headers,
+		Proxy:        httpCfg.Proxy,
+		CAFile:       httpCfg.CAFile,
+		MaxRetries:   httpCfg.Retry.MaxRetries,
+		RetryWait:    httpCfg.Retry.Wait,
+		RetryMaxWait: httpCfg.Retry.MaxWait,
+		OnRetry: func(url string, attempt int, reason string) {
+			logger.Event("http_retry").With("url", url, "attempt", attempt, "reason", reason).
+				Warn("请求 %s 第 %d 次尝试失败: %s", url, attempt, reason)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("HTTP 客户端配置无效: %v", err)
+	}
+	return factory, nil
+}
+
+// NewSpeedTestCNClient 根据 IP 绑定、HTTP 客户端和接口地址配置创建 speedtest.cn 客户端
+func NewSpeedTestCNClient(cfg *config.Config, httpFactory *api.HTTPClientFactory) (*api.SpeedTestCNClient, error) {
+	binding := &cfg.Speedup.IPBinding
+	family, err := api.ParseIPFamily(binding.Family)
+	if err != nil {
+		return nil, fmt.Errorf("IP 绑定配置无效: %v", err)
+	}
+
+	queryURL, reopenURL, err := cfg.Endpoints.URLs()
+	if err != nil {
+		return nil, fmt.Errorf("speedtest.cn 接口地址无效: %v", err)
+	}
+
+	opts := []api.ClientOption{
+		api.WithIPFamily(family),
+		api.WithHTTPClientFactory(httpFactory),
+		api.WithTimeouts(cfg.HTTPClient.Timeouts.Query, cfg.HTTPClient.Timeouts.Reopen),
+		api.WithEndpoints(queryURL, reopenURL),
+	}
+	if binding.Enabled && binding.BindIP == "" && binding.Interface != "" {
+		// 未指定 bind_ip 时按接口绑定，每次连接时解析接口当前地址
+		opts = append(opts, api.WithBindInterface(binding.Interface))
+	}
+
+	return api.NewSpeedTestCNClient(binding.BindIP, opts...)
+}
+
+// Manager 多线路管理器
+// 同一进程内管理多条线路，单条线路失败不影响其他线路
+type Manager struct {
+	lines    []*Line
+	logger   *utils.Logger
+	store    *state.Store
+	notifier notify.Notifier
+	opts     []Option // 创建线路（含重载时新增的线路）使用的依赖注入选项
+	started  bool
+	mu       sync.RWMutex
+}
+
+// NewManager 为所有启用的线路创建运行实例
+// 单条线路初始化失败时记录错误并跳过，没有任何可用线路时返回错误
+func NewManager(cfg *config.Config, opts ...Option) (*Manager, error) {
+	m := &Manager{
+		logger: newDeps(opts).logger(cfg, "Manager"),
+		opts:   opts,
+	}
+
+	for _, lineCfg := range cfg.EffectiveLines() {
+		if !lineCfg.Enabled {
+			m.logger.Info("线路 %s 未启用，跳过", lineCfg.Name)
+			continue
+		}
+
+		line, err := NewLine(cfg.ForLine(lineCfg), m.opts...)
+		if err != nil {
+			m.logger.Error("初始化线路 %s 失败: %v", lineCfg.Name, err)
+			continue
+		}
+		m.lines = append(m.lines, line)
+	}
+
+	if len(m.lines) == 0 {
+		return nil, fmt.Errorf("没有可用的提速线路")
+	}
+
+	return m, nil
+}
+
+// AttachState 为所有线路关联状态存储并恢复上次的运行状态
+func (m *Manager) AttachState(store *state.Store) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.store = store
+	for _, line := range m.lines {
+		line.AttachState(store)
+	}
+}
+
+// SetNotifier 为所有线路设置事件通知
+func (m *Manager) SetNotifier(n notify.Notifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.notifier = n
+	for _, line := range m.lines {
+		line.SpeedupService.SetNotifier(n)
+		line.Scheduler.SetNotifier(n)
+	}
+}
+
+// Lines 返回所有线路
+func (m *Manager) Lines() []*Line {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*Line(nil), m.lines...)
+}
+
+// Line 按名称查找线路
+func (m *Manager) Line(name string) *Line {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lineLocked(name)
+}
+
+// lineLocked 按名称查找线路（调用方需持有锁）
+func (m *Manager) lineLocked(name string) *Line {
+	for _, line := range m.lines {
+		if line.Name == name {
+			return line
+		}
+	}
+	return nil
+}
+
+// Start 并行启动所有线路的调度器
+// ctx 约束各线路启动时的首次提速
+func (m *Manager) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	started := 0
+
+	m.mu.Lock()
+	m.started = true
+	lines := append([]*Line(nil), m.lines...)
+	m.mu.Unlock()
+
+	for _, line := range lines {
+		wg.Add(1)
+		go func(line *Line) {
+			defer wg.Done()
+			if err := line.Scheduler.Start(ctx); err != nil {
+				m.logger.Error("线路 %s 启动失败: %v", line.Name, err)
+				return
+			}
+			mu.Lock()
+			started++
+			mu.Unlock()
+		}(line)
+	}
+	wg.Wait()
+
+	if started == 0 {
+		return fmt.Errorf("所有线路均启动失败")
+	}
+	return nil
+}
+
+// Stop 并行停止所有线路的调度器
+// 各线路共用 ctx 的截止时间等待正在运行的任务结束
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	m.started = false
+	lines := append([]*Line(nil), m.lines...)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	for _, line := range lines {
+		wg.Add(1)
+		go func(line *Line) {
+			defer wg.Done()
+			if err := line.Scheduler.Stop(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("线路 %s: %v", line.Name, err))
+				mu.Unlock()
+			}
+		}(line)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return fmt.Errorf("停止线路失败: %v", errs)
+	}
+	return nil
+}
+
+// GetStatus 获取所有线路的状态
+func (m *Manager) GetStatus() []map[string]interface{} {
+	lines := m.Lines()
+	status := make([]map[string]interface{}, 0, len(lines))
+	for _, line := range lines {
+		status = append(status, line.Scheduler.GetStatus())
+	}
+	return status
+}
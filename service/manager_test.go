@@ -0,0 +1,134 @@
+package service
+
+import (
+	"testing"
+
+	"speedtestup/config"
+)
+
+func TestNewManager(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.Enabled = true
+
+	line1 := config.LineConfig{Name: "telecom", SpeedupConfig: cfg.Speedup}
+	line2 := config.LineConfig{Name: "unicom", SpeedupConfig: cfg.Speedup}
+	line2.Enabled = false
+	line3 := config.LineConfig{Name: "broken", SpeedupConfig: cfg.Speedup}
+	line3.IPBinding.Family = "ipx"
+	cfg.Lines = []config.LineConfig{line1, line2, line3}
+
+	manager, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+
+	// 未启用的线路和配置错误的线路被跳过
+	if len(manager.Lines()) != 1 {
+		t.Fatalf("Expected 1 line, got %d", len(manager.Lines()))
+	}
+
+	line := manager.Line("telecom")
+	if line == nil {
+		t.Fatal("Expected to find line telecom")
+	}
+	if line.Scheduler.line != "telecom" {
+		t.Errorf("Expected scheduler to be tagged with line name, got %q", line.Scheduler.line)
+	}
+	if manager.Line("unicom") != nil {
+		t.Error("Disabled line should not be created")
+	}
+
+	status := manager.GetStatus()
+	if len(status) != 1 || status[0]["line"] != "telecom" {
+		t.Errorf("Unexpected status: %v", status)
+	}
+}
+
+func TestNewManager_NoLines(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.Enabled = false
+
+	if _, err := NewManager(cfg); err == nil {
+		t.Error("Expected error when no line is enabled")
+	}
+}
+
+func TestNewManager_DefaultLine(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.Enabled = true
+
+	manager, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	if manager.Line(config.DefaultLineName) == nil {
+		t.Error("Expected default line when no lines are configured")
+	}
+}
+
+func TestManager_Reload(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.Enabled = true
+	cfg.Lines = []config.LineConfig{
+		{Name: "telecom", SpeedupConfig: cfg.Speedup},
+		{Name: "unicom", SpeedupConfig: cfg.Speedup},
+	}
+
+	manager, err := NewManager(cfg)
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	telecom := manager.Line("telecom")
+	client := telecom.SpeedupService.client()
+
+	// 无效的新配置被拒绝，原配置保持不变
+	invalid := config.NewDefaultConfig()
+	invalid.Speedup.Enabled = true
+	broken := config.LineConfig{Name: "telecom", SpeedupConfig: invalid.Speedup}
+	broken.ReopenSchedule = "not a cron"
+	invalid.Lines = []config.LineConfig{broken}
+	if err := manager.Reload(invalid); err == nil {
+		t.Fatal("Expected invalid config to be rejected")
+	}
+	if len(manager.Lines()) != 2 || manager.Line("telecom") != telecom {
+		t.Fatal("Lines should be unchanged after a rejected reload")
+	}
+
+	// 修改绑定配置、移除 unicom、新增 mobile
+	next := config.NewDefaultConfig()
+	next.Speedup.Enabled = true
+	changed := config.LineConfig{Name: "telecom", SpeedupConfig: next.Speedup}
+	changed.DownAcc = false
+	changed.IPBinding.Family = config.FamilyIPv4
+	next.Lines = []config.LineConfig{changed, {Name: "mobile", SpeedupConfig: next.Speedup}}
+	if err := manager.Reload(next); err != nil {
+		t.Fatalf("Reload returned error: %v", err)
+	}
+
+	if manager.Line("telecom") != telecom {
+		t.Error("Existing line should be kept across reloads")
+	}
+	if telecom.SpeedupService.client() == client {
+		t.Error("Expected speedup client to be rebuilt when binding changes")
+	}
+	if downAcc, _ := telecom.SpeedupService.directions(); downAcc {
+		t.Error("Expected down_acc change to be applied")
+	}
+	if manager.Line("unicom") != nil {
+		t.Error("Removed line should be dropped")
+	}
+	if manager.Line("mobile") == nil {
+		t.Error("New line should be created")
+	}
+
+	// 只修改 HTTP 客户端配置时同样重建提速客户端
+	client = telecom.SpeedupService.client()
+	proxied := *next
+	proxied.HTTPClient.Proxy = "socks5://127.0.0.1:1080"
+	if err := manager.Reload(&proxied); err != nil {
+		t.Fatalf("Reload returned error: %v", err)
+	}
+	if telecom.SpeedupService.client() == client {
+		t.Error("Expected speedup client to be rebuilt when http_client changes")
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"time"
+
+	"speedtestup/api"
+	"speedtestup/config"
+	"speedtestup/metrics"
+)
+
+// lineLabel 获取指标使用的线路名称
+func lineLabel(cfg *config.Config) string {
+	if cfg.Line == "" {
+		return config.DefaultLineName
+	}
+	return cfg.Line
+}
+
+// observeDuration 记录接口请求耗时
+func observeDuration(line, endpoint string, start time.Time) {
+	metrics.RequestDuration.Observe(time.Since(start).Seconds(), line, endpoint)
+}
+
+// recordQueryMetrics 根据查询结果更新带宽和截止时间指标
+func recordQueryMetrics(line string, resp *api.SpeedupQueryResponse) {
+	metrics.DownloadBandwidth.Set(float64(resp.Data.Download), line)
+	metrics.UploadBandwidth.Set(float64(resp.Data.TargetUpH), line, "h")
+	metrics.UploadBandwidth.Set(float64(resp.Data.TargetUp100), line, "100")
+
+	expiries := resp.Expiries()
+	for _, kind := range []string{api.ExpiryDown, api.ExpiryUpH, api.ExpiryUp100, api.ExpiryDownUp50, api.ExpiryDownUp} {
+		if expireTime, ok := expiries[kind]; ok {
+			metrics.ExpirySeconds.SetToTimeUntil(expireTime, line, kind)
+		} else {
+			metrics.ExpirySeconds.Delete(line, kind)
+		}
+	}
+}
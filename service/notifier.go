@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+
+	"speedtestup/api"
+	"speedtestup/config"
+	"speedtestup/notify"
+)
+
+// NewNotifier 根据配置创建事件通知分发器
+func NewNotifier(cfg *config.Config, opts ...Option) (*notify.Dispatcher, error) {
+	dispatcher := notify.NewDispatcher(newDeps(opts).logger(cfg, "Notifier"))
+
+	// 通知渠道与各线路使用相同的 http_client 配置（代理、CA 证书、User-Agent 和请求头）
+	httpFactory, err := NewHTTPClientFactory(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sc := range cfg.Notify.Sinks {
+		sink, err := newNotifySink(&sc, httpFactory)
+		if err != nil {
+			return nil, fmt.Errorf("通知渠道 %s: %v", sc.Name, err)
+		}
+
+		events := make([]notify.EventType, 0, len(sc.Events))
+		for _, e := range sc.Events {
+			t, err := notify.ParseEventType(e)
+			if err != nil {
+				return nil, fmt.Errorf("通知渠道 %s: %v", sc.Name, err)
+			}
+			events = append(events, t)
+		}
+
+		dispatcher.Add(sink, events, sc.RateLimit)
+	}
+
+	return dispatcher, nil
+}
+
+// newNotifySink 创建单个通知渠道，HTTP 类型的渠道通过 httpFactory 创建客户端
+func newNotifySink(sc *config.NotifySinkConfig, httpFactory *api.HTTPClientFactory) (notify.Sink, error) {
+	switch sc.Type {
+	case config.NotifyWebhook:
+		if sc.URL == "" {
+			return nil, fmt.Errorf("未配置 url")
+		}
+		return notify.NewWebhookSink(httpFactory, sc.Name, sc.URL, sc.Method, sc.Headers, sc.Template)
+	case config.NotifyTelegram:
+		if sc.Token == "" || sc.ChatID == "" {
+			return nil, fmt.Errorf("未配置 token 或 chat_id")
+		}
+		return notify.NewTelegramSink(httpFactory, sc.Name, sc.APIBase, sc.Token, sc.ChatID), nil
+	case config.NotifyServerChan:
+		if sc.SendKey == "" {
+			return nil, fmt.Errorf("未配置 send_key")
+		}
+		return notify.NewServerChanSink(httpFactory, sc.Name, sc.APIBase, sc.SendKey), nil
+	case config.NotifyPushPlus:
+		if sc.Token == "" {
+			return nil, fmt.Errorf("未配置 token")
+		}
+		return notify.NewPushPlusSink(httpFactory, sc.Name, sc.APIBase, sc.Token), nil
+	case config.NotifySMTP:
+		if sc.Host == "" || len(sc.To) == 0 {
+			return nil, fmt.Errorf("未配置 host 或 to")
+		}
+		return notify.NewSMTPSink(sc.Name, sc.Host, sc.Port, sc.Username, sc.Password, sc.From, sc.To), nil
+	default:
+		return nil, fmt.Errorf("未知的通知渠道类型: %s", sc.Type)
+	}
+}
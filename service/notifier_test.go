@@ -0,0 +1,43 @@
+package service
+
+import (
+	"testing"
+
+	"speedtestup/config"
+)
+
+func TestNewNotifier(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Notify.Sinks = []config.NotifySinkConfig{
+		{Name: "hook", Type: config.NotifyWebhook, URL: "http://127.0.0.1/hook", Events: []string{"speedup_lapsed"}},
+		{Name: "tg", Type: config.NotifyTelegram, Token: "123:token", ChatID: "42"},
+		{Name: "sc", Type: config.NotifyServerChan, SendKey: "SCTKEY"},
+		{Name: "pp", Type: config.NotifyPushPlus, Token: "token"},
+		{Name: "mail", Type: config.NotifySMTP, Host: "smtp.example.com", To: []string{"admin@example.com"}},
+	}
+
+	dispatcher, err := NewNotifier(cfg)
+	if err != nil {
+		t.Fatalf("NewNotifier returned error: %v", err)
+	}
+	if dispatcher.Len() != 5 {
+		t.Errorf("Expected 5 sinks, got %d", dispatcher.Len())
+	}
+}
+
+func TestNewNotifier_Invalid(t *testing.T) {
+	cases := []config.NotifySinkConfig{
+		{Name: "unknown", Type: "pager"},
+		{Name: "hook", Type: config.NotifyWebhook},
+		{Name: "tg", Type: config.NotifyTelegram, Token: "123:token"},
+		{Name: "event", Type: config.NotifyServerChan, SendKey: "SCTKEY", Events: []string{"unknown"}},
+	}
+
+	for _, sc := range cases {
+		cfg := config.NewDefaultConfig()
+		cfg.Notify.Sinks = []config.NotifySinkConfig{sc}
+		if _, err := NewNotifier(cfg); err == nil {
+			t.Errorf("Expected error for sink %s", sc.Name)
+		}
+	}
+}
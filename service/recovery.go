@@ -0,0 +1,159 @@
+package service
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"speedtestup/config"
+)
+
+// RecoveryState 自动恢复状态
+type RecoveryState string
+
+// 自动恢复状态常量
+// idle → backing_off ⇄ attempting → idle（重试成功）或 exhausted → cooldown → idle
+const (
+	RecoveryIdle       RecoveryState = "idle"        // 未在恢复
+	RecoveryBackingOff RecoveryState = "backing_off" // 等待下一次重试
+	RecoveryAttempting RecoveryState = "attempting"  // 正在重试
+	RecoveryExhausted  RecoveryState = "exhausted"   // 重试次数已用尽（未设置冷却时间时保持到下一次提速成功）
+	RecoveryCooldown   RecoveryState = "cooldown"    // 冷却中，失败不再触发自动恢复
+)
+
+// RecoveryStatus 自动恢复的当前状态
+type RecoveryStatus struct {
+	State         RecoveryState `json:"state"`
+	Operation     string        `json:"operation,omitempty"`   // 触发恢复的操作
+	Attempt       int           `json:"attempt,omitempty"`     // 当前（或最后一次）重试的序号
+	MaxRetries    int           `json:"max_retries,omitempty"` // 最大重试次数
+	NextAttempt   time.Time     `json:"next_attempt"`          // 退避等待结束的时间
+	CooldownUntil time.Time     `json:"cooldown_until"`        // 冷却结束的时间
+	LastError     string        `json:"last_error,omitempty"`  // 最近一次失败的原因
+}
+
+// recoveryMachine 自动恢复状态机
+// 同一时间只允许一个恢复流程，冷却期在查询状态或开始恢复时按当前时间自动结束
+type recoveryMachine struct {
+	mu     sync.Mutex
+	status RecoveryStatus
+}
+
+// newRecoveryMachine 创建处于 idle 状态的状态机
+func newRecoveryMachine() *recoveryMachine {
+	return &recoveryMachine{status: RecoveryStatus{State: RecoveryIdle}}
+}
+
+// refreshLocked 冷却期结束后回到 idle（调用方需持有锁）
+func (m *recoveryMachine) refreshLocked(now time.Time) {
+	if m.status.State == RecoveryCooldown && !now.Before(m.status.CooldownUntil) {
+		m.status = RecoveryStatus{State: RecoveryIdle}
+	}
+}
+
+// Status 获取当前状态
+func (m *recoveryMachine) Status(now time.Time) RecoveryStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshLocked(now)
+	return m.status
+}
+
+// start 开始一次恢复流程
+// 已有恢复流程在进行或处于冷却期时返回 false 和当前状态
+func (m *recoveryMachine) start(now time.Time, operation string, maxRetries int, cause error) (RecoveryStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshLocked(now)
+
+	switch m.status.State {
+	case RecoveryBackingOff, RecoveryAttempting, RecoveryCooldown:
+		return m.status, false
+	}
+	m.status = RecoveryStatus{
+		State:      RecoveryIdle,
+		Operation:  operation,
+		MaxRetries: maxRetries,
+		LastError:  cause.Error(),
+	}
+	return m.status, true
+}
+
+// backOff 进入退避等待，next 为第 attempt 次重试的时间
+func (m *recoveryMachine) backOff(attempt int, next time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status.State = RecoveryBackingOff
+	m.status.Attempt = attempt
+	m.status.NextAttempt = next
+}
+
+// attempt 退避结束，开始重试
+func (m *recoveryMachine) attempt() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status.State = RecoveryAttempting
+	m.status.NextAttempt = time.Time{}
+}
+
+// fail 记录一次重试失败
+func (m *recoveryMachine) fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status.LastError = err.Error()
+}
+
+// exhaust 重试次数用尽，cooldown 大于 0 时进入冷却期
+func (m *recoveryMachine) exhaust(now time.Time, cooldown time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status.State = RecoveryExhausted
+	m.status.NextAttempt = time.Time{}
+	if cooldown > 0 {
+		m.status.State = RecoveryCooldown
+		m.status.CooldownUntil = now.Add(cooldown)
+	}
+}
+
+// reset 恢复成功或被取消，回到 idle
+func (m *recoveryMachine) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status = RecoveryStatus{State: RecoveryIdle}
+}
+
+// clear 提速成功后结束 exhausted 或 cooldown 状态，不影响正在进行的恢复流程
+func (m *recoveryMachine) clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch m.status.State {
+	case RecoveryExhausted, RecoveryCooldown:
+		m.status = RecoveryStatus{State: RecoveryIdle}
+	}
+}
+
+// backoffDelay 第 attempt 次重试（从 1 开始）前的等待时间
+// fixed 每次等待 retry_interval，exponential 从 retry_interval 开始每次翻倍；
+// 两者都不超过 max_interval，再随机增加 jitter 以内的时长
+func backoffDelay(cfg *config.AutoRecoveryConfig, attempt int) time.Duration {
+	delay := cfg.RetryInterval
+	if cfg.Backoff == config.BackoffExponential {
+		for i := 1; i < attempt; i++ {
+			if cfg.MaxInterval > 0 && delay >= cfg.MaxInterval {
+				break
+			}
+			if delay > math.MaxInt64/2 {
+				break
+			}
+			delay *= 2
+		}
+	}
+	if cfg.MaxInterval > 0 && delay > cfg.MaxInterval {
+		delay = cfg.MaxInterval
+	}
+	if cfg.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(cfg.Jitter)))
+	}
+	return delay
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"speedtestup/config"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.AutoRecoveryConfig
+		want []time.Duration
+	}{
+		{
+			name: "fixed",
+			cfg:  config.AutoRecoveryConfig{RetryInterval: 5 * time.Minute, Backoff: config.BackoffFixed},
+			want: []time.Duration{5 * time.Minute, 5 * time.Minute, 5 * time.Minute},
+		},
+		{
+			name: "exponential",
+			cfg:  config.AutoRecoveryConfig{RetryInterval: time.Minute, Backoff: config.BackoffExponential},
+			want: []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute},
+		},
+		{
+			name: "exponential capped",
+			cfg:  config.AutoRecoveryConfig{RetryInterval: time.Minute, Backoff: config.BackoffExponential, MaxInterval: 5 * time.Minute},
+			want: []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		for i, want := range tt.want {
+			if got := backoffDelay(&tt.cfg, i+1); got != want {
+				t.Errorf("%s: attempt %d expected %v, got %v", tt.name, i+1, want, got)
+			}
+		}
+	}
+
+	// 次数很大时不会溢出
+	cfg := config.AutoRecoveryConfig{RetryInterval: time.Minute, Backoff: config.BackoffExponential}
+	if got := backoffDelay(&cfg, 1000); got <= 0 {
+		t.Errorf("Expected a positive delay for attempt 1000, got %v", got)
+	}
+
+	cfg = config.AutoRecoveryConfig{RetryInterval: time.Minute, Backoff: config.BackoffFixed, MaxInterval: time.Minute, Jitter: 30 * time.Second}
+	for i := 0; i < 20; i++ {
+		if got := backoffDelay(&cfg, 1); got < time.Minute || got >= time.Minute+30*time.Second {
+			t.Fatalf("Expected delay in [1m, 1m30s), got %v", got)
+		}
+	}
+}
+
+func TestSpeedupService_RecoveryExhaustedCooldown(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.AutoRecovery.RetryInterval = time.Minute
+	cfg.Speedup.AutoRecovery.Backoff = config.BackoffExponential
+	clock := newFakeClock()
+	logs := &recordHandler{}
+	failure := errors.New("connection refused")
+	fake := &fakeSpeedupAPI{
+		reopens: []error{failure, failure, failure, failure, failure},
+		query:   queryResponse(t, "active", clock),
+	}
+	svc := NewSpeedupService(fake, cfg, WithClock(clock), WithLogHandler(logs))
+
+	// 每次重新开启提速时记录自动恢复的状态
+	var states []RecoveryState
+	fake.onReopen = func(context.Context) error {
+		states = append(states, svc.RecoveryStatus().State)
+		return nil
+	}
+
+	start := clock.Now()
+	if err := svc.Execute(context.Background(), TriggerManual); err == nil || !strings.Contains(err.Error(), "自动恢复失败") {
+		t.Fatalf("Expected recovery to be exhausted, got %v", err)
+	}
+	// 首次请求加 3 次重试，重试之间不会嵌套新的恢复流程
+	if got := fake.Calls(); got != "reopen,reopen,reopen,reopen" {
+		t.Errorf("Unexpected API calls: %s", got)
+	}
+	wantStates := []RecoveryState{RecoveryIdle, RecoveryAttempting, RecoveryAttempting, RecoveryAttempting}
+	for i, state := range states {
+		if state != wantStates[i] {
+			t.Errorf("Expected state %s on reopen %d, got %s", wantStates[i], i+1, state)
+		}
+	}
+	// 指数退避：1m + 2m + 4m
+	if elapsed := clock.Now().Sub(start); elapsed != 7*time.Minute {
+		t.Errorf("Expected 7m of backoff, got %v", elapsed)
+	}
+
+	status := svc.RecoveryStatus()
+	if status.State != RecoveryCooldown || status.Attempt != 3 || status.LastError != failure.Error() {
+		t.Errorf("Unexpected recovery status: %+v", status)
+	}
+	if want := clock.Now().Add(cfg.Speedup.AutoRecovery.Cooldown); !status.CooldownUntil.Equal(want) {
+		t.Errorf("Expected cooldown until %v, got %v", want, status.CooldownUntil)
+	}
+	for _, event := range []string{"recovery_started", "recovery_attempt", "recovery_exhausted"} {
+		if !logs.Has(event) {
+			t.Errorf("Expected %s log event", event)
+		}
+	}
+
+	// 冷却期内失败不再重试
+	if err := svc.Execute(context.Background(), TriggerManual); err == nil || !strings.Contains(err.Error(), "冷却") {
+		t.Errorf("Expected cooldown error, got %v", err)
+	}
+	if !logs.Has("recovery_skipped") {
+		t.Error("Expected recovery_skipped log event")
+	}
+
+	// 冷却结束后回到 idle，提速成功
+	clock.Advance(cfg.Speedup.AutoRecovery.Cooldown)
+	if state := svc.RecoveryStatus().State; state != RecoveryIdle {
+		t.Errorf("Expected idle after cooldown, got %s", state)
+	}
+	if err := svc.Execute(context.Background(), TriggerManual); err != nil {
+		t.Errorf("Expected Execute to succeed after cooldown, got %v", err)
+	}
+}
+
+func TestSpeedupService_RecoveryExhaustedWithoutCooldown(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.AutoRecovery.MaxRetries = 1
+	cfg.Speedup.AutoRecovery.Cooldown = 0
+	clock := newFakeClock()
+	failure := errors.New("connection refused")
+	fake := &fakeSpeedupAPI{
+		reopens: []error{failure, failure},
+		query:   queryResponse(t, "active", clock),
+	}
+	svc := NewSpeedupService(fake, cfg, WithClock(clock), WithLogHandler(&recordHandler{}))
+
+	if err := svc.Execute(context.Background(), TriggerManual); err == nil {
+		t.Fatal("Expected recovery to be exhausted")
+	}
+	if state := svc.RecoveryStatus().State; state != RecoveryExhausted {
+		t.Errorf("Expected state %s, got %s", RecoveryExhausted, state)
+	}
+
+	// 下一次提速成功后回到 idle
+	if err := svc.Execute(context.Background(), TriggerManual); err != nil {
+		t.Fatalf("Execute returned error: %v", err)
+	}
+	if state := svc.RecoveryStatus().State; state != RecoveryIdle {
+		t.Errorf("Expected idle after a successful execute, got %s", state)
+	}
+}
+
+func TestSpeedupService_RecoveryCanceled(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	clock := newFakeClock()
+	logs := &recordHandler{}
+	failure := errors.New("connection refused")
+	ctx, cancel := context.WithCancel(context.Background())
+	// 第二次重新开启提速时取消 context
+	fake := &fakeSpeedupAPI{reopens: []error{failure, failure, failure}}
+	reopens := 0
+	fake.onReopen = func(context.Context) error {
+		if reopens++; reopens == 2 {
+			cancel()
+		}
+		return nil
+	}
+	svc := NewSpeedupService(fake, cfg, WithClock(clock), WithLogHandler(logs))
+
+	// 第一次重试期间被取消，不再等待下一次重试
+	if err := svc.Execute(ctx, TriggerManual); err == nil || !strings.Contains(err.Error(), "已取消") {
+		t.Fatalf("Expected recovery to be canceled, got %v", err)
+	}
+	if got := fake.Calls(); got != "reopen,reopen" {
+		t.Errorf("Expected no retries after cancellation, got calls %s", got)
+	}
+	if state := svc.RecoveryStatus().State; state != RecoveryIdle {
+		t.Errorf("Expected idle after cancellation, got %s", state)
+	}
+	if !logs.Has("recovery_canceled") {
+		t.Error("Expected recovery_canceled log event")
+	}
+
+	// 已取消的 ctx 导致请求失败时不开始自动恢复
+	logs = &recordHandler{}
+	svc = NewSpeedupService(&fakeSpeedupAPI{reopens: []error{failure}}, cfg, WithClock(clock), WithLogHandler(logs))
+	if err := svc.Execute(ctx, TriggerManual); err == nil || !strings.Contains(err.Error(), "已取消") {
+		t.Errorf("Expected canceled error, got %v", err)
+	}
+	if logs.Has("recovery_started") {
+		t.Error("Expected no recovery after the context was canceled")
+	}
+}
+
+func TestScheduler_GetStatus_Recovery(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.AutoRecovery.MaxRetries = 1
+	clock := newFakeClock()
+	opts := []Option{WithClock(clock), WithLogHandler(&recordHandler{})}
+	fake := &fakeSpeedupAPI{reopens: []error{errors.New("timeout"), errors.New("timeout")}}
+	speedupService := NewSpeedupService(fake, cfg, opts...)
+	scheduler := NewScheduler(NewIPService(&fakeIPProvider{}, cfg, opts...), speedupService, cfg, opts...)
+
+	status, ok := scheduler.GetStatus()["recovery"].(RecoveryStatus)
+	if !ok || status.State != RecoveryIdle {
+		t.Fatalf("Expected idle recovery status, got %v", scheduler.GetStatus()["recovery"])
+	}
+
+	speedupService.Execute(context.Background(), TriggerManual)
+	status = scheduler.GetStatus()["recovery"].(RecoveryStatus)
+	if status.State != RecoveryCooldown || status.Operation != "重新开启提速" {
+		t.Errorf("Expected cooldown after exhausted recovery, got %+v", status)
+	}
+}
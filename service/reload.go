@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"speedtestup/api"
+	"speedtestup/config"
+)
+
+// linePlan 单条线路的重载计划
+// 所有可能失败的步骤（校验配置、创建客户端）都在应用前完成
+type linePlan struct {
+	cfg        *config.Config
+	line       *Line          // 已有的线路，新增线路时为 nil
+	created    *Line          // 新增的线路
+	ipAPI      api.IPProvider // IP 检测配置变化时重建的 IP 提供者
+	speedupAPI SpeedupAPI     // IP 绑定配置变化时重建的提速客户端
+}
+
+// Reload 应用新的配置
+// 先为所有线路校验配置并创建需要重建的客户端，任一线路失败时返回错误且保持原配置运行；
+// 全部成功后对已有线路只更新发生变化的部分，新增的线路会被启动，移除的线路会被停止
+func (m *Manager) Reload(cfg *config.Config) error {
+	m.mu.Lock()
+
+	var plans []*linePlan
+	for _, lineCfg := range cfg.EffectiveLines() {
+		if !lineCfg.Enabled {
+			continue
+		}
+		plan, err := m.prepareLine(cfg.ForLine(lineCfg))
+		if err != nil {
+			m.mu.Unlock()
+			return fmt.Errorf("线路 %s: %v", lineCfg.Name, err)
+		}
+		plans = append(plans, plan)
+	}
+	if len(plans) == 0 {
+		m.mu.Unlock()
+		return fmt.Errorf("没有可用的提速线路")
+	}
+
+	lines := make([]*Line, 0, len(plans))
+	kept := make(map[string]bool, len(plans))
+	var added []*Line
+	for _, plan := range plans {
+		if plan.created != nil {
+			line := plan.created
+			if m.store != nil {
+				line.AttachState(m.store)
+			}
+			if m.notifier != nil {
+				line.SpeedupService.SetNotifier(m.notifier)
+				line.Scheduler.SetNotifier(m.notifier)
+			}
+			added = append(added, line)
+			lines = append(lines, line)
+			m.logger.Info("新增线路 %s", line.Name)
+			continue
+		}
+
+		m.applyPlan(plan)
+		lines = append(lines, plan.line)
+		kept[plan.line.Name] = true
+	}
+
+	var removed []*Line
+	for _, line := range m.lines {
+		if kept[line.Name] {
+			continue
+		}
+		removed = append(removed, line)
+		m.logger.Info("线路 %s 已移除", line.Name)
+	}
+
+	m.lines = lines
+	started := m.started
+	m.mu.Unlock()
+
+	// 移除的线路在后台停止，按 shutdown.timeout 等待其正在运行的任务结束
+	if started {
+		for _, line := range removed {
+			go func(line *Line) {
+				ctx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+				defer cancel()
+				if err := line.Scheduler.Stop(ctx); err != nil {
+					m.logger.Warn("线路 %s 停止失败: %v", line.Name, err)
+				}
+			}(line)
+		}
+	}
+
+	// 新增线路的首次提速不阻塞重载
+	if started {
+		for _, line := range added {
+			go func(line *Line) {
+				if err := line.Scheduler.Start(context.Background()); err != nil {
+					m.logger.Error("线路 %s 启动失败: %v", line.Name, err)
+				}
+			}(line)
+		}
+	}
+	return nil
+}
+
+// prepareLine 为单条线路生成重载计划（调用方需持有锁）
+func (m *Manager) prepareLine(cfg *config.Config) (*linePlan, error) {
+	if err := config.ValidateCron(cfg.Speedup.ReopenSchedule); err != nil {
+		return nil, fmt.Errorf("reopen_schedule 无效: %v", err)
+	}
+
+	existing := m.lineLocked(cfg.Line)
+	if existing == nil {
+		line, err := NewLine(cfg, m.opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &linePlan{cfg: cfg, created: line}, nil
+	}
+
+	plan := &linePlan{cfg: cfg, line: existing}
+	old := &existing.Config.Speedup
+
+	// HTTP 客户端配置变化时 IP 提供者和提速客户端都需要重建，接口地址变化时重建提速客户端
+	httpChanged := !reflect.DeepEqual(existing.Config.HTTPClient, cfg.HTTPClient)
+	detectionChanged := httpChanged || !reflect.DeepEqual(old.IPDetection, cfg.Speedup.IPDetection)
+	bindingChanged := httpChanged || old.IPBinding != cfg.Speedup.IPBinding || existing.Config.Endpoints != cfg.Endpoints
+	if !detectionChanged && !bindingChanged {
+		return plan, nil
+	}
+
+	httpFactory, err := NewHTTPClientFactory(cfg, m.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if detectionChanged {
+		ipAPI, err := NewIPProvider(&cfg.Speedup.IPDetection, httpFactory)
+		if err != nil {
+			return nil, fmt.Errorf("初始化 IP 提供者失败: %v", err)
+		}
+		plan.ipAPI = ipAPI
+	}
+
+	if bindingChanged {
+		speedupAPI, err := NewSpeedTestCNClient(cfg, httpFactory)
+		if err != nil {
+			return nil, err
+		}
+		plan.speedupAPI = speedupAPI
+	}
+
+	return plan, nil
+}
+
+// applyPlan 将重载计划应用到已有线路（调用方需持有锁）
+func (m *Manager) applyPlan(plan *linePlan) {
+	line := plan.line
+	if plan.ipAPI != nil {
+		m.logger.Info("线路 %s 的 IP 检测或 HTTP 客户端配置已变化，已重建 IP 提供者", line.Name)
+	}
+	if plan.speedupAPI != nil {
+		m.logger.Info("线路 %s 的 IP 绑定、HTTP 客户端或接口地址配置已变化，已重建提速客户端", line.Name)
+	}
+
+	// 提速服务先于调度器更新，调度器重新安排续期时使用新的关注方向
+	line.IPService.Reload(plan.cfg, plan.ipAPI)
+	line.SpeedupService.Reload(plan.cfg, plan.speedupAPI)
+	line.Scheduler.Reload(plan.cfg)
+	line.Config = plan.cfg
+}
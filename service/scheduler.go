@@ -1,11 +1,19 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"speedtestup/api"
+	"speedtestup/clock"
 	"speedtestup/config"
+	"speedtestup/metrics"
+	"speedtestup/notify"
 	"speedtestup/utils"
 
 	"github.com/robfig/cron/v3"
@@ -13,44 +21,90 @@ import (
 
 // Scheduler 调度服务
 type Scheduler struct {
-	cron          *cron.Cron
-	ipService     *IPService
+	cron           Cron
+	ipService      *IPService
 	speedupService *SpeedupService
-	config        *config.SpeedupConfig
-	logger        *utils.Logger
-	lastIP        string
-	running       bool
-	mu            sync.Mutex
+	config         *config.SpeedupConfig
+	line           string
+	logger         *utils.Logger
+	running        bool
+	entries        map[string]cron.EntryID // 任务名称 -> cron 任务 ID
+	renewalTimer   clock.Timer             // 按截止时间续期的一次性定时器
+	renewalAt      time.Time
+	renewalRetries int // 续期连续失败的次数，按截止时间安排续期后清零
+	notifier       notify.Notifier
+	clock          clock.Clock
+	ctx            context.Context // 调度器运行期间有效，停止时取消以中断正在进行的请求和自动恢复的等待
+	cancel         context.CancelFunc
+	jobs           sync.WaitGroup        // 正在运行的任务
+	active         map[uint64]runningJob // 正在运行的任务，停止时用于记录被中止的任务
+	jobSeq         uint64
+	mu             sync.Mutex
 }
 
+// runningJob 正在运行的任务
+type runningJob struct {
+	name    string
+	started time.Time
+}
+
+// 定时任务名称
+const (
+	jobHeartbeat = "heartbeat"
+	jobSelfCheck = "self_check"
+	jobReopen    = "reopen"
+	jobRenewal   = "renewal"
+	jobStartup   = "startup" // 启动时的首次提速
+	jobManual    = "manual"  // 通过 HTTP 接口手动触发的提速
+)
+
+// minRenewalDelay 续期的最短等待时间
+// 续期后截止时间未延后时，避免在截止前的窗口内连续请求
+const minRenewalDelay = time.Minute
+
 // NewScheduler 创建新的调度器实例
-func NewScheduler(ipService *IPService, speedupService *SpeedupService, cfg *config.Config) *Scheduler {
-	logger, err := utils.NewLogger(cfg.Logging.Level, cfg.Logging.Output, cfg.Logging.File)
-	if err != nil {
-		// 无法初始化 logger 是一个严重问题，至少需要 panic 或返回错误
-		fmt.Printf("Failed to initialize logger for Scheduler: %v\n", err)
-		panic(fmt.Sprintf("failed to initialize logger: %v", err))
-	}
-	logger = logger.WithPrefix("Scheduler")
+// opts 可注入时钟和日志处理器
+func NewScheduler(ipService *IPService, speedupService *SpeedupService, cfg *config.Config, opts ...Option) *Scheduler {
+	d := newDeps(opts)
+	logger := d.logger(cfg, "Scheduler")
 
 	return &Scheduler{
-		cron:           cron.New(),
+		cron:           newCron(d.clock),
 		ipService:      ipService,
 		speedupService: speedupService,
 		config:         &cfg.Speedup,
+		line:           lineLabel(cfg),
 		logger:         logger,
-		lastIP:         "",
 		running:        false,
+		entries:        make(map[string]cron.EntryID),
+		notifier:       notify.Nop{},
+		clock:          d.clock,
+		ctx:            context.Background(),
+		cancel:         func() {},
+		active:         make(map[uint64]runningJob),
 	}
 }
 
+// speedupConfig 获取当前的提速配置
+func (s *Scheduler) speedupConfig() *config.SpeedupConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+// SetNotifier 设置事件通知
+func (s *Scheduler) SetNotifier(n notify.Notifier) {
+	s.notifier = n
+}
+
 // Start 启动调度器
 // 对应 luci-app-broadbandacc 中的 main 函数逻辑
-func (s *Scheduler) Start() error {
+// 启动后按 startup.policy 执行首次提速，ctx 只约束这次首次提速，定时任务在调度器停止前一直运行
+func (s *Scheduler) Start(ctx context.Context) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	if s.running {
+		s.mu.Unlock()
 		s.logger.Warn("调度器已在运行")
 		return nil
 	}
@@ -64,42 +118,190 @@ func (s *Scheduler) Start() error {
 	s.startSelfCheck()
 
 	// 3. 启动重新开启提速的定时任务（对应每周一 0:0 的任务）
-	s.startReopenSchedule()
+	// 启用按截止时间续期时，由查询结果安排续期，不再使用固定的 cron
+	if s.config.ExpiryRenewal.Enabled {
+		s.speedupService.OnQuery(s.scheduleRenewal)
+		if lastQuery, _ := s.speedupService.GetLastQuery(); lastQuery != nil {
+			s.scheduleRenewalLocked(lastQuery)
+		}
+	} else {
+		s.startReopenSchedule()
+	}
 
 	// 4. 启动 cron 调度器
 	s.cron.Start()
 
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 	s.running = true
+	s.mu.Unlock()
 	s.logger.Success("调度器启动成功")
 
-	// 5. 执行首次提速
+	// 5. 按启动策略执行首次提速（不持有锁，避免阻塞状态查询）
+	s.runJob(jobStartup, func(runCtx context.Context) {
+		execCtx, cancel := context.WithCancel(runCtx)
+		defer cancel()
+		stop := context.AfterFunc(ctx, cancel)
+		defer stop()
+
+		s.startupExecute(execCtx)
+	})
+
+	return nil
+}
+
+// startupExecute 按 startup.policy 执行首次提速
+// 距离上一次提速（包括重启前保存在状态文件中的记录）不足 startup.min_interval 时跳过，
+// 避免容器反复重启时频繁调用接口
+func (s *Scheduler) startupExecute(ctx context.Context) {
+	startup := s.speedupConfig().Startup
+	skipLogger := s.logger.Event("startup_skipped").With("policy", startup.Policy)
+	if startup.Policy == config.StartupSkip {
+		skipLogger.Info("首次提速策略为 skip，跳过首次提速")
+		return
+	}
+
+	if last := s.speedupService.LastRun(); last != nil && startup.MinInterval > 0 {
+		if elapsed := s.clock.Now().Sub(last.Time); elapsed >= 0 && elapsed < startup.MinInterval {
+			skipLogger.With("last_run", last.Time, "trigger", last.Trigger).
+				Info("上一次提速在 %v 前（%s），不足 %v，跳过首次提速", elapsed.Round(time.Second), last.Time.Format("2006-01-02 15:04:05"), startup.MinInterval)
+			return
+		}
+	}
+
+	if startup.Policy == config.StartupIfInactive {
+		active, err := s.speedupService.QueryStatus(ctx)
+		if err == nil && active {
+			skipLogger.Info("提速仍然有效，跳过首次提速")
+			return
+		}
+		if err != nil {
+			s.logger.Warn("查询提速状态失败，执行首次提速: %v", err)
+		}
+	}
+
 	s.logger.Info("执行首次提速...")
-	if err := s.speedupService.Execute(); err != nil {
+	if err := s.speedupService.Execute(ctx, TriggerStartup); err != nil {
 		s.logger.Error("首次提速失败: %v", err)
 	} else {
 		s.logger.Success("首次提速成功")
 	}
-
-	return nil
 }
 
 // Stop 停止调度器
-func (s *Scheduler) Stop() error {
+// 先取消正在进行的请求和自动恢复的等待，再等待正在运行的任务结束；
+// ctx 到期时仍未结束的任务会被记录，并返回错误
+func (s *Scheduler) Stop(ctx context.Context) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	if !s.running {
+		s.mu.Unlock()
 		s.logger.Warn("调度器未在运行")
 		return nil
 	}
 
 	s.logger.Info("停止调度器...")
-	s.cron.Stop()
 	s.running = false
+	s.cancel()
+	s.cron.Stop()
+	s.speedupService.OnQuery(nil)
+	s.stopRenewalLocked()
+	pending := s.activeJobsLocked()
+	s.mu.Unlock()
+
+	if len(pending) > 0 {
+		s.logger.Info("等待 %d 个正在运行的任务结束: %s", len(pending), strings.Join(pending, ", "))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.mu.Lock()
+		remaining := s.activeJobsLocked()
+		s.mu.Unlock()
+		s.logger.Event("shutdown_timeout").With("jobs", strings.Join(remaining, ", ")).
+			Error("等待任务结束超时，仍在运行: %s", strings.Join(remaining, ", "))
+		return fmt.Errorf("等待任务结束超时（仍在运行: %s）: %v", strings.Join(remaining, ", "), ctx.Err())
+	}
+
 	s.logger.Success("调度器已停止")
 	return nil
 }
 
+// runJob 在调度器的 context 中执行任务，调度器未运行时不执行
+func (s *Scheduler) runJob(name string, fn func(ctx context.Context)) {
+	ctx, end, ok := s.beginJob(name)
+	if !ok {
+		return
+	}
+	defer end()
+	fn(ctx)
+}
+
+// beginJob 登记一个正在运行的任务，停止调度器时会等待其结束
+// 返回任务使用的 context 和结束时调用的函数；任务因调度器停止而中止时结束函数会记录日志
+// 调度器未运行时返回 false
+func (s *Scheduler) beginJob(name string) (context.Context, func(), bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return nil, nil, false
+	}
+
+	ctx := s.ctx
+	s.jobSeq++
+	id := s.jobSeq
+	started := s.clock.Now()
+	s.active[id] = runningJob{name: name, started: started}
+	s.jobs.Add(1)
+
+	end := func() {
+		if ctx.Err() != nil {
+			s.logger.Event("job_aborted").With("job", name, "duration_ms", s.clock.Now().Sub(started).Milliseconds()).
+				Warn("任务 %s 因调度器停止而中止", name)
+		}
+		s.mu.Lock()
+		delete(s.active, id)
+		s.mu.Unlock()
+		s.jobs.Done()
+	}
+	return ctx, end, true
+}
+
+// ExecuteInBackground 在后台执行一次提速（如手动触发），调度器停止时会取消并等待其结束
+// 调度器未运行时不执行并返回 false
+func (s *Scheduler) ExecuteInBackground(reason string) bool {
+	ctx, end, ok := s.beginJob(jobManual)
+	if !ok {
+		return false
+	}
+	go func() {
+		defer end()
+		s.runExecute(ctx, TriggerManual, reason)
+	}()
+	return true
+}
+
+// activeJobsLocked 正在运行的任务及已运行的时长（调用方需持有锁）
+func (s *Scheduler) activeJobsLocked() []string {
+	now := s.clock.Now()
+	ids := make([]uint64, 0, len(s.active))
+	for id := range s.active {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	jobs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		job := s.active[id]
+		jobs = append(jobs, fmt.Sprintf("%s（已运行 %v）", job.name, now.Sub(job.started).Round(time.Second)))
+	}
+	return jobs
+}
+
 // startHeartbeat 启动心跳检测
 // 对应 luci-app-broadbandacc 中的 _keepalive 函数
 func (s *Scheduler) startHeartbeat() {
@@ -114,11 +316,12 @@ func (s *Scheduler) startHeartbeat() {
 	s.logger.Debug("配置心跳检测间隔: %v (Cron: %s)", interval, cronExpr)
 
 	// 添加心跳检测任务
-	_, err := s.cron.AddFunc(cronExpr, s.heartbeatCheck)
+	id, err := s.cron.AddFunc(cronExpr, func() { s.runJob(jobHeartbeat, s.heartbeatCheck) })
 	if err != nil {
 		s.logger.Error("添加心跳检测任务失败: %v", err)
 		return
 	}
+	s.entries[jobHeartbeat] = id
 
 	s.logger.Debug("心跳检测任务已添加")
 }
@@ -135,11 +338,12 @@ func (s *Scheduler) startSelfCheck() {
 	cronExpr := "0 0 * * 1"
 	s.logger.Debug("配置 7 天自检 (Cron: %s)", cronExpr)
 
-	_, err := s.cron.AddFunc(cronExpr, s.selfCheckTask)
+	id, err := s.cron.AddFunc(cronExpr, func() { s.runJob(jobSelfCheck, s.selfCheckTask) })
 	if err != nil {
 		s.logger.Error("添加 7 天自检任务失败: %v", err)
 		return
 	}
+	s.entries[jobSelfCheck] = id
 
 	s.logger.Debug("7 天自检任务已添加")
 }
@@ -153,67 +357,245 @@ func (s *Scheduler) startReopenSchedule() {
 
 	s.logger.Debug("配置重新开启提速任务 (Cron: %s)", cronExpr)
 
-	_, err := s.cron.AddFunc(cronExpr, s.reopenSpeedupTask)
+	id, err := s.cron.AddFunc(cronExpr, func() { s.runJob(jobReopen, s.reopenSpeedupTask) })
 	if err != nil {
 		s.logger.Error("添加重新开启提速任务失败: %v", err)
 		return
 	}
+	s.entries[jobReopen] = id
 
 	s.logger.Debug("重新开启提速任务已添加")
 }
 
+// Reload 应用新的线路配置
+// 对比新旧配置，只重新注册发生变化的定时任务，不会重新执行提速
+func (s *Scheduler) Reload(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.config
+	s.config = &cfg.Speedup
+	if !s.running {
+		return
+	}
+
+	if old.CheckInterval != s.config.CheckInterval {
+		s.removeJobLocked(jobHeartbeat)
+		s.startHeartbeat()
+		s.logger.Info("心跳检测间隔已更新: %v -> %v", old.CheckInterval, s.config.CheckInterval)
+	}
+
+	if old.SelfCheck.Enabled != s.config.SelfCheck.Enabled {
+		s.removeJobLocked(jobSelfCheck)
+		s.startSelfCheck()
+		s.logger.Info("7 天自检已%s", enabledText(s.config.SelfCheck.Enabled))
+	}
+
+	oldRenewal, newRenewal := old.ExpiryRenewal, s.config.ExpiryRenewal
+	switch {
+	case oldRenewal.Enabled != newRenewal.Enabled && newRenewal.Enabled:
+		// 由固定 cron 切换为按截止时间续期
+		s.removeJobLocked(jobReopen)
+		s.speedupService.OnQuery(s.scheduleRenewal)
+		if lastQuery, _ := s.speedupService.GetLastQuery(); lastQuery != nil {
+			s.scheduleRenewalLocked(lastQuery)
+		}
+		s.logger.Info("已切换为按截止时间续期")
+	case oldRenewal.Enabled != newRenewal.Enabled:
+		// 由按截止时间续期切换为固定 cron
+		s.speedupService.OnQuery(nil)
+		s.stopRenewalLocked()
+		s.startReopenSchedule()
+		s.logger.Info("已切换为按 reopen_schedule 重新开启提速")
+	case newRenewal.Enabled:
+		// 续期提前量或关注的方向变化后，按最近一次查询结果重新安排
+		if oldRenewal != newRenewal || old.DownAcc != s.config.DownAcc || old.UpAcc != s.config.UpAcc {
+			s.stopRenewalLocked()
+			if lastQuery, _ := s.speedupService.GetLastQuery(); lastQuery != nil {
+				s.scheduleRenewalLocked(lastQuery)
+			}
+		}
+	case old.ReopenSchedule != s.config.ReopenSchedule:
+		s.removeJobLocked(jobReopen)
+		s.startReopenSchedule()
+		s.logger.Info("重新开启提速任务已更新: %s -> %s", old.ReopenSchedule, s.config.ReopenSchedule)
+	}
+}
+
+// removeJobLocked 移除指定的定时任务（调用方需持有锁）
+func (s *Scheduler) removeJobLocked(name string) {
+	if id, ok := s.entries[name]; ok {
+		s.cron.Remove(id)
+		delete(s.entries, name)
+	}
+}
+
+// enabledText 启用状态描述
+func enabledText(enabled bool) string {
+	if enabled {
+		return "启用"
+	}
+	return "禁用"
+}
+
+// scheduleRenewal 根据查询结果安排下一次续期
+func (s *Scheduler) scheduleRenewal(resp *api.SpeedupQueryResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+	s.scheduleRenewalLocked(resp)
+}
+
+// scheduleRenewalLocked 在最早的截止时间前 lead（再随机提前 jitter 以内）安排一次续期
+// 调用方需持有锁
+func (s *Scheduler) scheduleRenewalLocked(resp *api.SpeedupQueryResponse) {
+	now := s.clock.Now()
+	kinds := s.speedupService.ExpiryKinds()
+	if len(kinds) == 0 {
+		s.logger.Debug("下行和上行提速均未启用，不安排续期")
+		return
+	}
+	kind, expireTime, ok := resp.NextExpiry(now, kinds...)
+	if !ok {
+		s.logger.Debug("没有未过期的提速，暂不安排续期")
+		return
+	}
+
+	renewAt := expireTime.Add(-s.config.ExpiryRenewal.Lead)
+	if jitter := s.config.ExpiryRenewal.Jitter; jitter > 0 {
+		renewAt = renewAt.Add(-time.Duration(rand.Int63n(int64(jitter))))
+	}
+	if earliest := now.Add(minRenewalDelay); renewAt.Before(earliest) {
+		renewAt = earliest
+	}
+
+	s.stopRenewalLocked()
+	s.renewalRetries = 0
+	s.renewalAt = renewAt
+	s.renewalTimer = s.clock.AfterFunc(renewAt.Sub(now), s.renewalTask)
+	s.logger.Event("renewal_scheduled").With("kind", kind, "expiry", expireTime, "renew_at", renewAt).
+		Info("提速（%s）将于 %s 截止，已安排在 %s 续期",
+			kind, expireTime.Format("2006-01-02 15:04:05"), renewAt.Format("2006-01-02 15:04:05"))
+}
+
+// stopRenewalLocked 取消已安排的续期（调用方需持有锁）
+func (s *Scheduler) stopRenewalLocked() {
+	if s.renewalTimer != nil {
+		s.renewalTimer.Stop()
+		s.renewalTimer = nil
+	}
+	s.renewalAt = time.Time{}
+}
+
+// renewalTask 按截止时间续期任务
+func (s *Scheduler) renewalTask() {
+	s.mu.Lock()
+	s.renewalTimer = nil
+	s.renewalAt = time.Time{}
+	s.mu.Unlock()
+
+	s.runJob(jobRenewal, func(ctx context.Context) {
+		if err := s.runExecute(ctx, TriggerRenewal, "提速即将截止，执行续期"); err != nil && ctx.Err() == nil {
+			s.scheduleRenewalRetry()
+		}
+	})
+}
+
+// scheduleRenewalRetry 续期失败后按自动恢复的退避策略安排重试
+// 续期模式下没有固定的 reopen 任务，续期只能由查询结果重新安排；
+// 续期失败且没有新的查询结果时，由重试保证续期不会就此停止
+func (s *Scheduler) scheduleRenewalRetry() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 执行过程中的查询已重新安排续期，或调度器已停止、已切换为固定 cron
+	if !s.running || !s.config.ExpiryRenewal.Enabled || s.renewalTimer != nil {
+		return
+	}
+
+	s.renewalRetries++
+	delay := backoffDelay(&s.config.AutoRecovery, s.renewalRetries)
+	if delay < minRenewalDelay {
+		delay = minRenewalDelay
+	}
+	s.renewalAt = s.clock.Now().Add(delay)
+	s.renewalTimer = s.clock.AfterFunc(delay, s.renewalTask)
+	s.logger.Event("renewal_retry_scheduled").With("attempt", s.renewalRetries, "renew_at", s.renewalAt).
+		Warn("续期失败，将在 %v 后（%s）第 %d 次重试", delay, s.renewalAt.Format("2006-01-02 15:04:05"), s.renewalRetries)
+}
+
+// runExecute 执行一次提速并记录结果
+func (s *Scheduler) runExecute(ctx context.Context, source TriggerSource, reason string) error {
+	logger := s.logger.Event("execute").With("source", string(source), "reason", reason)
+	logger.Info("%s...", reason)
+	err := s.speedupService.Execute(ctx, source)
+	if err != nil {
+		logger.With("error", err.Error()).Error("%s失败: %v", reason, err)
+	} else {
+		logger.Success("%s成功", reason)
+	}
+	return err
+}
+
 // shouldCheckSpeedupStatus 判断是否应该检查提速状态
+// 距离最近一次查询（包括执行提速时的查询）已超过 status_check_interval 时检查，
+// 间隔最长按 24 小时计算，确保每天至少检查一次
 func (s *Scheduler) shouldCheckSpeedupStatus() bool {
+	cfg := s.speedupConfig()
+
 	// 如果 StatusCheckInterval 为 0，表示禁用状态检查
-	if s.config.StatusCheckInterval <= 0 {
+	statusInterval := cfg.StatusCheckInterval
+	if statusInterval <= 0 {
 		s.logger.Debug("提速状态检查已禁用")
 		return false
 	}
 
 	// 限制最长检查间隔为 24 小时（确保能在 1 天内检查）
 	maxInterval := 24 * time.Hour
-	if s.config.StatusCheckInterval > maxInterval {
-		s.config.StatusCheckInterval = maxInterval
+	if statusInterval > maxInterval {
+		statusInterval = maxInterval
 	}
 
-	// 检查是否到了应该检查状态的时间
-	// 这里我们根据心跳检测的间隔来触发，确保不会超过配置的检查间隔
-	// 因为心跳检测每 10 分钟运行一次，我们可以计算需要多少次心跳后才检查状态
-	checkIntervalMinutes := int(s.config.StatusCheckInterval.Minutes())
-	heartbeatMinutes := int(s.config.CheckInterval.Minutes())
-
-	if heartbeatMinutes <= 0 {
-		heartbeatMinutes = 10 // 默认 10 分钟
-	}
-
-	// 计算需要多少次心跳检测后才检查一次状态
-	heartbeatCount := checkIntervalMinutes / heartbeatMinutes
-	if heartbeatCount <= 0 {
-		heartbeatCount = 1
+	_, lastQueryAt := s.speedupService.GetLastQuery()
+	if lastQueryAt.IsZero() {
+		return true
 	}
-
-	// 使用随机或简单的计数方式来避免每次都检查
-	// 这里我们使用当前时间戳来简化判断
-	timeStamp := time.Now().Unix()
-	return (timeStamp/int64(heartbeatMinutes))%int64(heartbeatCount) == 0
+	return s.clock.Now().Sub(lastQueryAt) >= statusInterval
 }
 
 // heartbeatCheck 心跳检测任务
 // 对应 luci-app-broadbandacc 中的 _keepalive 函数
-func (s *Scheduler) heartbeatCheck() {
-	s.logger.Debug("开始心跳检测...")
+func (s *Scheduler) heartbeatCheck(ctx context.Context) {
+	s.logger.Event("heartbeat").Debug("开始心跳检测...")
+	metrics.LastHeartbeat.Set(float64(s.clock.Now().Unix()), s.line)
 
-	// 1. 检查 IP 是否变化
-	ipChanged, err := s.ipService.CheckIPChange()
+	// 1. 检查绑定接口的地址是否变化（PPPoE 重拨后地址会改变）
+	ifaceChanged, err := s.ipService.CheckInterfaceIPChange()
+	if err != nil {
+		s.logger.Warn("检查绑定接口地址失败: %v", err)
+	} else if ifaceChanged {
+		s.speedupService.Rebind()
+	}
+
+	// 2. 检查 IP 是否变化
+	ipChanged, err := s.ipService.CheckIPChange(ctx)
 	if err != nil {
 		s.logger.Error("心跳检测失败: %v", err)
 		return
 	}
+	ipChanged = ipChanged || ifaceChanged
 
-	// 2. 如果 IP 发生变化，重新执行提速（立即执行）
+	// 3. 如果 IP 发生变化，重新执行提速（立即执行）
 	if ipChanged {
+		ipv4, ipv6 := s.ipService.GetLastIPs()
+		s.notifier.Notify(notify.NewEvent(notify.EventIPChanged, s.line, "IP 发生变化",
+			fmt.Sprintf("当前 IPv4: %s，IPv6: %s，将重新执行提速", displayIP(ipv4), displayIP(ipv6))))
+
 		s.logger.Info("IP 发生变化，重新执行提速...")
-		if err := s.speedupService.Execute(); err != nil {
+		if err := s.speedupService.Execute(ctx, TriggerIPChange); err != nil {
 			s.logger.Error("IP 变化后提速失败: %v", err)
 		} else {
 			s.logger.Success("IP 变化后提速成功")
@@ -221,15 +603,16 @@ func (s *Scheduler) heartbeatCheck() {
 		return
 	}
 
-	// 3. 检查提速状态是否失效（根据配置的间隔）
+	// 4. 检查提速状态是否失效（根据配置的间隔）
 	if s.shouldCheckSpeedupStatus() {
 		s.logger.Debug("检查提速状态是否有效...")
-		speedupActive, err := s.speedupService.QueryStatus()
+		speedupActive, err := s.speedupService.QueryStatus(ctx)
 		if err != nil {
 			s.logger.Error("查询提速状态失败: %v", err)
 		} else if !speedupActive {
 			s.logger.Warn("检测到提速已失效，重新执行提速...")
-			if err := s.speedupService.Execute(); err != nil {
+			s.notifier.Notify(notify.NewEvent(notify.EventSpeedupLapsed, s.line, "提速已失效", "将重新执行提速"))
+			if err := s.speedupService.Execute(ctx, TriggerLapsed); err != nil {
 				s.logger.Error("提速恢复失败: %v", err)
 			} else {
 				s.logger.Success("提速恢复成功")
@@ -239,9 +622,9 @@ func (s *Scheduler) heartbeatCheck() {
 		}
 	}
 
-	// 4. 如果设置了 IP 绑定，验证绑定状态
-	if s.config.IPBinding.Enabled {
-		currentIP, err := s.ipService.GetCurrentIP()
+	// 5. 如果设置了 IP 绑定，验证绑定状态
+	if s.speedupConfig().IPBinding.Enabled {
+		currentIP, err := s.ipService.GetCurrentIP(ctx)
 		if err != nil {
 			s.logger.Error("获取当前 IP 失败: %v", err)
 			return
@@ -258,10 +641,10 @@ func (s *Scheduler) heartbeatCheck() {
 
 // selfCheckTask 7 天自检任务
 // 对应 luci-app-broadbandacc 中的 Weekly_cycle 函数
-func (s *Scheduler) selfCheckTask() {
+func (s *Scheduler) selfCheckTask(ctx context.Context) {
 	s.logger.Info("执行 7 天自检任务...")
 
-	if err := s.speedupService.ExecuteSelfCheck(); err != nil {
+	if err := s.speedupService.ExecuteSelfCheck(ctx); err != nil {
 		s.logger.Error("7 天自检失败: %v", err)
 	} else {
 		s.logger.Success("7 天自检完成")
@@ -269,10 +652,10 @@ func (s *Scheduler) selfCheckTask() {
 }
 
 // reopenSpeedupTask 重新开启提速任务
-func (s *Scheduler) reopenSpeedupTask() {
+func (s *Scheduler) reopenSpeedupTask(ctx context.Context) {
 	s.logger.Info("执行重新开启提速任务...")
 
-	if err := s.speedupService.Execute(); err != nil {
+	if err := s.speedupService.Execute(ctx, TriggerSchedule); err != nil {
 		s.logger.Error("重新开启提速失败: %v", err)
 	} else {
 		s.logger.Success("重新开启提速成功")
@@ -286,16 +669,50 @@ func (s *Scheduler) IsRunning() bool {
 	return s.running
 }
 
+// NextRuns 获取各定时任务的下次执行时间
+func (s *Scheduler) NextRuns() map[string]time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextRunsLocked()
+}
+
+// nextRunsLocked 获取各定时任务的下次执行时间（调用方需持有锁）
+func (s *Scheduler) nextRunsLocked() map[string]time.Time {
+	runs := make(map[string]time.Time, len(s.entries))
+	for name, id := range s.entries {
+		entry := s.cron.Entry(id)
+		if entry.Valid() && !entry.Next.IsZero() {
+			runs[name] = entry.Next
+		}
+	}
+	if !s.renewalAt.IsZero() {
+		runs[jobRenewal] = s.renewalAt
+	}
+	return runs
+}
+
 // GetStatus 获取调度器状态
 func (s *Scheduler) GetStatus() map[string]interface{} {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	ipv4, ipv6 := s.ipService.GetLastIPs()
+	lastQuery, lastQueryAt := s.speedupService.GetLastQuery()
+
 	return map[string]interface{}{
-		"running":       s.running,
-		"last_execute":  s.speedupService.GetLastExecuteTime(),
+		"line":           s.line,
+		"running":        s.running,
+		"last_execute":   s.speedupService.GetLastExecuteTime(),
+		"last_query":     lastQuery,
+		"last_query_at":  lastQueryAt,
+		"ipv4":           ipv4,
+		"ipv6":           ipv6,
+		"next_runs":      s.nextRunsLocked(),
 		"check_interval": s.config.CheckInterval.String(),
-		"self_check":    s.config.SelfCheck.Enabled,
-		"auto_recovery": s.config.AutoRecovery.Enabled,
+		"self_check":     s.config.SelfCheck.Enabled,
+		"auto_recovery":  s.config.AutoRecovery.Enabled,
+		"recovery":       s.speedupService.RecoveryStatus(),
+		"executions":     s.speedupService.ExecutionStatus(),
+		"last_run":       s.speedupService.LastRun(),
 	}
 }
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"speedtestup/api"
+	"speedtestup/config"
+)
+
+// newTestScheduler 创建使用默认配置的调度器（不启动 cron）
+func newTestScheduler(t *testing.T, cfg *config.Config) *Scheduler {
+	t.Helper()
+	ipService := NewIPService(api.NewIPAPI(), cfg)
+	speedupService := NewSpeedupService(newTestClient(t, ""), cfg)
+	return NewScheduler(ipService, speedupService, cfg)
+}
+
+// TestScheduler_ScheduleRenewal 测试按截止时间安排续期
+func TestScheduler_ScheduleRenewal(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.ExpiryRenewal.Enabled = true
+	cfg.Speedup.ExpiryRenewal.Lead = 10 * time.Minute
+	cfg.Speedup.ExpiryRenewal.Jitter = 2 * time.Minute
+	s := newTestScheduler(t, cfg)
+	s.running = true
+	defer s.Stop(context.Background())
+
+	expireTime := time.Now().Add(time.Hour)
+	resp := &api.SpeedupQueryResponse{}
+	resp.Data.DownExpireT = float64(expireTime.Unix())
+	resp.Data.UpHExpireT = float64(expireTime.Add(time.Hour).Unix())
+	s.scheduleRenewal(resp)
+
+	renewAt, ok := s.NextRuns()[jobRenewal]
+	if !ok {
+		t.Fatal("Expected renewal to be scheduled")
+	}
+	latest := time.Unix(expireTime.Unix(), 0).Add(-10 * time.Minute)
+	earliest := latest.Add(-2 * time.Minute)
+	if renewAt.After(latest) || renewAt.Before(earliest) {
+		t.Errorf("Expected renewal between %v and %v, got %v", earliest, latest, renewAt)
+	}
+
+	// 截止时间已很近时至少等待 minRenewalDelay
+	resp.Data.DownExpireT = float64(time.Now().Add(5 * time.Minute).Unix())
+	s.scheduleRenewal(resp)
+	if renewAt := s.NextRuns()[jobRenewal]; time.Until(renewAt) < minRenewalDelay-time.Second {
+		t.Errorf("Expected renewal to be delayed at least %v, got %v", minRenewalDelay, time.Until(renewAt))
+	}
+
+	// 停止后取消续期
+	s.Stop(context.Background())
+	if _, ok := s.NextRuns()[jobRenewal]; ok {
+		t.Error("Expected renewal to be cancelled after stop")
+	}
+}
+
+// TestScheduler_ScheduleRenewal_NoExpiry 测试没有未过期的提速时不安排续期
+func TestScheduler_ScheduleRenewal_NoExpiry(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.ExpiryRenewal.Enabled = true
+	s := newTestScheduler(t, cfg)
+	s.running = true
+
+	resp := &api.SpeedupQueryResponse{}
+	resp.Data.DownExpireT = float64(time.Now().Add(-time.Hour).Unix())
+	s.scheduleRenewal(resp)
+
+	if _, ok := s.NextRuns()[jobRenewal]; ok {
+		t.Error("Expected no renewal for expired speedup")
+	}
+}
+
+// TestScheduler_Reload 测试重载配置时只更新发生变化的定时任务
+func TestScheduler_Reload(t *testing.T) {
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.SelfCheck.Enabled = false
+	s := newTestScheduler(t, cfg)
+	s.running = true
+	s.startHeartbeat()
+	s.startReopenSchedule()
+	heartbeatID := s.entries[jobHeartbeat]
+	reopenID := s.entries[jobReopen]
+
+	// 只修改 reopen_schedule 和自检开关
+	next := config.NewDefaultConfig()
+	next.Speedup.SelfCheck.Enabled = true
+	next.Speedup.ReopenSchedule = "30 3 * * *"
+	s.Reload(next)
+
+	if s.entries[jobHeartbeat] != heartbeatID {
+		t.Error("Heartbeat job should not be re-registered when check_interval is unchanged")
+	}
+	if id, ok := s.entries[jobReopen]; !ok || id == reopenID {
+		t.Error("Reopen job should be re-registered when reopen_schedule changes")
+	}
+	if _, ok := s.entries[jobSelfCheck]; !ok {
+		t.Error("Self check job should be registered after being enabled")
+	}
+	if len(s.cron.Entries()) != 3 {
+		t.Errorf("Expected 3 cron entries, got %d", len(s.cron.Entries()))
+	}
+
+	// 切换为按截止时间续期后移除固定的 reopen 任务
+	renewal := config.NewDefaultConfig()
+	renewal.Speedup.ExpiryRenewal.Enabled = true
+	s.Reload(renewal)
+	if _, ok := s.entries[jobReopen]; ok {
+		t.Error("Reopen job should be removed when expiry renewal is enabled")
+	}
+	if s.speedupService.onQuery == nil {
+		t.Error("Expected query callback to be registered for expiry renewal")
+	}
+	s.Stop(context.Background())
+}
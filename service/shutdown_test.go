@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"speedtestup/api"
+	"speedtestup/config"
+)
+
+// newBlockingSpeedupAPI 重新开启提速时阻塞，直到 ctx 取消（ignoreCtx 时直到 release 关闭）
+// 开始阻塞时 started 收到通知
+func newBlockingSpeedupAPI(ignoreCtx bool) (fake *fakeSpeedupAPI, started, release chan struct{}) {
+	started, release = make(chan struct{}, 1), make(chan struct{})
+	fake = &fakeSpeedupAPI{}
+	fake.onReopen = func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		if ignoreCtx {
+			<-release
+			return errors.New("released")
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	fake.onQuery = func(context.Context) (*api.SpeedupQueryResponse, error) {
+		return nil, errors.New("not implemented")
+	}
+	return fake, started, release
+}
+
+// startBlocked 启动调度器，返回时首次提速正阻塞在重新开启提速
+func startBlocked(t *testing.T, speedup *fakeSpeedupAPI, started <-chan struct{}, logs *recordHandler) *Scheduler {
+	t.Helper()
+	cfg := config.NewDefaultConfig()
+	opts := []Option{WithLogHandler(logs)}
+	scheduler := NewScheduler(NewIPService(&fakeIPProvider{ip: "203.0.113.1"}, cfg, opts...), NewSpeedupService(speedup, cfg, opts...), cfg, opts...)
+
+	go scheduler.Start(context.Background())
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the startup execute")
+	}
+	return scheduler
+}
+
+// TestScheduler_Stop_CancelsRunningJobs 测试停止调度器时取消正在进行的请求并等待任务结束
+func TestScheduler_Stop_CancelsRunningJobs(t *testing.T) {
+	logs := &recordHandler{}
+	speedup, started, _ := newBlockingSpeedupAPI(false)
+	scheduler := startBlocked(t, speedup, started, logs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := scheduler.Stop(ctx); err != nil {
+		t.Fatalf("Stop returned error: %v", err)
+	}
+	if !logs.Has("job_aborted") {
+		t.Error("Expected job_aborted log event")
+	}
+	if logs.Has("recovery_started") {
+		t.Error("Expected no recovery after cancellation")
+	}
+	if scheduler.ExecuteInBackground("手动提速") {
+		t.Error("Expected ExecuteInBackground to refuse after Stop")
+	}
+}
+
+// TestScheduler_Stop_Timeout 测试任务未在期限内结束时 Stop 返回错误
+func TestScheduler_Stop_Timeout(t *testing.T) {
+	logs := &recordHandler{}
+	speedup, started, release := newBlockingSpeedupAPI(true)
+	defer close(release)
+	scheduler := startBlocked(t, speedup, started, logs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := scheduler.Stop(ctx)
+	if err == nil || !strings.Contains(err.Error(), jobStartup) {
+		t.Errorf("Expected timeout error naming the startup job, got %v", err)
+	}
+	if !logs.Has("shutdown_timeout") {
+		t.Error("Expected shutdown_timeout log event")
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"speedtestup/api"
+	"speedtestup/clock"
+	"speedtestup/config"
+	"speedtestup/mockserver"
+)
+
+// newSimulatedSpeedupAPI 按虚拟时间记录调用的提速接口
+// 每次重新开启提速后提速在 lifetime 之后截止
+func newSimulatedSpeedupAPI(sim clock.Clock, lifetime time.Duration) *fakeSpeedupAPI {
+	var mu sync.Mutex
+	var expiresAt time.Time
+	fake := &fakeSpeedupAPI{clock: sim}
+	fake.onReopen = func(context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		expiresAt = sim.Now().Add(lifetime)
+		return nil
+	}
+	fake.onQuery = func(context.Context) (*api.SpeedupQueryResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		now := sim.Now()
+		sc, _ := mockserver.Preset("active")
+		sc.Expiry = expiresAt.Sub(now)
+		if sc.Expiry <= 0 {
+			sc.Expiry = -time.Second
+		}
+		return mockserver.QueryResponse(sc, mockserver.DefaultIP, now), nil
+	}
+	return fake
+}
+
+// TestScheduler_SimulatedWeek 以虚拟时间运行一周的心跳、状态检查、定时重新开启和 7 天自检
+func TestScheduler_SimulatedWeek(t *testing.T) {
+	// 周三 12:00 启动，一周内经过一次周一 0:00
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	sim := clock.NewSimulated(start)
+	opts := []Option{WithClock(sim), WithLogHandler(&recordHandler{})}
+
+	cfg := config.NewDefaultConfig()
+	speedup := newSimulatedSpeedupAPI(sim, 48*time.Hour)
+	provider := &fakeIPProvider{ip: "203.0.113.1"}
+	ipService := NewIPService(provider, cfg, opts...)
+	speedupService := NewSpeedupService(speedup, cfg, opts...)
+	scheduler := NewScheduler(ipService, speedupService, cfg, opts...)
+
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer scheduler.Stop(context.Background())
+
+	// 周一 06:05 IP 变化
+	sim.AdvanceTo(time.Date(2026, 10, 19, 6, 5, 0, 0, time.UTC))
+	provider.SetIP("203.0.113.2")
+	end := start.Add(7 * 24 * time.Hour)
+	sim.AdvanceTo(end)
+
+	// 提速在重新开启 48 小时后截止，由状态检查发现失效后重新开启；
+	// 周一 0:00 的 7 天自检和定时重新开启各执行一次提速，后一次按 min_reopen_interval 等待 1 分钟；
+	// 06:10 的心跳发现 IP 变化
+	calls := speedup.Timeline()
+	var reopens []string
+	for _, c := range calls {
+		if c.name == "reopen" {
+			reopens = append(reopens, c.String())
+		}
+	}
+	want := []string{
+		"Wed 12:00 reopen",
+		"Fri 12:00 reopen",
+		"Sun 12:00 reopen",
+		"Mon 00:00 reopen",
+		"Mon 00:01 reopen",
+		"Mon 06:10 reopen",
+		"Wed 06:10 reopen",
+	}
+	if strings.Join(reopens, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Unexpected reopen sequence:\n got %v\nwant %v", reopens, want)
+	}
+
+	// 每次重新开启后立即查询一次；其余查询为状态检查，在与上一次查询间隔 status_check_interval 后的第一次心跳进行
+	var lastQuery time.Time
+	statusChecks := 0
+	for i, c := range calls {
+		switch {
+		case c.name == "reopen":
+			if i+1 >= len(calls) || calls[i+1].name != "query" || !calls[i+1].at.Equal(c.at) {
+				t.Errorf("Expected a query right after %s", c)
+			}
+		case i > 0 && calls[i-1].name == "reopen":
+			lastQuery = c.at
+		default:
+			if gap := c.at.Sub(lastQuery); gap < cfg.Speedup.StatusCheckInterval || gap >= cfg.Speedup.StatusCheckInterval+cfg.Speedup.CheckInterval {
+				t.Errorf("Expected status check at the first heartbeat %v after the previous query, got %s after %v", cfg.Speedup.StatusCheckInterval, c, gap)
+			}
+			lastQuery = c.at
+			statusChecks++
+		}
+	}
+	if len(calls) != 2*len(want)+statusChecks || statusChecks != 81 {
+		t.Errorf("Expected %d reopens and 81 status checks, got %d calls with %d status checks", len(want), len(calls), statusChecks)
+	}
+
+	if next := scheduler.NextRuns()[jobHeartbeat]; !next.Equal(end.Add(10 * time.Minute)) {
+		t.Errorf("Expected next heartbeat at %v, got %v", end.Add(10*time.Minute), next)
+	}
+}
+
+// TestScheduler_SimulatedRenewalFailure 续期失败后按退避策略重试，直到续期成功后重新按截止时间安排
+func TestScheduler_SimulatedRenewalFailure(t *testing.T) {
+	start := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	sim := clock.NewSimulated(start)
+	opts := []Option{WithClock(sim), WithLogHandler(&recordHandler{})}
+
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.StatusCheckInterval = 0
+	cfg.Speedup.SelfCheck.Enabled = false
+	cfg.Speedup.ExpiryRenewal.Enabled = true
+	cfg.Speedup.ExpiryRenewal.Jitter = 0
+	cfg.Speedup.AutoRecovery.Enabled = false
+	cfg.Speedup.AutoRecovery.RetryInterval = 10 * time.Minute
+
+	// 13:00 到 14:25 之间网络不可用
+	outageStart, outageEnd := start.Add(time.Hour), start.Add(145*time.Minute)
+	speedup := newSimulatedSpeedupAPI(sim, 2*time.Hour)
+	succeed := speedup.onReopen
+	speedup.onReopen = func(ctx context.Context) error {
+		if now := sim.Now(); !now.Before(outageStart) && now.Before(outageEnd) {
+			return errors.New("network unreachable")
+		}
+		return succeed(ctx)
+	}
+
+	ipService := NewIPService(&fakeIPProvider{ip: "203.0.113.1"}, cfg, opts...)
+	speedupService := NewSpeedupService(speedup, cfg, opts...)
+	scheduler := NewScheduler(ipService, speedupService, cfg, opts...)
+
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	defer scheduler.Stop(context.Background())
+
+	sim.AdvanceTo(start.Add(5 * time.Hour))
+
+	// 13:50 的续期失败后每 10 分钟重试一次，14:30 续期成功后在新的截止时间前 10 分钟再次续期
+	var reopens []string
+	for _, c := range speedup.Timeline() {
+		if c.name == "reopen" {
+			reopens = append(reopens, c.String())
+		}
+	}
+	want := []string{
+		"Wed 12:00 reopen",
+		"Wed 13:50 reopen",
+		"Wed 14:00 reopen",
+		"Wed 14:10 reopen",
+		"Wed 14:20 reopen",
+		"Wed 14:30 reopen",
+		"Wed 16:20 reopen",
+	}
+	if strings.Join(reopens, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Unexpected reopen sequence:\n got %v\nwant %v", reopens, want)
+	}
+
+	if next := scheduler.NextRuns()[jobRenewal]; !next.Equal(time.Date(2026, 10, 14, 18, 10, 0, 0, time.UTC)) {
+		t.Errorf("Expected next renewal at 18:10, got %v", next)
+	}
+}
@@ -1,212 +1,654 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"speedtestup/api"
+	"speedtestup/clock"
 	"speedtestup/config"
+	"speedtestup/metrics"
+	"speedtestup/notify"
+	"speedtestup/state"
 	"speedtestup/utils"
 )
 
 // SpeedupService 提速服务
 type SpeedupService struct {
-	apiClient   *api.SpeedTestCNClient
+	apiClient   SpeedupAPI
 	config      *config.AutoRecoveryConfig
 	selfCheck   *config.SelfCheckConfig
+	downAcc     bool // 是否关注下行提速
+	upAcc       bool // 是否关注上行提速
+	line        string
 	logger      *utils.Logger
 	lastExecute time.Time
+	lastQuery   *api.SpeedupQueryResponse
+	lastQueryAt time.Time
+	lastRun     *state.RunRecord
+	store       *state.Store
+	onQuery     func(*api.SpeedupQueryResponse)
+	notifier    notify.Notifier
+	clock       clock.Clock
+	recovery    *recoveryMachine
+	executions  *executionCoordinator
+	minReopen   time.Duration // 两次重新开启提速之间的最短间隔
+	mu          sync.Mutex
 }
 
 // NewSpeedupService 创建新的提速服务实例
-func NewSpeedupService(speedTestCNClient *api.SpeedTestCNClient, cfg *config.Config) *SpeedupService {
-	logger, err := utils.NewLogger(cfg.Logging.Level, cfg.Logging.Output, cfg.Logging.File)
-	if err != nil {
-		// 无法初始化 logger 是一个严重问题，至少需要 panic 或返回错误
-		fmt.Printf("Failed to initialize logger for SpeedupService: %v\n", err)
-		panic(fmt.Sprintf("failed to initialize logger: %v", err))
-	}
-	logger = logger.WithPrefix("SpeedupService")
+// opts 可注入时钟和日志处理器
+func NewSpeedupService(client SpeedupAPI, cfg *config.Config, opts ...Option) *SpeedupService {
+	d := newDeps(opts)
+	logger := d.logger(cfg, "SpeedupService")
 
 	return &SpeedupService{
-		apiClient:   speedTestCNClient,
+		apiClient:   client,
 		config:      &cfg.Speedup.AutoRecovery,
 		selfCheck:   &cfg.Speedup.SelfCheck,
+		downAcc:     cfg.Speedup.DownAcc,
+		upAcc:       cfg.Speedup.UpAcc,
+		line:        lineLabel(cfg),
 		logger:      logger,
+		notifier:    notify.Nop{},
+		clock:       d.clock,
+		recovery:    newRecoveryMachine(),
+		executions:  &executionCoordinator{},
+		minReopen:   cfg.Speedup.MinReopenInterval,
 		lastExecute: time.Time{},
 	}
 }
 
-// Execute 执行提速（带自动恢复）
+// AttachState 关联状态存储，恢复上次的执行、查询和重新开启提速的记录，之后的记录会写入存储
+// 恢复的重新开启提速时间使 min_reopen_interval 在重启后仍然生效
+func (s *SpeedupService) AttachState(store *state.Store) {
+	saved := store.Line(s.line)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.store = store
+	s.lastExecute = saved.LastExecute
+	s.lastQuery = saved.LastQuery
+	s.lastQueryAt = saved.LastQueryAt
+	s.lastRun = saved.LastRun
+	s.executions.recordReopen(saved.LastReopen)
+	if !saved.LastExecute.IsZero() {
+		s.logger.Info("已恢复上次提速成功时间: %s", saved.LastExecute.Format("2006-01-02 15:04:05"))
+	}
+}
+
+// Reload 应用新的线路配置
+// client 不为 nil 时替换提速客户端（IP 绑定配置变化时）
+func (s *SpeedupService) Reload(cfg *config.Config, client SpeedupAPI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if client != nil {
+		s.apiClient = client
+	}
+	s.config = &cfg.Speedup.AutoRecovery
+	s.selfCheck = &cfg.Speedup.SelfCheck
+	s.downAcc = cfg.Speedup.DownAcc
+	s.upAcc = cfg.Speedup.UpAcc
+	s.minReopen = cfg.Speedup.MinReopenInterval
+}
+
+// client 获取当前的提速客户端
+func (s *SpeedupService) client() SpeedupAPI {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiClient
+}
+
+// directions 获取是否关注下行和上行提速
+func (s *SpeedupService) directions() (downAcc, upAcc bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.downAcc, s.upAcc
+}
+
+// recoveryConfig 获取当前的自动恢复配置
+func (s *SpeedupService) recoveryConfig() *config.AutoRecoveryConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config
+}
+
+// SetNotifier 设置事件通知
+func (s *SpeedupService) SetNotifier(n notify.Notifier) {
+	s.notifier = n
+}
+
+// OnQuery 设置每次查询成功后的回调（用于按截止时间安排续期）
+func (s *SpeedupService) OnQuery(fn func(*api.SpeedupQueryResponse)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onQuery = fn
+}
+
+// stateStore 关联的状态存储，未关联时为 nil
+func (s *SpeedupService) stateStore() *state.Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store
+}
+
+// saveState 写入状态存储
+func (s *SpeedupService) saveState(fn func(*state.LineState)) {
+	store := s.stateStore()
+	if store == nil {
+		return
+	}
+
+	if err := store.Update(s.line, fn); err != nil {
+		s.logger.Warn("保存运行状态失败: %v", err)
+	}
+}
+
+// recoverableError 可以通过自动恢复重试的失败（接口请求失败）
+type recoverableError struct {
+	operation string
+	err       error
+}
+
+func (e *recoverableError) Error() string { return e.err.Error() }
+func (e *recoverableError) Unwrap() error { return e.err }
+
+// Execute 执行提速（带自动恢复），source 为触发来源
 // 对应 luci-app-broadbandacc 中的 isp_bandwidth 函数
-func (s *SpeedupService) Execute() error {
+// 同一时间只有一次提速在进行：已有提速在进行时不再重复调用接口，而是等待并返回那次提速的结果；
+// ctx 取消时只放弃等待，不影响正在进行的提速
+func (s *SpeedupService) Execute(ctx context.Context, source TriggerSource) error {
+	f, leader := s.executions.join(s.clock.Now(), source)
+	metrics.ExecuteTriggers.Inc(s.line, string(source), strconv.FormatBool(!leader))
+	if !leader {
+		s.logger.Event("execute_coalesced").With("source", string(source), "execution", f.record.ID).
+			Info("已有提速正在进行，合并到该次提速 (触发来源: %s)", source)
+		select {
+		case <-f.done:
+			return f.err
+		case <-ctx.Done():
+			return fmt.Errorf("等待提速结果已取消: %v", ctx.Err())
+		}
+	}
+
+	s.logger.Event("execute_started").With("source", string(source), "execution", f.record.ID).
+		Debug("开始第 %d 次提速 (触发来源: %s)", f.record.ID, source)
+	err := s.execute(ctx)
+	now := s.clock.Now()
+	s.executions.finish(f, now, err)
+	s.recordRun(source, f.record.Started, now, err)
+	return err
+}
+
+// recordRun 记录最近一次提速执行，写入状态存储后重启也能据此判断是否需要首次提速
+func (s *SpeedupService) recordRun(source TriggerSource, started, finished time.Time, err error) {
+	record := &state.RunRecord{Time: started, Finished: finished, Trigger: string(source), Success: err == nil}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	s.lastRun = record
+	s.mu.Unlock()
+	s.saveState(func(ls *state.LineState) {
+		copied := *record
+		ls.LastRun = &copied
+	})
+}
+
+// LastRun 获取最近一次提速执行的记录，没有执行过时返回 nil
+func (s *SpeedupService) LastRun() *state.RunRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastRun == nil {
+		return nil
+	}
+	copied := *s.lastRun
+	return &copied
+}
+
+// execute 执行提速并在接口请求失败时自动恢复
+// 接口请求失败时按自动恢复配置退避重试；ctx 取消时中止正在进行的请求和退避等待，不再发起后续重试
+func (s *SpeedupService) execute(ctx context.Context) error {
+	err := s.executeOnce(ctx)
+	if err == nil {
+		s.recovery.clear()
+		return nil
+	}
+
+	var re *recoverableError
+	if errors.As(err, &re) {
+		if ctx.Err() != nil {
+			// 请求因 ctx 取消而失败，不再自动恢复
+			return fmt.Errorf("%s已取消: %v", re.operation, ctx.Err())
+		}
+		return s.recover(ctx, re.operation, re.err)
+	}
+	return err
+}
+
+// executeOnce 执行一次提速流程（重新开启提速并查询状态），不做自动恢复
+// 接口请求失败时返回 *recoverableError
+func (s *SpeedupService) executeOnce(ctx context.Context) error {
 	s.logger.Info("开始执行提速操作...")
 
 	// 1. 先重新开启提速
 	s.logger.Debug("调用重新开启提速接口...")
-	reopenResp, err := s.apiClient.ReopenSpeedup()
+	start := time.Now()
+	reopenResp, err := s.Reopen(ctx)
 	if err != nil {
-		s.logger.Error("重新开启提速失败: %v", err)
-		return s.handleError(err, "重新开启提速")
+		s.logger.Event("reopen_failed").With("error", err.Error()).Error("重新开启提速失败: %v", err)
+		s.recordExecute(metrics.ResultFailure, "request_error")
+		return &recoverableError{operation: "重新开启提速", err: err}
 	}
 
 	// 检查重新开启提速的响应
+	reopenLogger := s.logger.Event("reopen").With("code", reopenResp.Code, "duration_ms", time.Since(start).Milliseconds())
 	if reopenResp.Code != 0 {
 		switch reopenResp.Code {
 		case 10021:
-			s.logger.Error("请求接口异常，请重启插件再试")
+			reopenLogger.Error("请求接口异常，请重启插件再试")
+			s.recordExecute(metrics.ResultFailure, strconv.Itoa(reopenResp.Code))
 			return fmt.Errorf("接口异常，错误码: %d", reopenResp.Code)
 		case 10002:
-			s.logger.Warn("操作过于频繁，接口提速已受理")
+			reopenLogger.Warn("操作过于频繁，接口提速已受理")
 		default:
-			s.logger.Warn("重新开启提速返回错误码: %d, 消息: %s", reopenResp.Code, reopenResp.Message)
+			reopenLogger.With("message", reopenResp.Message).Warn("重新开启提速返回错误码: %d, 消息: %s", reopenResp.Code, reopenResp.Message)
 		}
 	} else {
-		s.logger.Info("重新开启提速接口连接正常")
+		reopenLogger.Info("重新开启提速接口连接正常")
 	}
 
 	// 2. 查询提速状态
 	s.logger.Debug("查询提速状态...")
-	queryResp, err := s.apiClient.QuerySpeedupStatus()
+	queryResp, err := s.Query(ctx)
 	if err != nil {
-		s.logger.Error("查询提速状态失败: %v", err)
-		return s.handleError(err, "查询提速状态")
+		s.logger.Event("query_failed").With("error", err.Error()).Error("查询提速状态失败: %v", err)
+		s.recordExecute(metrics.ResultFailure, "query_error")
+		return &recoverableError{operation: "查询提速状态", err: err}
 	}
 
 	// 3. 解析提速信息并输出
 	s.parseAndLogSpeedupInfo(queryResp)
 
 	// 4. 检查提速是否成功
+	downAcc, upAcc := s.directions()
 	if !queryResp.IsSpeedupAvailable() {
 		// 如果CanSpeed为0，检查是否有有效的带宽数据
 		// 这可能表示已经处于提速状态
-		hasBandwidth := queryResp.Data.Download > 0 || queryResp.Data.TargetUpH > 0 || queryResp.Data.TargetUp100 > 0
+		hasBandwidth := (downAcc && queryResp.Data.Download > 0) ||
+			(upAcc && (queryResp.Data.TargetUpH > 0 || queryResp.Data.TargetUp100 > 0))
 
 		if hasBandwidth {
 			s.logger.Warn("当前已处于提速状态（CanSpeed=0但检测到带宽数据）")
 			s.logger.Info("可能原因：当前线路已提速，或接口返回CanSpeed=0表示无需重复提速")
 		} else {
-			s.logger.Error("网络不支持提速")
+			s.logger.Event("speedup_unsupported").With("ip", queryResp.Data.IP).Error("网络不支持提速")
+			s.recordExecute(metrics.ResultFailure, "unsupported")
 			return fmt.Errorf("网络不支持提速")
 		}
 	}
 
-	// 5. 检查上行和下行提速状态
-	upActive, err := queryResp.IsUpSpeedupActive()
+	// 5. 检查启用的方向的提速状态（down_acc / up_acc）
+	downActive, upActive, err := s.activeDirections(queryResp)
 	if err != nil {
-		s.logger.Error("检查上行提速状态失败: %v", err)
+		s.logger.Error("%v", err)
+		s.recordExecute(metrics.ResultFailure, "parse_error")
 		return err
 	}
 
-	downActive, err := queryResp.IsDownloadSpeedupActive()
-	if err != nil {
-		s.logger.Error("检查下行提速状态失败: %v", err)
-		return err
+	// 6. 输出提速结果（未启用的方向不输出）
+	resultLogger := s.logger.Event("speedup_status").With("ip", queryResp.Data.IP)
+	if upAcc {
+		upLogger := resultLogger.With("direction", "up", "active", upActive)
+		if upActive {
+			upLogger.Success("上行提速已激活")
+		} else {
+			upLogger.Warn("上行提速未激活")
+		}
 	}
 
-	// 6. 输出提速结果
-	if upActive {
-		s.logger.Success("上行提速已激活")
-	} else {
-		s.logger.Warn("上行提速未激活")
+	if downAcc {
+		downLogger := resultLogger.With("direction", "down", "active", downActive)
+		if downActive {
+			downLogger.Success("下行提速已激活")
+		} else {
+			downLogger.Warn("下行提速未激活")
+		}
 	}
 
-	if downActive {
-		s.logger.Success("下行提速已激活")
-	} else {
-		s.logger.Warn("下行提速未激活")
+	if !downAcc && !upAcc {
+		s.logger.Warn("下行和上行提速均未启用（down_acc / up_acc），跳过提速状态检查")
+	}
+
+	if upActive || downActive {
+		s.notifier.Notify(notify.NewEvent(notify.EventSpeedupActivated, s.line, "提速已激活",
+			fmt.Sprintf("出口 IP: %s，%s", queryResp.Data.IP, s.directionSummary(downActive, upActive))))
 	}
 
-	s.lastExecute = time.Now()
+	now := s.clock.Now()
+	s.mu.Lock()
+	s.lastExecute = now
+	s.mu.Unlock()
+	s.saveState(func(ls *state.LineState) { ls.LastExecute = now })
+	s.recordExecute(metrics.ResultSuccess, strconv.Itoa(reopenResp.Code))
 	return nil
 }
 
-// handleError 处理错误（带自动恢复）
-func (s *SpeedupService) handleError(err error, operation string) error {
-	if !s.config.Enabled {
-		s.logger.Error("%s失败，自动恢复未启用: %v", operation, err)
-		return err
+// recordExecute 记录一次提速尝试的结果（自动恢复的每次重试单独计数）
+func (s *SpeedupService) recordExecute(result, code string) {
+	metrics.ExecuteTotal.Inc(s.line, result, code)
+}
+
+// recover 自动恢复：按退避策略等待后重新执行提速流程，直到成功、重试次数用尽或 ctx 被取消
+// 重试在循环中进行，失败的重试不会再嵌套新的恢复流程；重试次数用尽后进入冷却期
+func (s *SpeedupService) recover(ctx context.Context, operation string, cause error) error {
+	recovery := s.recoveryConfig()
+	if !recovery.Enabled {
+		s.logger.Error("%s失败，自动恢复未启用: %v", operation, cause)
+		return cause
 	}
 
-	s.logger.Warn("%s失败，开始自动恢复流程 (最大重试次数: %d)", operation, s.config.MaxRetries)
+	if current, ok := s.recovery.start(s.clock.Now(), operation, recovery.MaxRetries, cause); !ok {
+		skipLogger := s.logger.Event("recovery_skipped").With("operation", operation, "state", string(current.State), "error", cause.Error())
+		if current.State == RecoveryCooldown {
+			skipLogger.Warn("%s失败，自动恢复冷却中，%s 后才会再次自动恢复", operation, current.CooldownUntil.Format("2006-01-02 15:04:05"))
+			return fmt.Errorf("%v（自动恢复冷却中）", cause)
+		}
+		skipLogger.Warn("%s失败，已有自动恢复流程在进行", operation)
+		return cause
+	}
 
-	for i := 1; i <= s.config.MaxRetries; i++ {
-		s.logger.Info("自动恢复尝试 %d/%d，等待 %v", i, s.config.MaxRetries, s.config.RetryInterval)
-		time.Sleep(s.config.RetryInterval)
+	s.logger.Event("recovery_started").With("operation", operation, "max_retries", recovery.MaxRetries, "backoff", recovery.Backoff, "error", cause.Error()).
+		Warn("%s失败，开始自动恢复流程 (最大重试次数: %d)", operation, recovery.MaxRetries)
+
+	lastErr := cause
+	for i := 1; i <= recovery.MaxRetries; i++ {
+		delay := backoffDelay(recovery, i)
+		s.recovery.backOff(i, s.clock.Now().Add(delay))
+		s.logger.Event("recovery_attempt").With("operation", operation, "attempt", i, "retry_interval", delay.String()).
+			Info("自动恢复尝试 %d/%d，等待 %v", i, recovery.MaxRetries, delay)
+		if err := s.clock.SleepContext(ctx, delay); err != nil {
+			s.recovery.reset()
+			s.logger.Event("recovery_canceled").With("operation", operation, "attempt", i).Warn("自动恢复已取消: %v", err)
+			return fmt.Errorf("自动恢复已取消: %v", err)
+		}
 
-		if err := s.Execute(); err == nil {
-			s.logger.Success("自动恢复成功")
+		s.recovery.attempt()
+		retryErr := s.executeOnce(ctx)
+		s.recordRecovery(operation, i, retryErr)
+		if retryErr == nil {
+			s.recovery.reset()
+			s.logger.Event("recovery_succeeded").With("operation", operation, "attempt", i).Success("自动恢复成功")
 			return nil
 		}
+		lastErr = retryErr
+		s.recovery.fail(retryErr)
+	}
+
+	s.recovery.exhaust(s.clock.Now(), recovery.Cooldown)
+	s.logger.Event("recovery_exhausted").With("operation", operation, "attempts", recovery.MaxRetries, "cooldown", recovery.Cooldown.String(), "error", lastErr.Error()).
+		Error("自动恢复失败，已达到最大重试次数: %v", lastErr)
+	s.notifier.Notify(notify.NewEvent(notify.EventRecoveryExhausted, s.line, "自动恢复失败",
+		fmt.Sprintf("%s失败，已重试 %d 次: %v", operation, recovery.MaxRetries, lastErr)))
+	return fmt.Errorf("自动恢复失败: %v", lastErr)
+}
+
+// RecoveryStatus 获取自动恢复的当前状态
+func (s *SpeedupService) RecoveryStatus() RecoveryStatus {
+	return s.recovery.Status(s.clock.Now())
+}
+
+// ExecutionStatus 获取正在进行的提速和最近的提速记录
+func (s *SpeedupService) ExecutionStatus() ExecutionStatus {
+	return s.executions.Status()
+}
+
+// activeDirections 检查启用的方向的提速是否激活，未启用的方向始终返回 false
+func (s *SpeedupService) activeDirections(resp *api.SpeedupQueryResponse) (downActive, upActive bool, err error) {
+	downAcc, upAcc := s.directions()
+	if downAcc {
+		if downActive, err = resp.IsDownloadSpeedupActiveAt(s.clock.Now()); err != nil {
+			return false, false, fmt.Errorf("检查下行提速状态失败: %v", err)
+		}
+	}
+	if upAcc {
+		if upActive, err = resp.IsUpSpeedupActiveAt(s.clock.Now()); err != nil {
+			return false, false, fmt.Errorf("检查上行提速状态失败: %v", err)
+		}
+	}
+	return downActive, upActive, nil
+}
+
+// isActive 启用的方向是否均已激活
+func (s *SpeedupService) isActive(downActive, upActive bool) bool {
+	downAcc, upAcc := s.directions()
+	return (!downAcc || downActive) && (!upAcc || upActive)
+}
+
+// directionSummary 启用的方向的提速状态描述
+func (s *SpeedupService) directionSummary(downActive, upActive bool) string {
+	downAcc, upAcc := s.directions()
+	var parts []string
+	if downAcc {
+		parts = append(parts, "下行提速: "+activeText(downActive))
+	}
+	if upAcc {
+		parts = append(parts, "上行提速: "+activeText(upActive))
+	}
+	return strings.Join(parts, "，")
+}
+
+// activeText 提速状态描述
+func activeText(active bool) string {
+	if active {
+		return "已激活"
+	}
+	return "未激活"
+}
+
+// recordRecovery 记录一次自动恢复尝试
+func (s *SpeedupService) recordRecovery(operation string, attempt int, err error) {
+	record := state.RecoveryAttempt{
+		Time:      s.clock.Now(),
+		Operation: operation,
+		Attempt:   attempt,
+		Success:   err == nil,
+	}
+	if err != nil {
+		record.Error = err.Error()
 	}
 
-	s.logger.Error("自动恢复失败，已达到最大重试次数: %v", err)
-	return fmt.Errorf("自动恢复失败: %v", err)
+	store := s.stateStore()
+	if store == nil {
+		return
+	}
+	if err := store.AddRecoveryAttempt(s.line, record); err != nil {
+		s.logger.Warn("保存运行状态失败: %v", err)
+	}
 }
 
 // parseAndLogSpeedupInfo 解析并记录提速信息
 func (s *SpeedupService) parseAndLogSpeedupInfo(resp *api.SpeedupQueryResponse) {
-	s.logger.Info("提速开始时间: %s", resp.Data.UpdatedAt)
-	s.logger.Info("出口IP地址: %s", resp.Data.IP)
+	logger := s.logger.Event("speedup_info").With("ip", resp.Data.IP, "can_speed", resp.Data.CanSpeed)
+	logger.Info("提速开始时间: %s", resp.Data.UpdatedAt)
+	logger.Info("出口IP地址: %s", resp.Data.IP)
+
+	downAcc, upAcc := s.directions()
+	expiries := resp.Expiries()
+	expiryLogger := func(kind string, bandwidthKey string, bandwidth int) *utils.Logger {
+		l := logger.With("kind", kind, bandwidthKey, bandwidth)
+		if expireTime, ok := expiries[kind]; ok {
+			l = l.With("expiry", expireTime)
+		}
+		return l
+	}
 
-	// 上行带宽信息
-	if resp.Data.TargetUpH > 0 {
-		s.logger.Info("一类上行带宽%dM提速截至时间: %s", resp.GetUpHBandwidth(), resp.Data.UpHExpire)
+	// 上行带宽信息（未启用上行提速时不输出）
+	if upAcc && resp.Data.TargetUpH > 0 {
+		expiryLogger(api.ExpiryUpH, "bandwidth_kbps", resp.Data.TargetUpH).
+			Info("一类上行带宽%dM提速截至时间: %s", resp.GetUpHBandwidth(), resp.Data.UpHExpire)
 	}
-	if resp.Data.TargetUp100 > 0 {
-		s.logger.Info("二类上行带宽%dM提速截至时间: %s", resp.GetUp100Bandwidth(), resp.Data.Up100Expire)
+	if upAcc && resp.Data.TargetUp100 > 0 {
+		expiryLogger(api.ExpiryUp100, "bandwidth_kbps", resp.Data.TargetUp100).
+			Info("二类上行带宽%dM提速截至时间: %s", resp.GetUp100Bandwidth(), resp.Data.Up100Expire)
 	}
 
-	// 下行带宽信息
-	if resp.Data.Download > 0 {
-		s.logger.Info("下行带宽%dM提速截至时间: %s", resp.Data.Download, resp.Data.DownExpire)
+	// 下行带宽信息（未启用下行提速时不输出）
+	if downAcc && resp.Data.Download > 0 {
+		expiryLogger(api.ExpiryDown, "bandwidth_mbps", resp.Data.Download).
+			Info("下行带宽%dM提速截至时间: %s", resp.Data.Download, resp.Data.DownExpire)
 	}
 
 	// 套餐信息
 	if resp.Data.DownUp50Expire != "" {
-		s.logger.Info("一类套餐带宽%dM上行+%dM下行提速截至时间: %s",
-			resp.GetUpHBandwidth(), resp.Data.Download, resp.Data.DownUp50Expire)
+		expiryLogger(api.ExpiryDownUp50, "bandwidth_mbps", resp.Data.Download).
+			Info("一类套餐带宽%dM上行+%dM下行提速截至时间: %s",
+				resp.GetUpHBandwidth(), resp.Data.Download, resp.Data.DownUp50Expire)
 	}
 	if resp.Data.DownUpExpire != "" {
-		s.logger.Info("二类套餐带宽%dM上行+%dM下行提速截至时间: %s",
-			resp.GetUp100Bandwidth(), resp.Data.Download, resp.Data.DownUpExpire)
+		expiryLogger(api.ExpiryDownUp, "bandwidth_mbps", resp.Data.Download).
+			Info("二类套餐带宽%dM上行+%dM下行提速截至时间: %s",
+				resp.GetUp100Bandwidth(), resp.Data.Download, resp.Data.DownUpExpire)
+	}
+}
+
+// Reopen 调用一次重新开启提速接口，不查询状态也不自动恢复
+// 距离上一次调用不足 min_reopen_interval 时先等待，避免接口返回“操作过于频繁”
+func (s *SpeedupService) Reopen(ctx context.Context) (*api.SpeedupReopenResponse, error) {
+	s.mu.Lock()
+	interval := s.minReopen
+	s.mu.Unlock()
+	if wait := s.executions.reopenWait(s.clock.Now(), interval); wait > 0 {
+		s.logger.Event("reopen_throttled").With("wait", wait.String()).
+			Info("距离上一次重新开启提速不足 %v，等待 %v", interval, wait)
+		if err := s.clock.SleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+	reopenAt := s.clock.Now()
+	s.executions.recordReopen(reopenAt)
+	s.saveState(func(ls *state.LineState) { ls.LastReopen = reopenAt })
+
+	start := time.Now()
+	resp, err := s.client().ReopenSpeedup(ctx)
+	observeDuration(s.line, metrics.EndpointReopen, start)
+	return resp, err
+}
+
+// Query 查询提速状态并记录最近一次的查询结果
+func (s *SpeedupService) Query(ctx context.Context) (*api.SpeedupQueryResponse, error) {
+	start := time.Now()
+	resp, err := s.client().QuerySpeedupStatus(ctx)
+	observeDuration(s.line, metrics.EndpointQuery, start)
+	if err != nil {
+		return nil, err
+	}
+	recordQueryMetrics(s.line, resp)
+
+	now := s.clock.Now()
+	s.mu.Lock()
+	s.lastQuery = resp
+	s.lastQueryAt = now
+	onQuery := s.onQuery
+	s.mu.Unlock()
+	s.saveState(func(ls *state.LineState) {
+		ls.LastQuery = resp
+		ls.LastQueryAt = now
+	})
+
+	if onQuery != nil {
+		onQuery(resp)
 	}
+	return resp, nil
 }
 
-// QueryStatus 查询提速状态
-func (s *SpeedupService) QueryStatus() (bool, error) {
-	resp, err := s.apiClient.QuerySpeedupStatus()
+// QueryStatus 查询提速状态，返回启用的方向（down_acc / up_acc）是否均已激活
+// 只有启用的方向失效才视为提速失效
+func (s *SpeedupService) QueryStatus(ctx context.Context) (bool, error) {
+	resp, err := s.Query(ctx)
 	if err != nil {
 		return false, err
 	}
-	return resp.IsSpeedupAvailable(), nil
+
+	_, _, active, err := s.CheckActive(resp)
+	return active, err
+}
+
+// CheckActive 根据查询结果检查启用的方向的提速状态
+// 未启用的方向始终返回 false，active 表示启用的方向均已激活
+func (s *SpeedupService) CheckActive(resp *api.SpeedupQueryResponse) (downActive, upActive, active bool, err error) {
+	downActive, upActive, err = s.activeDirections(resp)
+	if err != nil {
+		return false, false, false, err
+	}
+	return downActive, upActive, s.isActive(downActive, upActive), nil
+}
+
+// Directions 返回关注的提速方向（down_acc / up_acc）
+func (s *SpeedupService) Directions() (downAcc, upAcc bool) {
+	return s.directions()
+}
+
+// ExpiryKinds 返回启用的方向相关的截止时间类型
+func (s *SpeedupService) ExpiryKinds() []string {
+	return api.ExpiryKinds(s.directions())
+}
+
+// GetLastQuery 获取最近一次的查询结果及查询时间
+func (s *SpeedupService) GetLastQuery() (*api.SpeedupQueryResponse, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastQuery, s.lastQueryAt
+}
+
+// Rebind 重建提速客户端的拨号器（绑定接口地址变化后调用）
+func (s *SpeedupService) Rebind() {
+	s.logger.Info("绑定接口地址已变化，重建提速客户端连接")
+	s.client().Rebind()
 }
 
 // GetLastExecuteTime 获取上次执行时间
 func (s *SpeedupService) GetLastExecuteTime() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.lastExecute
 }
 
 // ShouldSelfCheck 检查是否应该执行自检
 func (s *SpeedupService) ShouldSelfCheck() bool {
-	if !s.selfCheck.Enabled {
+	s.mu.Lock()
+	selfCheck := s.selfCheck
+	s.mu.Unlock()
+
+	if !selfCheck.Enabled {
 		return false
 	}
 
-	if s.lastExecute.IsZero() {
+	lastExecute := s.GetLastExecuteTime()
+	if lastExecute.IsZero() {
 		return false
 	}
 
-	return time.Since(s.lastExecute) >= s.selfCheck.Interval
+	return s.clock.Now().Sub(lastExecute) >= selfCheck.Interval
 }
 
 // ExecuteSelfCheck 执行自检
-func (s *SpeedupService) ExecuteSelfCheck() error {
+func (s *SpeedupService) ExecuteSelfCheck(ctx context.Context) error {
 	s.logger.Info("开始执行 7 天自检...")
-	if err := s.Execute(); err != nil {
+	if err := s.Execute(ctx, TriggerSelfCheck); err != nil {
 		s.logger.Error("7 天自检失败: %v", err)
+		s.notifier.Notify(notify.NewEvent(notify.EventSelfCheck, s.line, "7 天自检失败", err.Error()))
 		return err
 	}
 	s.logger.Success("7 天自检完成")
+	s.notifier.Notify(notify.NewEvent(notify.EventSelfCheck, s.line, "7 天自检完成", "提速状态正常"))
 	return nil
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -8,9 +9,19 @@ import (
 	"speedtestup/config"
 )
 
+// newTestClient 创建真实的 speedtest.cn 客户端
+func newTestClient(t *testing.T, bindIP string) *api.SpeedTestCNClient {
+	t.Helper()
+	client, err := api.NewSpeedTestCNClient(bindIP)
+	if err != nil {
+		t.Fatalf("NewSpeedTestCNClient returned error: %v", err)
+	}
+	return client
+}
+
 func TestNewSpeedupService(t *testing.T) {
 	cfg := config.NewDefaultConfig()
-	speedTestCNClient := api.NewSpeedTestCNClient("192.168.1.1")
+	speedTestCNClient := newTestClient(t, "192.168.1.1")
 	speedupService := NewSpeedupService(speedTestCNClient, cfg)
 
 	if speedupService == nil {
@@ -33,7 +44,7 @@ func TestNewSpeedupService(t *testing.T) {
 func TestSpeedupService_ShouldSelfCheck(t *testing.T) {
 	cfg := config.NewDefaultConfig()
 	cfg.Speedup.SelfCheck.Enabled = true
-	speedTestCNClient := api.NewSpeedTestCNClient("")
+	speedTestCNClient := newTestClient(t, "")
 	speedupService := NewSpeedupService(speedTestCNClient, cfg)
 
 	// 初始状态下不应该执行自检
@@ -51,13 +62,13 @@ func TestSpeedupService_ShouldSelfCheck(t *testing.T) {
 // TestSpeedupService_QueryStatus 测试查询提速状态
 func TestSpeedupService_QueryStatus(t *testing.T) {
 	cfg := config.NewDefaultConfig()
-	speedTestCNClient := api.NewSpeedTestCNClient("")
+	speedTestCNClient := newTestClient(t, "")
 	speedupService := NewSpeedupService(speedTestCNClient, cfg)
 
 	// 测试QueryStatus方法返回布尔值和错误
 	// 注意：这里可能涉及网络请求，在测试环境中可能会失败
 	// 我们主要测试方法不会panic
-	canSpeed, err := speedupService.QueryStatus()
+	canSpeed, err := speedupService.QueryStatus(context.Background())
 	if err != nil {
 		t.Logf("Expected network error in test environment: %v", err)
 	} else {
@@ -68,7 +79,7 @@ func TestSpeedupService_QueryStatus(t *testing.T) {
 // TestSpeedupService_GetLastExecuteTime 测试获取最后执行时间
 func TestSpeedupService_GetLastExecuteTime(t *testing.T) {
 	cfg := config.NewDefaultConfig()
-	speedTestCNClient := api.NewSpeedTestCNClient("")
+	speedTestCNClient := newTestClient(t, "")
 	speedupService := NewSpeedupService(speedTestCNClient, cfg)
 
 	// 验证初始返回零时间
@@ -87,3 +98,57 @@ func TestSpeedupService_GetLastExecuteTime(t *testing.T) {
 		t.Errorf("Expected lastExecute to be %v, got %v", now, lastExecute)
 	}
 }
+
+// TestSpeedupService_Directions 测试 down_acc / up_acc 开关
+func TestSpeedupService_Directions(t *testing.T) {
+	resp := &api.SpeedupQueryResponse{}
+	resp.Data.DownExpireT = float64(time.Now().Add(time.Hour).Unix())
+	resp.Data.UpHExpireT = "false"
+	resp.Data.Up100ExpireT = "false"
+	resp.Data.DownUp50ExpireT = "false"
+	resp.Data.DownUpExpireT = "false"
+
+	cases := []struct {
+		name       string
+		downAcc    bool
+		upAcc      bool
+		wantDown   bool
+		wantUp     bool
+		wantActive bool
+	}{
+		{"both", true, true, true, false, false},
+		{"down only", true, false, true, false, true},
+		{"up only", false, true, false, false, false},
+		{"none", false, false, false, false, true},
+	}
+
+	for _, c := range cases {
+		cfg := config.NewDefaultConfig()
+		cfg.Speedup.DownAcc = c.downAcc
+		cfg.Speedup.UpAcc = c.upAcc
+		speedupService := NewSpeedupService(newTestClient(t, ""), cfg)
+
+		downActive, upActive, err := speedupService.activeDirections(resp)
+		if err != nil {
+			t.Fatalf("%s: activeDirections returned error: %v", c.name, err)
+		}
+		if downActive != c.wantDown || upActive != c.wantUp {
+			t.Errorf("%s: expected down=%v up=%v, got down=%v up=%v", c.name, c.wantDown, c.wantUp, downActive, upActive)
+		}
+		if active := speedupService.isActive(downActive, upActive); active != c.wantActive {
+			t.Errorf("%s: expected active=%v, got %v", c.name, c.wantActive, active)
+		}
+	}
+
+	// 未启用的方向不检查，也不会因无法解析而失败
+	resp.Data.UpHExpireT = true
+	cfg := config.NewDefaultConfig()
+	cfg.Speedup.UpAcc = false
+	speedupService := NewSpeedupService(newTestClient(t, ""), cfg)
+	if _, _, err := speedupService.activeDirections(resp); err != nil {
+		t.Errorf("Expected disabled upstream to be ignored, got %v", err)
+	}
+	if summary := speedupService.directionSummary(true, false); summary != "下行提速: 已激活" {
+		t.Errorf("Unexpected summary: %s", summary)
+	}
+}
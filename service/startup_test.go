@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"speedtestup/config"
+	"speedtestup/state"
+)
+
+// newStartupScheduler 创建使用虚拟时钟和模拟接口的调度器
+func newStartupScheduler(t *testing.T, cfg *config.Config, scenario string) (*Scheduler, *SpeedupService, *fakeSpeedupAPI, *recordHandler) {
+	t.Helper()
+	clock := newFakeClock()
+	logs := &recordHandler{}
+	opts := []Option{WithClock(clock), WithLogHandler(logs)}
+	fake := &fakeSpeedupAPI{query: queryResponse(t, scenario, clock)}
+	speedupService := NewSpeedupService(fake, cfg, opts...)
+	scheduler := NewScheduler(NewIPService(&fakeIPProvider{ip: "203.0.113.1"}, cfg, opts...), speedupService, cfg, opts...)
+	return scheduler, speedupService, fake, logs
+}
+
+func TestScheduler_StartupPolicy(t *testing.T) {
+	tests := []struct {
+		policy   string
+		scenario string
+		want     string
+		skipped  bool
+	}{
+		{policy: config.StartupAlways, scenario: "active", want: "reopen,query"},
+		{policy: config.StartupIfInactive, scenario: "active", want: "query", skipped: true},
+		{policy: config.StartupIfInactive, scenario: "expired", want: "query,reopen,query"},
+		{policy: config.StartupSkip, scenario: "active", want: "", skipped: true},
+	}
+
+	for _, tt := range tests {
+		cfg := config.NewDefaultConfig()
+		cfg.Speedup.Startup.Policy = tt.policy
+		scheduler, _, fake, logs := newStartupScheduler(t, cfg, tt.scenario)
+
+		if err := scheduler.Start(context.Background()); err != nil {
+			t.Fatalf("%s/%s: Start returned error: %v", tt.policy, tt.scenario, err)
+		}
+		scheduler.Stop(context.Background())
+
+		if got := fake.Calls(); got != tt.want {
+			t.Errorf("%s/%s: Expected calls %q, got %q", tt.policy, tt.scenario, tt.want, got)
+		}
+		if logs.Has("startup_skipped") != tt.skipped {
+			t.Errorf("%s/%s: Expected startup_skipped logged=%v", tt.policy, tt.scenario, tt.skipped)
+		}
+	}
+}
+
+// TestScheduler_StartupMinInterval 重启后根据状态文件中的上一次提速记录跳过首次提速
+func TestScheduler_StartupMinInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	cfg := config.NewDefaultConfig()
+
+	// 第一次启动执行提速，记录写入状态文件
+	store, err := state.Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	scheduler, speedupService, fake, _ := newStartupScheduler(t, cfg, "active")
+	speedupService.AttachState(store)
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	scheduler.Stop(context.Background())
+	if got := fake.Calls(); got != "reopen,query" {
+		t.Fatalf("Expected startup execute, got %q", got)
+	}
+
+	saved := store.Line(config.DefaultLineName)
+	if saved.LastRun == nil || saved.LastRun.Trigger != string(TriggerStartup) || !saved.LastRun.Success {
+		t.Fatalf("Expected a successful startup run to be saved, got %+v", saved.LastRun)
+	}
+	if saved.LastReopen.IsZero() {
+		t.Error("Expected last reopen to be saved")
+	}
+
+	// 不足 startup.min_interval 时重启，不再调用接口
+	store, err = state.Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	scheduler, speedupService, fake, logs := newStartupScheduler(t, cfg, "active")
+	speedupService.AttachState(store)
+	if !speedupService.ExecutionStatus().LastReopen.Equal(saved.LastReopen) {
+		t.Errorf("Expected last reopen %v to be restored, got %v", saved.LastReopen, speedupService.ExecutionStatus().LastReopen)
+	}
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	scheduler.Stop(context.Background())
+	if got := fake.Calls(); got != "" {
+		t.Errorf("Expected no API calls within startup.min_interval, got %q", got)
+	}
+	if !logs.Has("startup_skipped") {
+		t.Error("Expected startup_skipped log event")
+	}
+
+	// 超过 startup.min_interval 后重启正常执行
+	store.Update(config.DefaultLineName, func(ls *state.LineState) {
+		ls.LastRun.Time = ls.LastRun.Time.Add(-cfg.Speedup.Startup.MinInterval - time.Second)
+	})
+	scheduler, speedupService, fake, _ = newStartupScheduler(t, cfg, "active")
+	speedupService.AttachState(store)
+	if err := scheduler.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	scheduler.Stop(context.Background())
+	if got := fake.Calls(); got != "reopen,query" {
+		t.Errorf("Expected startup execute after startup.min_interval, got %q", got)
+	}
+}
@@ -1,16 +1,22 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"speedtestup/api"
 	"speedtestup/config"
+	"speedtestup/notify"
+	"speedtestup/server"
 	"speedtestup/service"
+	"speedtestup/state"
 	"speedtestup/utils"
+
+	"gopkg.in/yaml.v2"
 )
 
 // 版本信息变量（通过 LDFLAGS 设置）
@@ -24,8 +30,12 @@ func main() {
 	// 解析命令行参数
 	var configPath string
 	var showVersion bool
+	var checkConfig bool
 	flag.StringVar(&configPath, "config", "config.json", "配置文件路径")
 	flag.BoolVar(&showVersion, "version", false, "显示版本信息")
+	flag.BoolVar(&checkConfig, "check-config", false, "校验配置并输出合并默认值后的实际配置（YAML），然后退出")
+	flagOverrides := config.RegisterFlags(flag.CommandLine)
+	flag.Usage = usage
 	flag.Parse()
 
 	// 显示版本信息
@@ -37,81 +47,311 @@ func main() {
 		return
 	}
 
-	// 加载配置
-	cfg, err := config.LoadConfig(configPath)
+	// 不需要配置的子命令（如 mock-server）
+	if cmd := findSubcommand(flag.Arg(0)); cmd != nil && cmd.standalone != nil {
+		os.Exit(cmd.standalone(flag.Args()[1:]))
+	}
+
+	// 加载配置（默认值 < 配置文件 < 环境变量 < 命令行参数）
+	envOverrides, err := config.EnvOverrides(os.Environ())
+	if err != nil {
+		fmt.Printf("❌ 加载配置失败: %v\n", err)
+		os.Exit(1)
+	}
+	configPath = resolveConfigPath(configPath, flagPassed("config"))
+	cfg, err := config.Load(configPath, envOverrides, flagOverrides)
 	if err != nil {
 		fmt.Printf("❌ 加载配置失败: %v\n", err)
 		os.Exit(1)
 	}
 
+	// 校验配置，有任何问题都拒绝启动
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	if checkConfig {
+		printConfig(cfg)
+		return
+	}
+
+	// 一次性子命令，不要求启用提速服务
+	if flag.NArg() > 0 {
+		os.Exit(runSubcommand(cfg, flag.Arg(0), flag.Args()[1:]))
+	}
+
 	// 验证配置
-	if !cfg.Speedup.Enabled {
-		fmt.Println("❌ 提速服务未启用，请在 config.json 中设置 speedup.enabled = true")
+	if !cfg.AnyLineEnabled() {
+		fmt.Println("❌ 提速服务未启用，请在 config.json 中设置 speedup.enabled = true，或设置环境变量 SPEEDTESTUP_SPEEDUP_ENABLED=true")
 		os.Exit(1)
 	}
 
-	// 初始化日志
-	logger, err := utils.NewLogger(cfg.Logging.Level, cfg.Logging.Output, cfg.Logging.File)
+	os.Exit(serve(cfg, configPath, envOverrides, flagOverrides))
+}
+
+// serve 作为常驻服务运行，直到收到退出信号并完成关闭，返回进程的退出码
+// 退出前关闭日志文件和配置文件监视，失败时也会执行
+func serve(cfg *config.Config, configPath string, envOverrides, flagOverrides *config.Overrides) int {
+	// 初始化日志：日志文件只打开一次，所有组件共用，退出时关闭
+	sink, err := service.OpenLogSink(cfg)
 	if err != nil {
 		fmt.Printf("❌ 初始化日志失败: %v\n", err)
-		os.Exit(1)
+		return 1
+	}
+	defer sink.Close()
+	logger, err := service.NewLogger(cfg, sink)
+	if err != nil {
+		fmt.Printf("❌ 初始化日志失败: %v\n", err)
+		return 1
 	}
-	defer logger.Close()
+	logSink := service.WithLogSink(sink)
+
+	// 尽早注册 SIGHUP：启动阶段的首次提速可能因自动恢复持续数分钟，
+	// 未注册时 SIGHUP（如 logrotate 的 postrotate）会按默认处理方式终止进程
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	stopStartupHUP := watchStartupHUP(logger, sink, hup)
 
 	logger.Info("🚀 SpeedTestUp 宽带提速服务启动")
-	logger.Info("📋 配置信息:")
-	logger.Info("  - 提速服务: %v", cfg.Speedup.Enabled)
-	logger.Info("  - 下行提速: %v", cfg.Speedup.DownAcc)
-	logger.Info("  - 上行提速: %v", cfg.Speedup.UpAcc)
-	logger.Info("  - IP 绑定: %v", cfg.Speedup.IPBinding.Enabled)
-	logger.Info("  - 自动恢复: %v", cfg.Speedup.AutoRecovery.Enabled)
-	logger.Info("  - 7 天自检: %v", cfg.Speedup.SelfCheck.Enabled)
-	logger.Info("  - 日志记录: %v", cfg.Speedup.Logging)
-	logger.Info("  - 详细模式: %v", cfg.Speedup.Verbose)
-
-	// 初始化 API 客户端
-	ipAPI := api.NewIPAPI()
-	speedupAPI := api.NewSpeedTestCNClient(cfg.Speedup.IPBinding.BindIP)
-
-	// 初始化服务
-	ipService := service.NewIPService(ipAPI, cfg)
-	speedupService := service.NewSpeedupService(speedupAPI, cfg)
-	scheduler := service.NewScheduler(ipService, speedupService, cfg)
-
-	// 启动服务
-	if err := scheduler.Start(); err != nil {
+	for _, warning := range cfg.Warnings() {
+		logger.Warn("⚠️  %s", warning)
+	}
+	if configPath == "" {
+		logger.Info("📄 未使用配置文件，配置来自默认值、环境变量和命令行参数")
+	}
+	if n := envOverrides.Len() + flagOverrides.Len(); n > 0 {
+		logger.Info("📄 %d 个配置项由环境变量或命令行参数覆盖", n)
+	}
+	for _, line := range cfg.EffectiveLines() {
+		logger.Info("📋 线路 %s 配置信息:", line.Name)
+		logger.Info("  - 提速服务: %v", line.Enabled)
+		logger.Info("  - 下行提速: %v", line.DownAcc)
+		logger.Info("  - 上行提速: %v", line.UpAcc)
+		logger.Info("  - IP 绑定: %v", line.IPBinding.Enabled)
+		logger.Info("  - 自动恢复: %v", line.AutoRecovery.Enabled)
+		logger.Info("  - 7 天自检: %v", line.SelfCheck.Enabled)
+		logger.Info("  - 日志记录: %v", line.Logging)
+		logger.Info("  - 详细模式: %v", line.Verbose)
+	}
+
+	// 初始化各线路的 API 客户端和服务
+	manager, err := service.NewManager(cfg, logSink)
+	if err != nil {
+		logger.Error("❌ 初始化服务失败: %v", err)
+		return 1
+	}
+
+	// 初始化事件通知
+	notifier, err := service.NewNotifier(cfg, logSink)
+	if err != nil {
+		logger.Error("❌ 初始化通知失败: %v", err)
+		return 1
+	}
+	if notifier.Len() > 0 {
+		manager.SetNotifier(notifier)
+		logger.Info("🔔 已启用 %d 个通知渠道", notifier.Len())
+	}
+
+	// 恢复持久化的运行状态
+	if cfg.State.File != "" {
+		store, err := state.Open(cfg.State.File)
+		if err != nil {
+			logger.Error("❌ 加载状态文件失败: %v", err)
+			return 1
+		}
+		manager.AttachState(store)
+		logger.Info("💾 运行状态保存在 %s", store.Path())
+	}
+
+	// 启动服务：各线路启动时按 startup.policy 执行首次提速；
+	// 启动阶段收到 SIGINT / SIGTERM 时取消首次提速的请求，随后正常关闭
+	startCtx, stopStartup := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	if err := manager.Start(startCtx); err != nil {
 		logger.Error("❌ 启动服务失败: %v", err)
-		os.Exit(1)
+		return 1
 	}
 	logger.Info("✅ 服务启动成功")
+	interrupted := startCtx.Err() != nil
+	stopStartup()
+	if interrupted {
+		logger.Info("📴 启动过程中收到退出信号，正在优雅关闭...")
+		return shutdown(logger, manager, nil, notifier, cfg.Shutdown.Timeout)
+	}
 
-	// 执行首次提速检查
-	logger.Info("🔍 执行首次提速检查...")
-	if err := speedupService.Execute(); err != nil {
-		logger.Warn("⚠️  首次提速检查失败: %v", err)
-	} else {
-		logger.Info("✅ 首次提速检查完成")
+	// 启动 HTTP 状态与控制接口
+	var apiServer *server.Server
+	if cfg.Server.Enabled {
+		apiServer, err = server.New(cfg, manager, logger)
+		if err == nil {
+			err = apiServer.Start()
+		}
+		if err != nil {
+			logger.Error("❌ 启动 HTTP 接口失败: %v", err)
+			return 1
+		}
+	}
+
+	// 配置热重载：SIGHUP 或配置文件变化时重新加载
+	reloader := newReloader(configPath, cfg, logger, manager, envOverrides, flagOverrides)
+	if stopStartupHUP() {
+		reloader.reload("启动期间收到 SIGHUP")
+	}
+	if cfg.Reload.Watch && configPath != "" {
+		watcher := config.NewWatcher(configPath, cfg.Reload.Interval)
+		watcher.Start(func() { reloader.reload("配置文件已变化") })
+		defer watcher.Stop()
+		logger.Info("👀 正在监视配置文件 %s 的变化", configPath)
 	}
 
 	// 等待退出信号
-	waitForShutdown(logger, scheduler)
+	return waitForShutdown(logger, sink, manager, apiServer, notifier, reloader, hup)
+}
+
+// usage 输出命令行用法
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "用法: %s [参数] [命令] [命令参数]\n\n", os.Args[0])
+	printSubcommands(w)
+	fmt.Fprintln(w, "\n参数:")
+	flag.PrintDefaults()
+}
+
+// resolveConfigPath 确定配置文件路径
+// 优先使用 -config 参数，其次是 SPEEDTESTUP_CONFIG 环境变量；
+// 都未指定且默认的配置文件不存在时返回空字符串，即只使用默认值、环境变量和命令行参数
+func resolveConfigPath(path string, explicit bool) string {
+	if explicit {
+		return path
+	}
+	if env := os.Getenv(config.EnvConfigPath); env != "" {
+		return env
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return ""
+	}
+	return path
+}
+
+// flagPassed 检查命令行参数是否被显式设置
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
+
+// printConfig 以 YAML 格式输出实际生效的配置（已合并默认值和线路继承）
+func printConfig(cfg *config.Config) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		fmt.Printf("❌ 输出配置失败: %v\n", err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(data)
+	for _, warning := range cfg.Warnings() {
+		fmt.Fprintf(os.Stderr, "⚠️  %s\n", warning)
+	}
+	fmt.Fprintln(os.Stderr, "✅ 配置校验通过")
+}
+
+// watchStartupHUP 在启动完成前处理 SIGHUP
+// 收到时立即重新打开日志文件，配置的重新加载推迟到启动完成后；
+// 返回的函数停止处理，并报告期间是否收到过 SIGHUP
+func watchStartupHUP(logger *utils.Logger, sink *utils.LogSink, hup <-chan os.Signal) func() bool {
+	done := make(chan struct{})
+	received := make(chan bool, 1)
+	go func() {
+		pending := false
+		for {
+			select {
+			case <-hup:
+				pending = true
+				reopenLogFiles(logger, sink)
+				logger.Info("🔄 启动尚未完成，配置将在启动完成后重新加载")
+			case <-done:
+				received <- pending
+				return
+			}
+		}
+	}()
+	return func() bool {
+		close(done)
+		return <-received
+	}
+}
+
+// reopenLogFiles 重新打开日志文件（配合外部 logrotate）
+func reopenLogFiles(logger *utils.Logger, sink *utils.LogSink) {
+	if err := sink.Reopen(); err != nil {
+		logger.Error("❌ %v", err)
+	} else {
+		logger.Info("🔄 已重新打开日志文件")
+	}
 }
 
 // waitForShutdown 等待退出信号并优雅关闭
-func waitForShutdown(logger *utils.Logger, scheduler *service.Scheduler) {
+// 收到 SIGHUP 时重新打开日志文件（配合外部 logrotate）并重新加载配置，不退出
+func waitForShutdown(logger *utils.Logger, sink *utils.LogSink, manager *service.Manager, apiServer *server.Server, notifier *notify.Dispatcher, reloader *reloader, hup <-chan os.Signal) int {
 	// 创建信号通道
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	// 等待信号
-	sig := <-sigChan
+	var sig os.Signal
+	for sig == nil {
+		select {
+		case <-hup:
+			reopenLogFiles(logger, sink)
+			reloader.reload("收到 SIGHUP")
+		case sig = <-sigChan:
+		}
+	}
 	logger.Info("📴 收到信号 %v，正在优雅关闭...", sig)
+	return shutdown(logger, manager, apiServer, notifier, reloader.config().Shutdown.Timeout)
+}
 
-	// 关闭调度器
-	if err := scheduler.Stop(); err != nil {
+// notifyGracePeriod 关闭超时后等待通知发送的最长时间
+const notifyGracePeriod = 5 * time.Second
+
+// shutdown 关闭 HTTP 接口和所有线路的调度器，并等待已发出的通知发送完成，返回进程的退出码
+// 正在进行的请求和自动恢复的等待会被取消，整个过程最多等待 timeout；
+// 调度器未能在截止时间内停止时仍会等待通知发送，以便送出失败通知和最后的日志
+func shutdown(logger *utils.Logger, manager *service.Manager, apiServer *server.Server, notifier *notify.Dispatcher, timeout time.Duration) int {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// 关闭 HTTP 接口
+	if apiServer != nil {
+		if err := apiServer.Shutdown(ctx); err != nil {
+			logger.Warn("⚠️  关闭 HTTP 接口失败: %v", err)
+		}
+	}
+
+	// 关闭所有线路的调度器
+	code := 0
+	if err := manager.Stop(ctx); err != nil {
 		logger.Error("❌ 关闭服务失败: %v", err)
-		os.Exit(1)
+		code = 1
 	}
 
-	logger.Info("✅ 服务已优雅关闭")
+	// 等待已发出的通知发送完成，与关闭共用同一个截止时间；
+	// 截止时间已过（调度器未能按时停止）时另外等待 notifyGracePeriod，避免丢失失败通知
+	notifyCtx := ctx
+	if ctx.Err() != nil {
+		var cancelNotify context.CancelFunc
+		notifyCtx, cancelNotify = context.WithTimeout(context.Background(), notifyGracePeriod)
+		defer cancelNotify()
+	}
+	if err := notifier.Wait(notifyCtx); err != nil {
+		logger.Warn("⚠️  等待通知发送超时: %v", err)
+	}
+
+	if code == 0 {
+		logger.Info("✅ 服务已优雅关闭")
+	}
+	return code
 }
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"io"
+	"os"
+	"syscall"
 	"testing"
 	"time"
 
@@ -91,4 +94,20 @@ func TestAutoRecoveryConfig(t *testing.T) {
 	assert.True(t, cfg.Speedup.AutoRecovery.Enabled, "Auto recovery should be enabled by default")
 	assert.Equal(t, 3, cfg.Speedup.AutoRecovery.MaxRetries, "Default max retries should be 3")
 	assert.Equal(t, 5*time.Minute, cfg.Speedup.AutoRecovery.RetryInterval, "Default retry interval should be 5 minutes")
-}
\ No newline at end of file
+}
+
+// 测试启动阶段收到的 SIGHUP 会推迟到启动完成后重新加载
+func TestWatchStartupHUP(t *testing.T) {
+	logger, err := utils.NewLogger("error", "stdout", "")
+	assert.NoError(t, err)
+
+	hup := make(chan os.Signal, 1)
+	stop := watchStartupHUP(logger, utils.NewWriterSink(io.Discard), hup)
+	assert.False(t, stop(), "No reload should be pending without SIGHUP")
+
+	hup = make(chan os.Signal, 1)
+	stop = watchStartupHUP(logger, utils.NewWriterSink(io.Discard), hup)
+	hup <- syscall.SIGHUP
+	assert.Eventually(t, func() bool { return len(hup) == 0 }, time.Second, time.Millisecond)
+	assert.True(t, stop(), "SIGHUP received during startup should queue a reload")
+}
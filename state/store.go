@@ -0,0 +1,169 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"speedtestup/api"
+)
+
+// MaxRecoveryHistory 每条线路保留的自动恢复记录条数
+const MaxRecoveryHistory = 50
+
+// State 持久化的运行状态
+type State struct {
+	Lines map[string]*LineState `json:"lines"`
+}
+
+// LineState 单条线路的运行状态
+type LineState struct {
+	LastIPv4        string                    `json:"last_ipv4,omitempty"`
+	LastIPv6        string                    `json:"last_ipv6,omitempty"`
+	LastInterfaceIP string                    `json:"last_interface_ip,omitempty"`
+	LastExecute     time.Time                 `json:"last_execute"`
+	LastQuery       *api.SpeedupQueryResponse `json:"last_query,omitempty"`
+	LastQueryAt     time.Time                 `json:"last_query_at"`
+	LastReopen      time.Time                 `json:"last_reopen"`
+	LastRun         *RunRecord                `json:"last_run,omitempty"`
+	RecoveryHistory []RecoveryAttempt         `json:"recovery_history,omitempty"`
+}
+
+// RunRecord 最近一次提速执行的记录
+type RunRecord struct {
+	Time     time.Time `json:"time"`     // 开始时间
+	Finished time.Time `json:"finished"` // 结束时间
+	Trigger  string    `json:"trigger"`  // 触发来源
+	Success  bool      `json:"success"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// RecoveryAttempt 一次自动恢复尝试的记录
+type RecoveryAttempt struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"` // 触发自动恢复的操作
+	Attempt   int       `json:"attempt"`   // 第几次尝试
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Store 基于 JSON 文件的状态存储
+// 每次更新都会写入临时文件后重命名，保证文件始终完整
+type Store struct {
+	path string
+	data State
+	mu   sync.Mutex
+}
+
+// Open 打开状态文件，文件不存在时返回空状态
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path: path,
+		data: State{Lines: make(map[string]*LineState)},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("读取状态文件失败: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &s.data); err != nil {
+		return nil, fmt.Errorf("解析状态文件失败: %v", err)
+	}
+	if s.data.Lines == nil {
+		s.data.Lines = make(map[string]*LineState)
+	}
+	return s, nil
+}
+
+// Path 返回状态文件路径
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Line 获取线路状态的副本，线路不存在时返回零值
+func (s *Store) Line(name string) LineState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ls, ok := s.data.Lines[name]
+	if !ok {
+		return LineState{}
+	}
+	copied := *ls
+	if ls.LastRun != nil {
+		lastRun := *ls.LastRun
+		copied.LastRun = &lastRun
+	}
+	copied.RecoveryHistory = append([]RecoveryAttempt(nil), ls.RecoveryHistory...)
+	return copied
+}
+
+// Update 修改线路状态并写入文件
+func (s *Store) Update(name string, fn func(*LineState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ls, ok := s.data.Lines[name]
+	if !ok {
+		ls = &LineState{}
+		s.data.Lines[name] = ls
+	}
+	fn(ls)
+
+	if n := len(ls.RecoveryHistory); n > MaxRecoveryHistory {
+		ls.RecoveryHistory = append([]RecoveryAttempt(nil), ls.RecoveryHistory[n-MaxRecoveryHistory:]...)
+	}
+
+	return s.saveLocked()
+}
+
+// AddRecoveryAttempt 追加一条自动恢复记录
+func (s *Store) AddRecoveryAttempt(name string, attempt RecoveryAttempt) error {
+	return s.Update(name, func(ls *LineState) {
+		ls.RecoveryHistory = append(ls.RecoveryHistory, attempt)
+	})
+}
+
+// saveLocked 原子写入状态文件（调用方需持有锁）
+func (s *Store) saveLocked() error {
+	data, err := json.MarshalIndent(&s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化状态失败: %v", err)
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建状态目录失败: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时状态文件失败: %v", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // 重命名成功后删除不会生效
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入状态文件失败: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("同步状态文件失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入状态文件失败: %v", err)
+	}
+
+	if err := os.Rename(tmpName, s.path); err != nil {
+		return fmt.Errorf("替换状态文件失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"speedtestup/api"
+)
+
+func TestStore_OpenMissingFile(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	if ls := store.Line("default"); !ls.LastExecute.IsZero() || ls.LastIPv4 != "" {
+		t.Errorf("Expected empty line state, got %+v", ls)
+	}
+}
+
+func TestStore_UpdateAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "state.json")
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	executed := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	query := &api.SpeedupQueryResponse{}
+	query.Data.Download = 1000
+	query.Data.DownExpireT = float64(1700000000)
+
+	err = store.Update("telecom", func(ls *LineState) {
+		ls.LastIPv4 = "203.0.113.1"
+		ls.LastExecute = executed
+		ls.LastQuery = query
+	})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if err := store.AddRecoveryAttempt("telecom", RecoveryAttempt{Operation: "查询提速状态", Attempt: 1, Error: "timeout"}); err != nil {
+		t.Fatalf("AddRecoveryAttempt returned error: %v", err)
+	}
+
+	// 目录中不应残留临时文件
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the state file, got %d entries", len(entries))
+	}
+
+	reloaded, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	ls := reloaded.Line("telecom")
+	if ls.LastIPv4 != "203.0.113.1" {
+		t.Errorf("Expected LastIPv4 203.0.113.1, got %s", ls.LastIPv4)
+	}
+	if !ls.LastExecute.Equal(executed) {
+		t.Errorf("Expected LastExecute %v, got %v", executed, ls.LastExecute)
+	}
+	if ls.LastQuery == nil || ls.LastQuery.Data.Download != 1000 {
+		t.Errorf("Expected last query to be restored, got %+v", ls.LastQuery)
+	}
+	if len(ls.RecoveryHistory) != 1 || ls.RecoveryHistory[0].Error != "timeout" {
+		t.Errorf("Unexpected recovery history: %+v", ls.RecoveryHistory)
+	}
+}
+
+func TestStore_RecoveryHistoryLimit(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+
+	for i := 1; i <= MaxRecoveryHistory+5; i++ {
+		if err := store.AddRecoveryAttempt("default", RecoveryAttempt{Attempt: i}); err != nil {
+			t.Fatalf("AddRecoveryAttempt returned error: %v", err)
+		}
+	}
+
+	history := store.Line("default").RecoveryHistory
+	if len(history) != MaxRecoveryHistory {
+		t.Fatalf("Expected %d records, got %d", MaxRecoveryHistory, len(history))
+	}
+	if history[0].Attempt != 6 {
+		t.Errorf("Expected oldest records to be dropped, first attempt is %d", history[0].Attempt)
+	}
+}
+
+func TestStore_OpenInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{invalid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(path); err == nil {
+		t.Error("Expected error for invalid state file")
+	}
+}
@@ -1,20 +1,25 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
-	"os"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
 )
 
 // Logger 日志工具
+// 基于 log/slog，支持保持原有格式的文本输出和结构化的 JSON 输出
 type Logger struct {
-	logger  *log.Logger
-	prefix  string
-	level   string
-	output  string
-	file    string
-	fileHandle io.Writer
+	handler   slog.Handler
+	levels    *levelConfig
+	line      string
+	component string
+	event     string
+	fields    []any
+	owned     *LogSink // 日志器自己打开的输出目标，Close 时关闭
 }
 
 // LogLevel 日志级别常量
@@ -25,88 +30,237 @@ const (
 	LevelError = "error"
 )
 
+// 日志格式常量
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// LevelSuccess 成功日志的级别
+// 介于 WARN 和 ERROR 之间：日志级别为 warn 时仍会输出，为 error 时不输出
+const LevelSuccess = slog.LevelWarn + 1
+
+// LoggerOptions 日志配置
+type LoggerOptions struct {
+	Level           string            // 默认日志级别（debug, info, warn, error）
+	Output          string            // 输出方式（stdout, stderr, file）
+	File            string            // 日志文件路径
+	Format          string            // 输出格式（text, json）
+	ComponentLevels map[string]string // 按组件覆盖日志级别，如 {"Scheduler": "debug"}
+	Rotate          RotateOptions     // 日志文件轮转配置
+	Sink            *LogSink          // 共用的输出目标，设置后忽略 Output、File 和 Rotate，日志器关闭时不关闭它
+}
+
+// levelConfig 默认日志级别及按组件覆盖的级别
+type levelConfig struct {
+	defaultLevel slog.Level
+	components   map[string]slog.Level
+}
+
+// forComponent 获取组件的日志级别
+func (c *levelConfig) forComponent(component string) slog.Level {
+	if level, ok := c.components[component]; ok {
+		return level
+	}
+	return c.defaultLevel
+}
+
+// ParseLevel 解析日志级别
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case LevelDebug:
+		return slog.LevelDebug, nil
+	case LevelInfo, "":
+		return slog.LevelInfo, nil
+	case LevelWarn, "warning":
+		return slog.LevelWarn, nil
+	case LevelError:
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("未知的日志级别: %s", level)
+	}
+}
+
 // NewLogger 创建新的日志实例
 // 参数：level, output, file
 func NewLogger(level, output, file string) (*Logger, error) {
-	var outputWriter io.Writer
+	return NewLoggerWithOptions(LoggerOptions{Level: level, Output: output, File: file})
+}
 
-	// 设置输出位置
-	if output == "file" && file != "" {
-		fileHandle, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+// NewLoggerWithOptions 根据日志配置创建日志实例
+func NewLoggerWithOptions(opts LoggerOptions) (*Logger, error) {
+	levels := &levelConfig{components: make(map[string]slog.Level, len(opts.ComponentLevels))}
+
+	defaultLevel, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	levels.defaultLevel = defaultLevel
+
+	for component, level := range opts.ComponentLevels {
+		l, err := ParseLevel(level)
 		if err != nil {
-			return nil, fmt.Errorf("打开日志文件失败: %v", err)
+			return nil, fmt.Errorf("组件 %s: %v", component, err)
+		}
+		levels.components[component] = l
+	}
+
+	format := strings.ToLower(opts.Format)
+	if format != FormatText && format != FormatJSON && format != "" {
+		return nil, fmt.Errorf("未知的日志格式: %s", opts.Format)
+	}
+
+	// 设置输出位置，未指定共用的输出目标时自己打开
+	var owned *LogSink
+	sink := opts.Sink
+	if sink == nil {
+		if sink, err = OpenLogSink(opts.Output, opts.File, opts.Rotate); err != nil {
+			return nil, err
 		}
-		outputWriter = fileHandle
-	} else {
-		outputWriter = os.Stdout
+		owned = sink
 	}
 
 	// 设置日志格式
-	logger := log.New(outputWriter, "", log.Ldate|log.Ltime|log.Lshortfile)
-
-	l := &Logger{
-		logger:  logger,
-		prefix:  "",
-		level:   level,
-		output:  output,
-		file:    file,
-		fileHandle: outputWriter,
+	var handler slog.Handler = NewTextHandler(sink)
+	if format == FormatJSON {
+		handler = NewJSONHandler(sink)
 	}
 
-	return l, nil
+	return &Logger{
+		handler: handler,
+		levels:  levels,
+		owned:   owned,
+	}, nil
+}
+
+// NewHandlerLogger 创建输出到指定处理器的日志实例
+// 所有级别的日志都交给处理器，可用于接入其他日志系统或在测试中记录日志
+func NewHandlerLogger(handler slog.Handler) *Logger {
+	return &Logger{
+		handler: handler,
+		levels:  &levelConfig{defaultLevel: slog.LevelDebug},
+	}
+}
+
+// NewJSONHandler 创建 JSON 格式的日志处理器
+// 级别过滤由 Logger 完成，处理器输出所有级别
+func NewJSONHandler(w io.Writer) slog.Handler {
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.LevelKey && len(groups) == 0 {
+				if level, ok := a.Value.Any().(slog.Level); ok {
+					a.Value = slog.StringValue(levelName(level))
+				}
+			}
+			return a
+		},
+	})
+}
+
+// levelName 日志级别名称
+func levelName(level slog.Level) string {
+	if level == LevelSuccess {
+		return "SUCCESS"
+	}
+	return level.String()
 }
 
 // Debug 输出调试日志
 func (l *Logger) Debug(format string, args ...interface{}) {
-	if l.level == LevelDebug {
-		l.logger.Printf(l.prefix+"[DEBUG] "+format, args...)
-	}
+	l.log(slog.LevelDebug, format, args...)
 }
 
 // Info 输出信息日志
 func (l *Logger) Info(format string, args ...interface{}) {
-	if l.level == LevelDebug || l.level == LevelInfo {
-		l.logger.Printf(l.prefix+"[INFO] "+format, args...)
-	}
+	l.log(slog.LevelInfo, format, args...)
 }
 
 // Warn 输出警告日志
 func (l *Logger) Warn(format string, args ...interface{}) {
-	if l.level == LevelDebug || l.level == LevelInfo || l.level == LevelWarn {
-		l.logger.Printf(l.prefix+"[WARN] "+format, args...)
-	}
+	l.log(slog.LevelWarn, format, args...)
 }
 
 // Error 输出错误日志
 func (l *Logger) Error(format string, args ...interface{}) {
-	l.logger.Printf(l.prefix+"[ERROR] "+format, args...)
+	l.log(slog.LevelError, format, args...)
 }
 
 // Success 输出成功日志
 func (l *Logger) Success(format string, args ...interface{}) {
-	if l.level == LevelDebug || l.level == LevelInfo || l.level == LevelWarn {
-		l.logger.Printf(l.prefix+"[SUCCESS] "+format, args...)
+	l.log(LevelSuccess, format, args...)
+}
+
+// Enabled 检查指定级别的日志是否会输出
+func (l *Logger) Enabled(level slog.Level) bool {
+	return level >= l.levels.forComponent(l.component)
+}
+
+// log 构造日志记录并交给处理器输出
+func (l *Logger) log(level slog.Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	// 跳过 runtime.Callers、log 和 Debug/Info 等方法，记录调用方位置
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+
+	record := slog.NewRecord(time.Now(), level, fmt.Sprintf(format, args...), pcs[0])
+	if l.line != "" {
+		record.AddAttrs(slog.String("line", l.line))
 	}
+	if l.component != "" {
+		record.AddAttrs(slog.String("component", l.component))
+	}
+	if l.event != "" {
+		record.AddAttrs(slog.String("event", l.event))
+	}
+	record.Add(l.fields...)
+
+	_ = l.handler.Handle(context.Background(), record)
 }
 
-// WithPrefix 设置日志前缀
+// clone 复制日志实例
+func (l *Logger) clone() *Logger {
+	c := *l
+	c.fields = append([]any(nil), l.fields...)
+	return &c
+}
+
+// WithPrefix 设置日志前缀（即组件名称）
 func (l *Logger) WithPrefix(prefix string) *Logger {
-	return &Logger{
-		logger:  l.logger,
-		prefix:  l.prefix + "[" + prefix + "] ",
-		level:   l.level,
-		output:  l.output,
-		file:    l.file,
-		fileHandle: l.fileHandle,
-	}
+	c := l.clone()
+	c.component = prefix
+	return c
 }
 
-// Close 关闭日志文件
+// WithLine 设置线路名称
+func (l *Logger) WithLine(line string) *Logger {
+	c := l.clone()
+	c.line = line
+	return c
+}
+
+// Event 设置事件名称（JSON 格式中输出为 event 字段）
+func (l *Logger) Event(name string) *Logger {
+	c := l.clone()
+	c.event = name
+	return c
+}
+
+// With 添加结构化字段，参数为键值对，如 With("ip", ip, "code", code)
+// 文本格式不输出这些字段
+func (l *Logger) With(args ...any) *Logger {
+	c := l.clone()
+	c.fields = append(c.fields, args...)
+	return c
+}
+
+// Close 关闭日志器自己打开的日志文件，共用的输出目标由其创建者关闭
 func (l *Logger) Close() error {
-	if l.fileHandle != nil {
-		if file, ok := l.fileHandle.(*os.File); ok {
-			return file.Close()
-		}
+	if l.owned == nil {
+		return nil
 	}
-	return nil
+	return l.owned.Close()
 }
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// newBufferLogger 创建输出到缓冲区的日志器
+func newBufferLogger(t *testing.T, format, level string, components map[string]string) (*Logger, *bytes.Buffer) {
+	t.Helper()
+	logger, err := NewLoggerWithOptions(LoggerOptions{Level: level, Format: format, ComponentLevels: components})
+	if err != nil {
+		t.Fatalf("NewLoggerWithOptions returned error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatJSON:
+		logger.handler = NewJSONHandler(&buf)
+	default:
+		logger.handler = NewTextHandler(&buf)
+	}
+	return logger, &buf
+}
+
+func TestLogger_TextFormat(t *testing.T) {
+	logger, buf := newBufferLogger(t, FormatText, LevelInfo, nil)
+
+	logger.WithLine("telecom").WithPrefix("SpeedupService").With("ip", "203.0.113.1").Success("下行提速已激活")
+	logger.Debug("不应输出")
+
+	pattern := regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} logger_test\.go:\d+: \[telecom\] \[SpeedupService\] \[SUCCESS\] 下行提速已激活\n$`)
+	if !pattern.MatchString(buf.String()) {
+		t.Errorf("Unexpected text output: %q", buf.String())
+	}
+}
+
+func TestLogger_JSONFormat(t *testing.T) {
+	logger, buf := newBufferLogger(t, FormatJSON, LevelInfo, nil)
+
+	logger.WithPrefix("SpeedupService").Event("reopen").With("code", 10002, "ip", "203.0.113.1").Warn("操作过于频繁")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to decode JSON log %q: %v", buf.String(), err)
+	}
+	expected := map[string]interface{}{
+		"level":     "WARN",
+		"msg":       "操作过于频繁",
+		"component": "SpeedupService",
+		"event":     "reopen",
+		"code":      float64(10002),
+		"ip":        "203.0.113.1",
+	}
+	for key, want := range expected {
+		if entry[key] != want {
+			t.Errorf("Expected %s=%v, got %v", key, want, entry[key])
+		}
+	}
+
+	buf.Reset()
+	logger.Success("成功")
+	if !strings.Contains(buf.String(), `"level":"SUCCESS"`) {
+		t.Errorf("Expected SUCCESS level, got %s", buf.String())
+	}
+}
+
+func TestLogger_ComponentLevels(t *testing.T) {
+	logger, buf := newBufferLogger(t, FormatText, LevelWarn, map[string]string{"Scheduler": "debug"})
+
+	logger.WithPrefix("Scheduler").Debug("调度器调试")
+	logger.WithPrefix("IPService").Info("IP 服务信息")
+	logger.WithPrefix("IPService").Success("IP 服务成功")
+
+	out := buf.String()
+	if !strings.Contains(out, "调度器调试") {
+		t.Errorf("Expected Scheduler debug log, got %q", out)
+	}
+	if strings.Contains(out, "IP 服务信息") {
+		t.Errorf("Expected IPService info log to be filtered, got %q", out)
+	}
+	// warn 级别仍输出成功日志
+	if !strings.Contains(out, "IP 服务成功") {
+		t.Errorf("Expected success log at warn level, got %q", out)
+	}
+	if logger.WithPrefix("IPService").Enabled(slog.LevelInfo) {
+		t.Error("Expected info to be disabled for IPService")
+	}
+}
+
+func TestNewLoggerWithOptions_Invalid(t *testing.T) {
+	if _, err := NewLoggerWithOptions(LoggerOptions{Level: "verbose"}); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+	if _, err := NewLoggerWithOptions(LoggerOptions{Format: "xml"}); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+	if _, err := NewLoggerWithOptions(LoggerOptions{ComponentLevels: map[string]string{"Scheduler": "loud"}}); err == nil {
+		t.Error("Expected error for unknown component level")
+	}
+}
@@ -0,0 +1,289 @@
+package utils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RotateOptions 日志文件轮转配置
+type RotateOptions struct {
+	MaxSize    int64         // 单个文件的最大字节数（0 表示不按大小轮转）
+	MaxAge     time.Duration // 单个文件的最长写入时间（0 表示不按时间轮转）
+	MaxBackups int           // 保留的历史文件数量（0 表示全部保留）
+	Compress   bool          // 是否使用 gzip 压缩历史文件
+}
+
+// backupTimeFormat 历史文件名中的时间格式
+const backupTimeFormat = "20060102-150405"
+
+// backupPattern 匹配历史文件名的后缀，如 .20240101-150405、.20240101-150405.1.gz
+var backupPattern = regexp.MustCompile(`^\.\d{8}-\d{6}(\.\d+)?(\.gz)?$`)
+
+// RotatingFile 支持按大小和时间轮转的日志文件
+// 轮转时当前文件重命名为 <文件名>.<时间>，并按配置压缩和清理历史文件；
+// 压缩在后台进行，不阻塞日志写入
+type RotatingFile struct {
+	path     string
+	opts     RotateOptions
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+	mu       sync.Mutex
+
+	toCompress  []string       // 等待压缩的历史文件，按轮转顺序
+	compressing bool           // 后台压缩协程是否在运行
+	pending     sync.WaitGroup // 后台压缩协程
+}
+
+// OpenRotatingFile 打开（或创建）日志文件
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, opts: opts, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open 以追加方式打开日志文件（调用方需持有锁）
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取日志文件信息失败: %v", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	// 追加到已有内容时按文件的修改时间计算 max_age，避免服务频繁重启时一直不按时间轮转
+	if f.size > 0 && info.ModTime().Before(f.openedAt) {
+		f.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// Write 写入日志，写入前检查是否需要轮转
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// shouldRotate 检查写入 n 字节前是否需要轮转（调用方需持有锁）
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.opts.MaxSize > 0 && f.size+n > f.opts.MaxSize {
+		return true
+	}
+	if f.opts.MaxAge > 0 && f.now().Sub(f.openedAt) >= f.opts.MaxAge {
+		return true
+	}
+	return false
+}
+
+// Rotate 立即轮转日志文件
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotate()
+}
+
+// rotate 轮转日志文件（调用方需持有锁）
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return fmt.Errorf("关闭日志文件失败: %v", err)
+		}
+		f.file = nil
+	}
+
+	backup := f.backupName()
+	if err := os.Rename(f.path, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("轮转日志文件失败: %v", err)
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	if f.opts.Compress {
+		// 压缩和之后的清理交给后台协程按轮转顺序进行
+		f.toCompress = append(f.toCompress, backup)
+		if !f.compressing {
+			f.compressing = true
+			f.pending.Add(1)
+			go f.compressBackups()
+		}
+		return nil
+	}
+	f.prune()
+	return nil
+}
+
+// compressBackups 在后台依次压缩历史文件，每压缩一个清理一次超出保留数量的历史文件
+func (f *RotatingFile) compressBackups() {
+	defer f.pending.Done()
+	for {
+		f.mu.Lock()
+		if len(f.toCompress) == 0 {
+			f.compressing = false
+			f.mu.Unlock()
+			return
+		}
+		backup := f.toCompress[0]
+		f.toCompress = f.toCompress[1:]
+		f.mu.Unlock()
+
+		// 轮转快于压缩时，排队的历史文件可能已被清理
+		if err := compressFile(backup); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(f, "压缩日志文件 %s 失败: %v\n", backup, err)
+		}
+		f.prune()
+	}
+}
+
+// backupName 生成不与已有文件冲突的历史文件名
+func (f *RotatingFile) backupName() string {
+	base := f.path + "." + f.now().Format(backupTimeFormat)
+	name := base
+	for i := 1; ; i++ {
+		_, err := os.Stat(name)
+		_, errGz := os.Stat(name + ".gz")
+		if os.IsNotExist(err) && os.IsNotExist(errGz) {
+			return name
+		}
+		name = base + "." + strconv.Itoa(i)
+	}
+}
+
+// Backups 返回现有的历史文件，按从旧到新排序
+func (f *RotatingFile) Backups() []string {
+	matches, _ := filepath.Glob(f.path + ".*")
+
+	var backups []string
+	for _, m := range matches {
+		if backupPattern.MatchString(m[len(f.path):]) {
+			backups = append(backups, m)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backupKey(backups[i], f.path) < backupKey(backups[j], f.path)
+	})
+	return backups
+}
+
+// backupKey 历史文件的排序键（时间 + 序号），忽略 .gz 后缀
+func backupKey(name, path string) string {
+	suffix := name[len(path)+1:]
+	if filepath.Ext(suffix) == ".gz" {
+		suffix = suffix[:len(suffix)-3]
+	}
+	stamp, seq := suffix, 0
+	if len(suffix) > len(backupTimeFormat) {
+		stamp = suffix[:len(backupTimeFormat)]
+		seq, _ = strconv.Atoi(suffix[len(backupTimeFormat)+1:])
+	}
+	return fmt.Sprintf("%s.%06d", stamp, seq)
+}
+
+// prune 删除超出保留数量的历史文件（调用方需持有锁，或为后台压缩协程）
+func (f *RotatingFile) prune() {
+	if f.opts.MaxBackups <= 0 {
+		return
+	}
+
+	backups := f.Backups()
+	for len(backups) > f.opts.MaxBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+}
+
+// Reopen 重新打开日志文件（外部 logrotate 移走文件后调用）
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	return f.open()
+}
+
+// Close 等待后台压缩完成并关闭日志文件
+func (f *RotatingFile) Close() error {
+	f.pending.Wait()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// compressFile 使用 gzip 压缩文件并删除原文件
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(name + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	src.Close()
+	return os.Remove(name)
+}
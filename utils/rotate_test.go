@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeNow 返回可手动推进的时钟
+func fakeNow(start time.Time) (func() time.Time, func(time.Duration)) {
+	now := start
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+// openTestFile 打开使用模拟时钟的轮转文件
+func openTestFile(t *testing.T, opts RotateOptions) (*RotatingFile, func(time.Duration)) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "speedup.log")
+	f, err := OpenRotatingFile(path, opts)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile returned error: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	now, advance := fakeNow(time.Date(2024, 11, 8, 15, 30, 45, 0, time.Local))
+	f.now = now
+	f.openedAt = now()
+	return f, advance
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestRotatingFile_MaxSize(t *testing.T) {
+	f, advance := openTestFile(t, RotateOptions{MaxSize: 10})
+
+	f.Write([]byte("0123456789"))
+	advance(time.Second)
+	f.Write([]byte("abc"))
+
+	backups := f.Backups()
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 backup, got %v", backups)
+	}
+	if !strings.HasSuffix(backups[0], ".20241108-153046") {
+		t.Errorf("Unexpected backup name: %s", backups[0])
+	}
+	if got := readFile(t, backups[0]); got != "0123456789" {
+		t.Errorf("Unexpected backup content: %q", got)
+	}
+	if got := readFile(t, f.path); got != "abc" {
+		t.Errorf("Unexpected current content: %q", got)
+	}
+}
+
+func TestRotatingFile_MaxAge(t *testing.T) {
+	f, advance := openTestFile(t, RotateOptions{MaxAge: time.Hour})
+
+	f.Write([]byte("first\n"))
+	advance(30 * time.Minute)
+	f.Write([]byte("second\n"))
+	if len(f.Backups()) != 0 {
+		t.Fatalf("Expected no rotation before max age")
+	}
+
+	advance(30 * time.Minute)
+	f.Write([]byte("third\n"))
+	if len(f.Backups()) != 1 {
+		t.Fatalf("Expected rotation after max age, got %v", f.Backups())
+	}
+	if got := readFile(t, f.path); got != "third\n" {
+		t.Errorf("Unexpected current content: %q", got)
+	}
+}
+
+// 追加到已有日志文件时按文件的修改时间计算 max_age，重启不会重新计时
+func TestRotatingFile_MaxAgeAcrossRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "speedup.log")
+	if err := os.WriteFile(path, []byte("before restart\n"), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+	modTime := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+
+	f, err := OpenRotatingFile(path, RotateOptions{MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("OpenRotatingFile returned error: %v", err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("after restart\n"))
+	if len(f.Backups()) != 1 {
+		t.Fatalf("Expected rotation for a file older than max age, got %v", f.Backups())
+	}
+	if got := readFile(t, path); got != "after restart\n" {
+		t.Errorf("Unexpected current content: %q", got)
+	}
+}
+
+func TestRotatingFile_MaxBackupsAndCompress(t *testing.T) {
+	f, advance := openTestFile(t, RotateOptions{MaxSize: 1, MaxBackups: 2, Compress: true})
+
+	for _, line := range []string{"a", "b", "c", "d"} {
+		f.Write([]byte(line))
+		advance(time.Second)
+	}
+
+	// 压缩在后台进行，Close 等待其完成
+	f.Close()
+	backups := f.Backups()
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %v", backups)
+	}
+
+	for i, want := range []string{"b", "c"} {
+		if !strings.HasSuffix(backups[i], ".gz") {
+			t.Fatalf("Expected compressed backup, got %s", backups[i])
+		}
+		file, err := os.Open(backups[i])
+		if err != nil {
+			t.Fatalf("Failed to open backup: %v", err)
+		}
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("Failed to read gzip: %v", err)
+		}
+		data, _ := io.ReadAll(gz)
+		file.Close()
+		if string(data) != want {
+			t.Errorf("Expected backup %d to contain %q, got %q", i, want, data)
+		}
+	}
+}
+
+func TestRotatingFile_Reopen(t *testing.T) {
+	f, _ := openTestFile(t, RotateOptions{})
+
+	f.Write([]byte("before\n"))
+
+	// 模拟外部 logrotate 移走文件
+	moved := f.path + ".1"
+	if err := os.Rename(f.path, moved); err != nil {
+		t.Fatalf("Failed to move log file: %v", err)
+	}
+	if err := f.Reopen(); err != nil {
+		t.Fatalf("Reopen returned error: %v", err)
+	}
+	f.Write([]byte("after\n"))
+
+	if got := readFile(t, moved); got != "before\n" {
+		t.Errorf("Unexpected moved content: %q", got)
+	}
+	if got := readFile(t, f.path); got != "after\n" {
+		t.Errorf("Unexpected reopened content: %q", got)
+	}
+}
+
+func TestLogSink_Shared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "speedup.log")
+	sink, err := OpenLogSink("file", path, RotateOptions{})
+	if err != nil {
+		t.Fatalf("OpenLogSink returned error: %v", err)
+	}
+	opts := LoggerOptions{Level: LevelInfo, Sink: sink}
+
+	first, err := NewLoggerWithOptions(opts)
+	if err != nil {
+		t.Fatalf("NewLoggerWithOptions returned error: %v", err)
+	}
+	second, err := NewLoggerWithOptions(opts)
+	if err != nil {
+		t.Fatalf("NewLoggerWithOptions returned error: %v", err)
+	}
+
+	// 关闭日志器不会关闭共用的输出目标
+	first.WithPrefix("Scheduler").Close()
+	first.Close()
+	second.WithPrefix("IPService").Info("仍可写入")
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("Failed to move log file: %v", err)
+	}
+	if err := sink.Reopen(); err != nil {
+		t.Fatalf("Reopen returned error: %v", err)
+	}
+	second.Info("重新打开后写入")
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if got := readFile(t, path+".1"); !strings.Contains(got, "仍可写入") {
+		t.Errorf("Expected write after logger close, got %q", got)
+	}
+	if got := readFile(t, path); !strings.Contains(got, "重新打开后写入") {
+		t.Errorf("Expected write after reopen, got %q", got)
+	}
+	if _, err := sink.Write([]byte("x")); err == nil {
+		t.Error("Expected write after sink close to fail")
+	}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"io"
+	"os"
+)
+
+// LogSink 日志输出目标
+// 进程按日志配置创建一次并传给所有日志器共用，同一日志文件只有一个句柄；由创建者在退出时关闭
+type LogSink struct {
+	w    io.Writer
+	file *RotatingFile // 输出到文件时非空
+}
+
+// OpenLogSink 按输出方式（stdout, stderr, file）打开日志输出目标
+// 输出到文件时使用 rotate 作为轮转配置
+func OpenLogSink(output, file string, rotate RotateOptions) (*LogSink, error) {
+	switch {
+	case output == "file" && file != "":
+		rf, err := OpenRotatingFile(file, rotate)
+		if err != nil {
+			return nil, err
+		}
+		return &LogSink{w: rf, file: rf}, nil
+	case output == "stderr":
+		return NewWriterSink(os.Stderr), nil
+	default:
+		return NewWriterSink(os.Stdout), nil
+	}
+}
+
+// NewWriterSink 创建输出到 w 的日志输出目标
+func NewWriterSink(w io.Writer) *LogSink {
+	return &LogSink{w: w}
+}
+
+// Write 写入日志
+func (s *LogSink) Write(p []byte) (int, error) {
+	return s.w.Write(p)
+}
+
+// Reopen 重新打开日志文件（收到 SIGHUP 时调用，配合外部 logrotate），不输出到文件时什么也不做
+func (s *LogSink) Reopen() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Reopen()
+}
+
+// Close 关闭日志文件，不输出到文件时什么也不做
+func (s *LogSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// TextHandler 保持原有格式的文本日志处理器
+// 格式: 2006/01/02 15:04:05 file.go:12: [线路] [组件] [INFO] 消息
+type TextHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	attrs []slog.Attr
+}
+
+// NewTextHandler 创建文本日志处理器
+func NewTextHandler(w io.Writer) *TextHandler {
+	return &TextHandler{w: w, mu: &sync.Mutex{}}
+}
+
+// Enabled 级别过滤由 Logger 完成，处理器输出所有级别
+func (h *TextHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle 输出一条日志
+func (h *TextHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	buf.WriteString(r.Time.Format("2006/01/02 15:04:05 "))
+
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		if frame.File != "" {
+			buf.WriteString(filepath.Base(frame.File))
+			buf.WriteByte(':')
+			buf.WriteString(strconv.Itoa(frame.Line))
+			buf.WriteString(": ")
+		}
+	}
+
+	// 线路和组件作为前缀输出，其他结构化字段不输出
+	var line, component string
+	collect := func(a slog.Attr) bool {
+		switch a.Key {
+		case "line":
+			line = a.Value.String()
+		case "component":
+			component = a.Value.String()
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		collect(a)
+	}
+	r.Attrs(collect)
+
+	if line != "" {
+		buf.WriteString("[" + line + "] ")
+	}
+	if component != "" {
+		buf.WriteString("[" + component + "] ")
+	}
+	buf.WriteString("[" + levelName(r.Level) + "] ")
+	buf.WriteString(r.Message)
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+// WithAttrs 返回带有附加字段的处理器
+func (h *TextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &c
+}
+
+// WithGroup 文本格式不区分分组
+func (h *TextHandler) WithGroup(string) slog.Handler {
+	return h
+}